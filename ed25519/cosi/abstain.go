@@ -0,0 +1,83 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+// SetAbstaining records which cosigners are present but abstaining,
+// replacing any previously recorded abstention set.
+//
+// The participation bitmask carried in a collective signature is binary:
+// a cosigner either contributed to the signature or did not.
+// Some systems also let a cosigner take part in a signing round
+// without endorsing the outcome,
+// for example to acknowledge having seen a proposal it does not support.
+// Such abstention is agreed upon outside the signature,
+// and SetAbstaining records it in the Cosigners object,
+// leaving each cosigner in one of three states:
+// disabled (did not sign), abstaining (enabled and in the abstention set),
+// or affirmatively signing (enabled and not abstaining).
+// A disabled cosigner in the abstention set is simply disabled.
+//
+// The abstention set does not affect the aggregate public key
+// or the cryptographic validity of a signature,
+// and is not changed by SetMask or Verify:
+// only policies that consult Abstaining, such as StakePolicy, observe it.
+// SetAbstaining returns ErrSignerRange, leaving the set unchanged,
+// if any index is not a valid cosigner index.
+func (cos *Cosigners) SetAbstaining(signers []int) error {
+	var abstain map[int]bool
+	for _, i := range signers {
+		if i < 0 || i >= len(cos.keys) {
+			return ErrSignerRange
+		}
+		if abstain == nil {
+			abstain = make(map[int]bool)
+		}
+		abstain[i] = true
+	}
+	cos.abstain = abstain
+	return nil
+}
+
+// Abstaining reports whether the cosigner at index i
+// is in the abstention set recorded by SetAbstaining,
+// regardless of its participation bit.
+func (cos *Cosigners) Abstaining(i int) bool {
+	return cos.abstain[i]
+}
+
+type stakePolicy struct {
+	stakes    map[int]int
+	threshold int
+}
+
+func (p *stakePolicy) Check(cosigners *Cosigners) bool {
+	total := 0
+	for i, stake := range p.stakes {
+		if i < 0 || i >= cosigners.CountTotal() {
+			continue
+		}
+		if cosigners.MaskBit(i) == Enabled && !cosigners.Abstaining(i) {
+			total += stake
+		}
+	}
+	return total >= p.threshold
+}
+
+// StakePolicy creates a Policy object
+// in which the cosigner at index i holds stakes[i],
+// and which deems a collective signature acceptable provided
+// that the total stake of the cosigners affirmatively signing,
+// that is, enabled in the participation bitmask
+// and not in the abstention set recorded by SetAbstaining,
+// is at least the given threshold.
+// Cosigners missing from stakes hold no stake,
+// and entries for indices outside the cosigner list are ignored.
+func StakePolicy(stakes map[int]int, threshold int) Policy {
+	p := &stakePolicy{make(map[int]int, len(stakes)), threshold}
+	for i, stake := range stakes {
+		p.stakes[i] = stake
+	}
+	return p
+}
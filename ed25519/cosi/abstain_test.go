@@ -0,0 +1,68 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"testing"
+)
+
+func TestStakePolicy(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stakes := map[int]int{0: 60, 1: 10, 2: 10, 3: 10, 4: 10, 9: 100}
+	policy := StakePolicy(stakes, 50)
+	stakes[1] = 1000 // StakePolicy must keep its own copy
+	cos.SetPolicy(policy)
+
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("full participation rejected")
+	}
+
+	// The high-stake signer signs but abstains,
+	// leaving only 40 of affirmative stake.
+	if err := cos.SetAbstaining([]int{0}); err != nil {
+		t.Fatal(err)
+	}
+	if !cos.Abstaining(0) || cos.Abstaining(1) {
+		t.Errorf("wrong abstention set")
+	}
+	if cos.Verify(rightMessage, sig) {
+		t.Errorf("abstaining high-stake signer counted toward threshold")
+	}
+	if !cos.VerifyWithPolicy(rightMessage, sig, ThresholdPolicy(n)) {
+		t.Errorf("abstention affected a signature's validity")
+	}
+	if cos.CountEnabled() != n || !cos.Abstaining(0) {
+		t.Errorf("Verify changed the abstention set")
+	}
+
+	// Absent low-stake signers do not matter once it stops abstaining.
+	cos.SetAbstaining([]int{1, 2})
+	cos.SetMask([]byte{0x18})
+	if !policy.Check(cos) {
+		t.Errorf("affirmative high-stake signer rejected")
+	}
+	cos.SetMaskBit(0, Disabled)
+	if policy.Check(cos) {
+		t.Errorf("disabled high-stake signer counted toward threshold")
+	}
+
+	// Out-of-range abstentions are refused.
+	if err := cos.SetAbstaining([]int{1, n}); err != ErrSignerRange {
+		t.Errorf("out-of-range abstention: got %v", err)
+	}
+	if !cos.Abstaining(1) || !cos.Abstaining(2) {
+		t.Errorf("failed SetAbstaining changed the abstention set")
+	}
+	cos.SetAbstaining(nil)
+	if cos.Abstaining(1) {
+		t.Errorf("abstention set not cleared")
+	}
+}
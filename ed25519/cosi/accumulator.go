@@ -0,0 +1,94 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	//"golang.org/x/crypto/ed25519"
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// Accumulator incrementally combines cosigners' signature parts
+// into a collective signature,
+// allowing the leader to fold in each part as it arrives,
+// in any order, rather than collecting them all
+// before calling AggregateSignature.
+type Accumulator struct {
+	n        int    // total number of cosigners
+	mask     []byte // participation mask fixed at creation
+	received []byte // bit-vector of cosigners whose parts were added
+	missing  int    // number of enabled cosigners yet to add a part
+	sum      [32]byte
+}
+
+// NewAccumulator creates an Accumulator for a signing round
+// using the Cosigners object's current participation mask,
+// which must be the same mask the leader used in AggregateCommit.
+// Later changes to the Cosigners object's mask
+// do not affect the Accumulator.
+func (cos *Cosigners) NewAccumulator() *Accumulator {
+	return &Accumulator{
+		n:        len(cos.keys),
+		mask:     cos.Mask(),
+		received: make([]byte, len(cos.mask)),
+		missing:  cos.CountEnabled(),
+	}
+}
+
+// AddPart folds the signature part produced by the indicated cosigner
+// into the collective signature.
+// It returns an error, leaving the Accumulator unchanged,
+// if the cosigner is out of range or disabled in the participation mask,
+// if the cosigner's part was already added,
+// or if the part has the wrong length.
+func (acc *Accumulator) AddPart(signer int, part SignaturePart) error {
+	if signer < 0 || signer >= acc.n {
+		return ErrSignerRange
+	}
+	byt := signer >> 3
+	bit := byte(1) << uint(signer&7)
+	if acc.mask[byt]&bit != 0 {
+		return ErrSignerDisabled
+	}
+	if acc.received[byt]&bit != 0 {
+		return ErrDuplicatePart
+	}
+	if len(part) != 32 {
+		return ErrPartLength
+	}
+
+	var indivS [32]byte
+	copy(indivS[:], part)
+	edwards25519.ScMulAdd(&acc.sum, &acc.sum, &scOne, &indivS)
+	acc.received[byt] |= bit
+	acc.missing--
+	return nil
+}
+
+// Missing returns the number of enabled cosigners
+// whose signature parts have not yet been added.
+func (acc *Accumulator) Missing() int {
+	return acc.missing
+}
+
+// Finalize produces the collective signature
+// from the aggregate commit and all the signature parts added so far.
+// It returns ErrIncomplete if any enabled cosigner's part is still missing,
+// and ErrCommitLength if aggregateR has the wrong length.
+func (acc *Accumulator) Finalize(aggregateR Commitment) ([]byte, error) {
+	if acc.missing != 0 {
+		return nil, ErrIncomplete
+	}
+	if len(aggregateR) != ed25519.PublicKeySize {
+		return nil, ErrCommitLength
+	}
+
+	signature := make([]byte, ed25519.SignatureSize+len(acc.mask))
+	copy(signature[:], aggregateR)
+	copy(signature[32:64], acc.sum[:])
+	copy(signature[64:], acc.mask)
+	return signature, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAccumulator(t *testing.T) {
+	n := 8
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetMaskBit(5, Disabled)
+	cos.SetPolicy(ThresholdPolicy(n - 1))
+
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		if i != 5 {
+			parts[i] = Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		}
+	}
+
+	acc := cos.NewAccumulator()
+	if err := acc.AddPart(5, parts[4]); err != ErrSignerDisabled {
+		t.Errorf("part from disabled signer: got %v", err)
+	}
+	if err := acc.AddPart(n, parts[4]); err != ErrSignerRange {
+		t.Errorf("part from out-of-range signer: got %v", err)
+	}
+	if err := acc.AddPart(0, parts[0][:31]); err != ErrPartLength {
+		t.Errorf("short part: got %v", err)
+	}
+
+	// Parts arrive out of order.
+	for _, i := range []int{7, 2, 0, 6, 3} {
+		if err := acc.AddPart(i, parts[i]); err != nil {
+			t.Fatalf("part %d rejected: %v", i, err)
+		}
+	}
+	if err := acc.AddPart(2, parts[2]); err != ErrDuplicatePart {
+		t.Errorf("duplicate part: got %v", err)
+	}
+	if acc.Missing() != 2 {
+		t.Errorf("Missing = %d, want 2", acc.Missing())
+	}
+	if _, err := acc.Finalize(aggR); err != ErrIncomplete {
+		t.Errorf("finalizing incomplete signature: got %v", err)
+	}
+
+	for _, i := range []int{4, 1} {
+		if err := acc.AddPart(i, parts[i]); err != nil {
+			t.Fatalf("part %d rejected: %v", i, err)
+		}
+	}
+	if _, err := acc.Finalize(aggR[:31]); err != ErrCommitLength {
+		t.Errorf("short aggregate commit: got %v", err)
+	}
+	sig, err := acc.Finalize(aggR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, cos.AggregateSignature(aggR, parts)) {
+		t.Errorf("accumulated signature differs from AggregateSignature")
+	}
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("accumulated signature rejected")
+	}
+}
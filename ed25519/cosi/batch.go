@@ -0,0 +1,300 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// VerifyBatch checks many collective signatures at once,
+// where sigs[i] is the collective signature on messages[i].
+// Each signature may carry a different participation mask,
+// and each must independently satisfy the current Policy.
+//
+// VerifyBatch combines all the verification equations
+// into a single random linear combination,
+// which it checks using one shared multi-scalar multiplication.
+// This is substantially faster than verifying each signature separately
+// when the batch is large.
+// Signatures whose R or aggregate public key has a small-order component,
+// for which the combined check could disagree with Verify,
+// are instead verified individually,
+// so that VerifyBatch accepts a batch exactly when Verify
+// accepts every signature in it.
+// If the combined check passes, VerifyBatch returns true and a nil slice.
+// Otherwise, it falls back to checking each signature individually,
+// and unless every signature then verifies,
+// returns false together with a slice reporting
+// which of the signatures were valid.
+// VerifyBatch also returns false and a nil slice
+// if messages and sigs have different lengths.
+//
+// In a build with the cosi_norand tag,
+// which has no source of randomness for the linear combination,
+// VerifyBatch always checks each signature individually,
+// returning true and a nil slice if all are valid.
+//
+// Batch verification is not constant-time,
+// and a failed batch reveals which signatures were bad.
+// It should therefore be used only on already-public data,
+// such as collectively-signed log entries.
+//
+// Like Verify, VerifyBatch changes the participation bitmask;
+// on return the mask is that of the last signature examined.
+func (cos *Cosigners) VerifyBatch(messages [][]byte, sigs [][]byte) (bool, []bool) {
+
+	if len(messages) != len(sigs) {
+		return false, nil
+	}
+
+	n := len(sigs)
+
+	// For each signature i we want to check S_i*B == R_i + h_i*K_i.
+	// We instead check that sum z_i*(S_i*B - R_i - h_i*K_i) is the identity
+	// for random 128-bit z_i, using negated R_i and K_i as the points.
+	// A small-order component in R_i or K_i survives multiplication by z_i
+	// only for some z_i, so the combination could accept a signature
+	// that Verify rejects; such signatures are verified individually instead.
+	scalars := make([][32]byte, 0, 2*n)
+	points := make([]edwards25519.ExtendedGroupElement, 0, 2*n)
+	var sumS [32]byte
+	var zero [32]byte
+	var lastMask []byte
+	aggrTorsionFree := false
+	rand, err := cos.randReader()
+	ok := err == nil
+	for i := 0; i < n && ok; i++ {
+		sig := sigs[i]
+		if validateSignatureForm(sig, cos.MaskLen()) != nil {
+			ok = false
+			break
+		}
+
+		cos.SetMask(sig[64:])
+		if !cos.checkPolicyMessage(cos.policy, messages[i]) ||
+			isIdentityPoint(&cos.aggr) {
+			ok = false
+			break
+		}
+		if lastMask == nil || !bytes.Equal(sig[64:], lastMask) {
+			lastMask = sig[64:]
+			aggrTorsionFree = isTorsionFree(&cos.aggr)
+		}
+
+		var R edwards25519.ExtendedGroupElement
+		var RBytes [32]byte
+		copy(RBytes[:], sig[:32])
+		if !canonicalPoint(&RBytes) || !R.FromBytes(&RBytes) {
+			ok = false
+			break
+		}
+		if !aggrTorsionFree || !isTorsionFree(&R) {
+			ok = cos.Verify(messages[i], sig)
+			continue
+		}
+
+		hReduced := cos.Challenge(messages[i], sig[:32])
+
+		var z [32]byte
+		if _, err := io.ReadFull(rand, z[:16]); err != nil {
+			ok = false
+			break
+		}
+
+		var S [32]byte
+		copy(S[:], sig[32:64])
+		edwards25519.ScMulAdd(&sumS, &z, &S, &sumS)
+
+		var zh [32]byte
+		edwards25519.ScMulAdd(&zh, &z, &hReduced, &zero)
+		scalars = append(scalars, z, zh)
+		points = append(points, R, cos.aggr)
+	}
+
+	if ok {
+		for i := range points {
+			edwards25519.FeNeg(&points[i].X, &points[i].X)
+			edwards25519.FeNeg(&points[i].T, &points[i].T)
+		}
+
+		var check edwards25519.ProjectiveGroupElement
+		edwards25519.GeMultiScalarMultVartime(&check, scalars, points,
+			&sumS)
+
+		var checkBytes [32]byte
+		check.ToBytes(&checkBytes)
+		if checkBytes == identity {
+			return true, nil
+		}
+	}
+
+	// Something in the batch is bad, or there was no randomness:
+	// find out exactly what is bad.
+	valid := make([]bool, n)
+	allValid := true
+	for i := range sigs {
+		valid[i] = cos.Verify(messages[i], sigs[i])
+		allValid = allValid && valid[i]
+	}
+	if allValid {
+		return true, nil
+	}
+	return false, valid
+}
+
+// identity is the encoding of the neutral element of the curve.
+var identity = [32]byte{1}
+
+// groupOrder is the order l of the Ed25519 base point, little-endian.
+var groupOrder = [32]byte{
+	0xed, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58,
+	0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+	0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0x10,
+}
+
+// keyTorsionFree reports whether cosigner i's public key is torsion-free,
+// checking all the keys on first use after any change to the key list.
+func (cos *Cosigners) keyTorsionFree(i int) bool {
+	if cos.torsionFree == nil {
+		cos.torsionFree = make([]bool, len(cos.keys))
+		for j := range cos.keys {
+			cos.torsionFree[j] = isTorsionFree(&cos.keys[j])
+		}
+	}
+	return cos.torsionFree[i]
+}
+
+// isTorsionFree reports whether P lies in the prime-order subgroup
+// generated by the base point, that is, whether [l]P is the identity,
+// so that P has no small-order component.
+func isTorsionFree(P *edwards25519.ExtendedGroupElement) bool {
+	var zero, check [32]byte
+	var proj edwards25519.ProjectiveGroupElement
+	edwards25519.GeDoubleScalarMultVartime(&proj, &groupOrder, P, &zero)
+	proj.ToBytes(&check)
+	return check == identity
+}
+
+// VerifyParts checks many cosigners' signature parts at once
+// during collective signing,
+// where parts maps each cosigner's index to its signature part,
+// and commits holds the cosigners' individual commitments,
+// indexed as for AggregateCommit.
+// Each part is checked against its own cosigner's commitment,
+// which the aggregate commit aggR does not determine,
+// so VerifyParts needs commits as well as the message and aggR.
+//
+// VerifyParts combines the checks into a single random linear combination
+// as VerifyBatch does, which is much faster for large groups
+// than calling VerifyPart on each part.
+// As in VerifyBatch, parts whose commitment or public key
+// has a small-order component are checked individually.
+// A part passes exactly when VerifyPart accepts it
+// and its commitment is one AggregateCommitErr would accept:
+// unlike VerifyPart, VerifyParts also rejects a part
+// whose commitment is missing, non-canonical, or the identity,
+// since no signing round can use such a commitment.
+// In a build with the cosi_norand tag,
+// which has no source of randomness for the linear combination,
+// VerifyParts always checks each part individually.
+//
+// If every part is valid, VerifyParts returns true and a nil slice.
+// Otherwise it checks each part individually,
+// and returns false together with the indices, in increasing order,
+// of the cosigners whose parts are invalid,
+// so that the leader can exclude them and restart the signing round.
+// A part whose cosigner index is out of range counts as invalid.
+func (cos *Cosigners) VerifyParts(message, aggR []byte, commits []Commitment,
+	parts map[int][]byte) (bool, []int) {
+
+	signers := make([]int, 0, len(parts))
+	for i := range parts {
+		signers = append(signers, i)
+	}
+	sort.Ints(signers)
+
+	// For each part i we want to check s_i*B == R_i + c*K_i,
+	// with the same challenge c for every part.
+	// As in VerifyBatch, we check a random linear combination instead.
+	// Parts whose commitment or public key has a small-order component
+	// are verified individually, as VerifyBatch does.
+	n := len(signers)
+	scalars := make([][32]byte, 0, 2*n)
+	points := make([]edwards25519.ExtendedGroupElement, 0, 2*n)
+	c := cos.Challenge(message, aggR)
+	var sumS, zero [32]byte
+	rand, err := cos.randReader()
+	ok := err == nil && len(aggR) == 32
+	for _, i := range signers {
+		if !ok {
+			break
+		}
+		part := parts[i]
+		var R edwards25519.ExtendedGroupElement
+		if i < 0 || i >= len(cos.keys) || i >= len(commits) ||
+			len(part) != 32 || !scMinimal(part) ||
+			decodeCommitment(&R, commits[i]) != nil {
+			ok = false
+			break
+		}
+		if !cos.keyTorsionFree(i) || !isTorsionFree(&R) {
+			ok = cos.VerifyPart(message, aggR, i, commits[i], part)
+			continue
+		}
+
+		var z [32]byte
+		if _, err := io.ReadFull(rand, z[:16]); err != nil {
+			ok = false
+			break
+		}
+
+		var S [32]byte
+		copy(S[:], part)
+		edwards25519.ScMulAdd(&sumS, &z, &S, &sumS)
+
+		var zc [32]byte
+		edwards25519.ScMulAdd(&zc, &z, &c, &zero)
+		scalars = append(scalars, z, zc)
+		points = append(points, R, *cos.aggKey(i))
+	}
+
+	if ok {
+		for i := range points {
+			edwards25519.FeNeg(&points[i].X, &points[i].X)
+			edwards25519.FeNeg(&points[i].T, &points[i].T)
+		}
+
+		var check edwards25519.ProjectiveGroupElement
+		edwards25519.GeMultiScalarMultVartime(&check, scalars, points,
+			&sumS)
+
+		var checkBytes [32]byte
+		check.ToBytes(&checkBytes)
+		if checkBytes == identity {
+			return true, nil
+		}
+	}
+
+	// Something is bad, or there was no randomness:
+	// find out exactly which parts are bad.
+	bad := []int{}
+	for _, i := range signers {
+		if i < 0 || i >= len(cos.keys) || i >= len(commits) ||
+			ValidateCommitment(commits[i]) != nil ||
+			!cos.VerifyPart(message, aggR, i, commits[i], parts[i]) {
+			bad = append(bad, i)
+		}
+	}
+	if len(bad) == 0 {
+		return true, nil
+	}
+	return false, bad
+}
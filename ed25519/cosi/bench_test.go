@@ -0,0 +1,225 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// The benchmarks in this file measure each step of a signing round
+// for the whole group, over the group sizes in benchSizes,
+// and draw all keys and commitments from fixed seeds
+// so that runs are reproducible and comparable.
+// Run them with, e.g., go test -run XXX -bench Group.
+
+// benchSizes are the numbers of cosigners the group benchmarks cover.
+var benchSizes = []int{1, 16, 256, 4096}
+
+// benchSeed seeds the randomness of the group benchmarks.
+const benchSeed = 1
+
+// benchGroup is a group of cosigners with distinct keys for benchmarking.
+type benchGroup struct {
+	pub []ed25519.PublicKey
+	pri []ed25519.PrivateKey
+}
+
+// benchGroups caches the groups made by newBenchGroup.
+var benchGroups = map[int]*benchGroup{}
+
+// newBenchGroup returns a group of n cosigners
+// whose keys are derived from benchSeed.
+// Unlike genKeys, it yields distinct keys for more than 256 cosigners.
+func newBenchGroup(tb testing.TB, n int) *benchGroup {
+	if g := benchGroups[n]; g != nil {
+		return g
+	}
+	rng := rand.New(rand.NewSource(benchSeed))
+	g := &benchGroup{
+		pub: make([]ed25519.PublicKey, n),
+		pri: make([]ed25519.PrivateKey, n),
+	}
+	for i := range g.pub {
+		var err error
+		g.pub[i], g.pri[i], err = ed25519.GenerateKey(rng)
+		if err != nil {
+			tb.Fatal(err)
+		}
+	}
+	benchGroups[n] = g
+	return g
+}
+
+// round runs a signing round by the whole group on message,
+// drawing the commitments from rng,
+// and returns the resulting commits, signature parts, and signature.
+func (g *benchGroup) round(tb testing.TB, cos *Cosigners, rng *rand.Rand,
+	message []byte) (Commitment, []SignaturePart, []byte) {
+
+	commits, secrets, err := CommitBatch(rng, len(g.pub))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	aggK, aggR := cos.AggregatePublicKey(), cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, len(g.pub))
+	for i := range parts {
+		parts[i] = Cosign(g.pri[i], secrets[i], message, aggK, aggR)
+	}
+	return aggR, parts, cos.AggregateSignature(aggR, parts)
+}
+
+// benchGroupSizes runs bench as a sub-benchmark for each of benchSizes,
+// passing it a group of that size, a Cosigners for it,
+// and a random source seeded with benchSeed.
+func benchGroupSizes(b *testing.B, bench func(b *testing.B, g *benchGroup,
+	cos *Cosigners, rng *rand.Rand)) {
+
+	for _, n := range benchSizes {
+		b.Run("N="+strconv.Itoa(n), func(b *testing.B) {
+			g := newBenchGroup(b, n)
+			cos, err := NewCosignersErr(g.pub, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			rng := rand.New(rand.NewSource(benchSeed))
+			b.ResetTimer()
+			bench(b, g, cos, rng)
+		})
+	}
+}
+
+// BenchmarkGroupCommit measures every cosigner making a commitment.
+func BenchmarkGroupCommit(b *testing.B) {
+	benchGroupSizes(b, func(b *testing.B, g *benchGroup, cos *Cosigners,
+		rng *rand.Rand) {
+
+		for i := 0; i < b.N; i++ {
+			for range g.pub {
+				if _, _, err := Commit(rng); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+// BenchmarkGroupCosign measures every cosigner producing its signature part,
+// excluding the commitments.
+func BenchmarkGroupCosign(b *testing.B) {
+	benchGroupSizes(b, func(b *testing.B, g *benchGroup, cos *Cosigners,
+		rng *rand.Rand) {
+
+		aggK := cos.AggregatePublicKey()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			commits, secrets, _ := CommitBatch(rng, len(g.pub))
+			aggR := cos.AggregateCommit(commits)
+			b.StartTimer()
+			for j := range g.pri {
+				Cosign(g.pri[j], secrets[j], rightMessage, aggK, aggR)
+			}
+		}
+	})
+}
+
+// BenchmarkGroupAggregateCommit measures the leader
+// combining the cosigners' commitments.
+func BenchmarkGroupAggregateCommit(b *testing.B) {
+	benchGroupSizes(b, func(b *testing.B, g *benchGroup, cos *Cosigners,
+		rng *rand.Rand) {
+
+		commits, _, _ := CommitBatch(rng, len(g.pub))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := cos.AggregateCommitErr(commits); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkGroupAggregateSignature measures the leader
+// combining the cosigners' signature parts.
+func BenchmarkGroupAggregateSignature(b *testing.B) {
+	benchGroupSizes(b, func(b *testing.B, g *benchGroup, cos *Cosigners,
+		rng *rand.Rand) {
+
+		aggR, parts, _ := g.round(b, cos, rng, rightMessage)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := cos.AggregateSignatureErr(aggR, parts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkGroupVerify measures verifying a collective signature
+// whose mask matches the one already set, as when verifying repeatedly.
+func BenchmarkGroupVerify(b *testing.B) {
+	benchGroupSizes(b, func(b *testing.B, g *benchGroup, cos *Cosigners,
+		rng *rand.Rand) {
+
+		_, _, sig := g.round(b, cos, rng, rightMessage)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if !cos.Verify(rightMessage, sig) {
+				b.Fatal("signature rejected")
+			}
+		}
+	})
+}
+
+// BenchmarkGroupMaskSwitch measures switching between two masks
+// that differ in every cosigner's bit,
+// which is the cost Verify pays when consecutive signatures
+// come from different sets of cosigners.
+func BenchmarkGroupMaskSwitch(b *testing.B) {
+	benchGroupSizes(b, func(b *testing.B, g *benchGroup, cos *Cosigners,
+		rng *rand.Rand) {
+
+		masks := [2][]byte{make([]byte, cos.MaskLen()),
+			make([]byte, cos.MaskLen())}
+		for i := range masks[0] {
+			masks[0][i] = 0x55
+			masks[1][i] = 0xaa
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			cos.SetMask(masks[i&1])
+		}
+	})
+}
+
+func TestBenchGroup(t *testing.T) {
+	n := 300
+	g := newBenchGroup(t, n)
+	seen := make(map[string]bool)
+	for _, pub := range g.pub {
+		if seen[string(pub)] {
+			t.Fatalf("benchmark group has duplicate keys")
+		}
+		seen[string(pub)] = true
+	}
+	if newBenchGroup(t, n) != g {
+		t.Errorf("benchmark group not reused")
+	}
+
+	// The seeded round is reproducible and valid.
+	cos, _ := NewCosignersErr(g.pub[:16], nil)
+	sub := &benchGroup{g.pub[:16], g.pri[:16]}
+	_, _, sig1 := sub.round(t, cos, rand.New(rand.NewSource(benchSeed)),
+		rightMessage)
+	_, _, sig2 := sub.round(t, cos, rand.New(rand.NewSource(benchSeed)),
+		rightMessage)
+	if !cos.Verify(rightMessage, sig1) || !SignatureEqual(sig1, sig2) {
+		t.Errorf("seeded signing round not valid and reproducible")
+	}
+}
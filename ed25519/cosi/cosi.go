@@ -67,7 +67,7 @@
 // suitable for collective signing using this module.
 // The Cosigners type implemented by this package
 // represents a set of cosigners identified by their ed25519 public keys:
-// you create such a set by calling NewCosigners with the list of public keys.
+// you create such a set by calling NewCosignersErr with the list of public keys.
 //
 // The order of this public key list is arbitrary,
 // but must be kept consistent between signing and verifying.
@@ -104,12 +104,19 @@
 // For further details, see the CoSi paper above,
 // as well as section 3.2 of this paper:
 // http://cs-www.bu.edu/~reyzin/papers/multisig.pdf.
+// MakeKeyProof and VerifyKeyProof implement such self-signatures,
+// and NewCosignersWithProofs requires one for every public key.
+// As an additional safeguard, NewCosignersStrict rejects public keys
+// of small order, which correspond to no usable private key.
+// Alternatively, SetMuSig weights each key by a hash of the whole key list,
+// which defeats such attacks without proofs of possession,
+// at the cost of signatures incompatible with plain CoSi.
 //
 // Verifying Collective Signatures
 //
 // Verifying collective signatures is simple,
 // and may be done offline at any time without any special protocol.
-// Simply use NewCosigners to create a Cosigners object
+// Simply use NewCosignersErr to create a Cosigners object
 // representing the list of cosigners identified by their public keys,
 // then invoke the Verify method on this object
 // to verify a signature on a particular message.
@@ -139,6 +146,17 @@
 // and will be accepted if the verifier calls SetPolicy(ThresholdPolicy(0))!
 // This merely illustrates the importance of
 // choosing the verification policy carefully.
+// As a safety net, verifiers are encouraged to call SetRejectEmpty(true),
+// which rejects such signatures whatever the policy.
+//
+// Collective signatures in plain (non-MuSig) mode are interoperable
+// with those of the DEDIS cothority's CoSi implementation,
+// go.dedis.ch/kyber/v3/sign/cosi, configured with SHA-512 as its hash:
+// both compute the same Ed25519 challenge over the same aggregate key,
+// and lay out R, S and the participation mask identically,
+// except that kyber's mask bits mark enabled rather than disabled cosigners.
+// ConvertCothority translates signatures between the two formats,
+// in either direction.
 //
 // Producing Collective Signatures
 //
@@ -245,6 +263,10 @@
 package cosi
 
 import (
+	"hash"
+	"io"
+	"strconv"
+
 	//"golang.org/x/crypto/ed25519"
 	//"golang.org/x/crypto/ed25519/internal/edwards25519"
 	"github.com/bford/golang-x-crypto/ed25519"
@@ -283,15 +305,53 @@ type Cosigners struct {
 	// cached aggregate of all enabled cosigners' public keys
 	aggr edwards25519.ExtendedGroupElement
 
+	// cached number of enabled cosigners
+	enabled int
+
+	// optional cache of aggregates for recently-used masks
+	cache *maskCache
+
 	// cosigner-presence policy for checking signatures
 	policy Policy
+
+	// reject signatures with no participants regardless of policy
+	rejectEmpty bool
+
+	// cosigners present but abstaining, tracked outside the mask
+	abstain map[int]bool
+
+	// cosigners that witness the message without validating it
+	witness map[int]bool
+
+	// lazily-built map from public key encoding to index, or nil
+	index map[string]int
+
+	// lazily-computed torsion-freeness of each public key, or nil
+	torsionFree []bool
+
+	// bit-vector of permanently disabled cosigners, or nil if none
+	revoked []byte
+
+	// challenge hash constructor, or nil for SHA-512
+	newHash func() hash.Hash
+
+	// source of randomness, or nil for the default source
+	rand io.Reader
+
+	// cosigner count at which summations go parallel, or 0 for never
+	parallel int
+
+	// MuSig coefficients and weighted public keys, or nil in plain mode
+	coefs    [][32]byte
+	weighted []edwards25519.ExtendedGroupElement
 }
 
-// NewCosigners creates a new Cosigners object
+// NewCosignersErr creates a new Cosigners object
 // for a particular list of cosigners identified by Ed25519 public keys.
 //
 // The specified list of public keys remains immutable
-// for the lifetime of this Cosigners object.
+// for the lifetime of this Cosigners object,
+// except that AppendCosigner may add new keys to the end.
 // Collective signature verifiers must use a public key list identical
 // to the one that was used in the collective signing process,
 // although the participation bitmask may change
@@ -299,14 +359,33 @@ type Cosigners struct {
 //
 // The mask parameter may be nil to enable all participants initially,
 // and otherwise is an initial participation bitmask as defined in SetMask.
-func NewCosigners(publicKeys []ed25519.PublicKey, mask []byte) *Cosigners {
+//
+// If any public key is malformed, NewCosignersErr returns a *KeyError
+// identifying the first offending key and the reason it was rejected.
+//
+// Any options, such as WithPolicy or WithStrictKeys,
+// are applied before the Cosigners object is returned,
+// so that it is never observable in a partly-configured state.
+// With no options, NewCosignersErr uses the default Policy and hash
+// and checks keys only for well-formedness.
+func NewCosignersErr(publicKeys []ed25519.PublicKey, mask []byte,
+	opts ...Option) (*Cosigners, error) {
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	var publicKeyBytes [32]byte
 	cos := &Cosigners{}
 	cos.keys = make([]edwards25519.ExtendedGroupElement, len(publicKeys))
 	for i, publicKey := range publicKeys {
+		if len(publicKey) != ed25519.PublicKeySize {
+			return nil, &KeyError{i, ErrKeyLength}
+		}
 		copy(publicKeyBytes[:], publicKey)
 		if !cos.keys[i].FromBytes(&publicKeyBytes) {
-			return nil
+			return nil, &KeyError{i, ErrInvalidKey}
 		}
 	}
 
@@ -319,9 +398,205 @@ func NewCosigners(publicKeys []ed25519.PublicKey, mask []byte) *Cosigners {
 	cos.SetMask(mask)
 
 	cos.policy = fullPolicy{}
+	if err := o.apply(cos, publicKeys); err != nil {
+		return nil, err
+	}
+	return cos, nil
+}
+
+// NewCosignersStrict is like NewCosignersErr,
+// but applies additional checks appropriate for cosigner lists
+// assembled from untrusted sources.
+//
+// NewCosignersStrict rejects any public key of small order,
+// including the identity point, with a *KeyError wrapping ErrSmallOrderKey.
+// Such keys have no corresponding private key in the prime-order subgroup,
+// and a malicious participant could register one
+// to mount related-key or cancellation attacks against the aggregate.
+// It also rejects public keys that are not canonically encoded
+// with a *KeyError wrapping ErrInvalidKey.
+//
+// Finally, NewCosignersStrict rejects a list in which the same public key
+// appears more than once, returning a *DuplicateKeyError:
+// otherwise that cosigner would effectively vote twice,
+// rendering threshold policies meaningless.
+//
+// Verifiers should use NewCosignersStrict
+// in addition to requiring each participant to prove
+// possession of its private key as described in the package documentation.
+func NewCosignersStrict(publicKeys []ed25519.PublicKey, mask []byte) (*Cosigners, error) {
+	return NewCosignersErr(publicKeys, mask, WithStrictKeys())
+}
+
+// checkStrictKeys applies the checks described in NewCosignersStrict
+// to the already-decoded public keys of cos.
+func (cos *Cosigners) checkStrictKeys(publicKeys []ed25519.PublicKey) error {
+	seen := make(map[[32]byte]int, len(publicKeys))
+	for i := range cos.keys {
+		var keyBytes [32]byte
+		copy(keyBytes[:], publicKeys[i])
+		if !canonicalPoint(&keyBytes) {
+			return &KeyError{i, ErrInvalidKey}
+		}
+		if isSmallOrder(&cos.keys[i]) {
+			return &KeyError{i, ErrSmallOrderKey}
+		}
+		if j, ok := seen[keyBytes]; ok {
+			return &DuplicateKeyError{j, i}
+		}
+		seen[keyBytes] = i
+	}
+	return nil
+}
+
+// isSmallOrder reports whether P is of small order,
+// i.e., whether multiplying it by the cofactor 8 yields the identity.
+func isSmallOrder(P *edwards25519.ExtendedGroupElement) bool {
+	var t edwards25519.CompletedGroupElement
+	var Q edwards25519.ExtendedGroupElement
+	Q = *P
+	for i := 0; i < 3; i++ {
+		Q.Double(&t)
+		t.ToExtended(&Q)
+	}
+	return isIdentityPoint(&Q)
+}
+
+// isIdentityPoint reports whether P is the identity.
+func isIdentityPoint(P *edwards25519.ExtendedGroupElement) bool {
+
+	// The identity has X == 0 and Y == Z
+	var d edwards25519.FieldElement
+	edwards25519.FeSub(&d, &P.Y, &P.Z)
+	return edwards25519.FeIsNonZero(&P.X) == 0 &&
+		edwards25519.FeIsNonZero(&d) == 0
+}
+
+// NewCosigners creates a new Cosigners object
+// for a particular list of cosigners identified by Ed25519 public keys,
+// exactly as NewCosignersErr does,
+// but returns nil without explanation if any public key is malformed.
+//
+// Deprecated: Use NewCosignersErr, which reports which key was rejected.
+func NewCosigners(publicKeys []ed25519.PublicKey, mask []byte) *Cosigners {
+	cos, err := NewCosignersErr(publicKeys, mask)
+	if err != nil {
+		return nil
+	}
 	return cos
 }
 
+// Clone returns an independent deep copy of the Cosigners object,
+// including its public key list, participation bitmask,
+// cached aggregate public key, and current Policy.
+// If a mask cache is enabled, the clone gets its own, initially empty,
+// cache of the same size.
+// Subsequent changes to the mask of either object
+// do not affect the other,
+// so a server can keep one canonical Cosigners object
+// and hand a separate clone to each goroutine that needs one.
+// The Policy itself is shared, not copied,
+// and hence must be safe for concurrent use if the clones are.
+func (cos *Cosigners) Clone() *Cosigners {
+	c := &Cosigners{}
+	c.keys = append([]edwards25519.ExtendedGroupElement{}, cos.keys...)
+	c.mask = append([]byte{}, cos.mask...)
+	c.aggr = cos.aggr
+	c.enabled = cos.enabled
+	c.policy = cos.policy
+	c.rejectEmpty = cos.rejectEmpty
+	c.abstain = cos.abstain
+	c.witness = cos.witness
+	c.index = cos.index
+	c.torsionFree = cos.torsionFree
+	if cos.revoked != nil {
+		c.revoked = append([]byte{}, cos.revoked...)
+	}
+	c.newHash = cos.newHash
+	c.rand = cos.rand
+	c.parallel = cos.parallel
+	c.coefs = cos.coefs
+	c.weighted = cos.weighted
+	if cos.cache != nil {
+		c.cache = newMaskCache(cos.cache.size)
+	}
+	return c
+}
+
+// Reset returns the Cosigners object to the state
+// NewCosigners leaves it in with a nil mask:
+// all cosigners enabled, as after SetMask(nil),
+// the default Policy requiring every cosigner,
+// SetRejectEmpty off, and no cosigners abstaining or marked as witnesses.
+// It keeps the public key list without reallocating it,
+// along with the settings tied to the keys and the object's configuration:
+// revocations by DisablePermanently, MuSig mode,
+// and any mask cache, hash function, randomness source,
+// or parallel threshold.
+// Reset makes it cheap to reuse Cosigners objects,
+// for example from a sync.Pool, across unrelated signatures.
+func (cos *Cosigners) Reset() {
+	cos.SetMask(nil)
+	cos.policy = fullPolicy{}
+	cos.rejectEmpty = false
+	cos.abstain = nil
+	cos.witness = nil
+}
+
+// AppendCosigner adds a new cosigner with the given public key
+// to the end of the cosigner list, initially Enabled,
+// without renumbering the existing cosigners
+// or re-decoding their public keys.
+// The participation bitmask grows by one byte
+// whenever the number of cosigners passes a multiple of 8.
+// Existing collective signatures therefore continue to verify
+// as long as MaskLen is unchanged,
+// since the unused high bits of their masks
+// mark the new cosigner as not having signed;
+// once MaskLen grows, they have the wrong length.
+//
+// AppendCosigner returns ErrKeyLength or ErrInvalidKey
+// if the public key is malformed, leaving the Cosigners object unchanged.
+// Like NewCosignersErr, it does not check for small-order or duplicate keys.
+func (cos *Cosigners) AppendCosigner(publicKey ed25519.PublicKey) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return ErrKeyLength
+	}
+	var publicKeyBytes [32]byte
+	var key edwards25519.ExtendedGroupElement
+	copy(publicKeyBytes[:], publicKey)
+	if !key.FromBytes(&publicKeyBytes) {
+		return ErrInvalidKey
+	}
+
+	i := len(cos.keys)
+	cos.keys = append(cos.keys, key)
+	cos.index = nil
+	cos.torsionFree = nil
+	if i&7 == 0 {
+		cos.mask = append(cos.mask, 0xff) // all disabled
+		if cos.revoked != nil {
+			cos.revoked = append(cos.revoked, 0)
+		}
+	}
+	if cos.weighted != nil {
+		// Every MuSig coefficient depends on the whole list.
+		cos.mask[i>>3] &^= byte(1) << uint(i&7)
+		cos.weighKeys()
+		cos.reaggregate()
+		return nil
+	}
+	cos.mask[i>>3] &^= byte(1) << uint(i&7) // enable it
+	cos.aggr.Add(&cos.aggr, &key)
+	cos.enabled++
+
+	// Cached masks are for the old cosigner list.
+	if cos.cache != nil {
+		cos.cache = newMaskCache(cos.cache.size)
+	}
+	return nil
+}
+
 // CountTotal returns the total number of cosigners,
 // i.e., the length of the list of public keys supplied to NewCosigners.
 func (cos *Cosigners) CountTotal() int {
@@ -332,19 +607,35 @@ func (cos *Cosigners) CountTotal() int {
 // in the participation bitmask.
 // This is always between 0 and CountTotal inclusive.
 func (cos *Cosigners) CountEnabled() int {
-	// Yes, we could count zero-bits much more efficiently...
-	count := 0
+	return cos.enabled
+}
+
+// PublicKeys returns the list of cosigners' public keys,
+// in the order originally supplied to NewCosignersErr.
+// The returned keys are freshly allocated copies
+// that the caller may modify without affecting the Cosigners object.
+func (cos *Cosigners) PublicKeys() []ed25519.PublicKey {
+	keys := make([]ed25519.PublicKey, len(cos.keys))
 	for i := range cos.keys {
-		if cos.mask[i>>3]&(1<<uint(i&7)) == 0 {
-			count++
-		}
+		var keyBytes [32]byte
+		cos.keys[i].ToBytes(&keyBytes)
+		keys[i] = keyBytes[:]
 	}
-	return count
+	return keys
 }
 
-//func (cos *Cosigners) PublicKeys() []ed25519.PublicKey {
-//	return cos.keys
-//}
+// CosignerKey returns the public key of the single cosigner at index i,
+// in the order originally supplied to NewCosignersErr,
+// without materializing the whole list as PublicKeys does.
+// It returns ErrSignerRange if i is not a valid cosigner index.
+func (cos *Cosigners) CosignerKey(i int) (ed25519.PublicKey, error) {
+	if i < 0 || i >= len(cos.keys) {
+		return nil, ErrSignerRange
+	}
+	var keyBytes [32]byte
+	cos.keys[i].ToBytes(&keyBytes)
+	return keyBytes[:], nil
+}
 
 // SetMask sets the entire participation bitmask according to the provided
 // packed byte-slice interpreted in little-endian byte-order.
@@ -356,25 +647,125 @@ func (cos *Cosigners) CountEnabled() int {
 // If the mask provided is too short (or nil),
 // SetMask conservatively interprets the bits of the missing bytes
 // to be 0, or Enabled.
+// Cosigners revoked by DisablePermanently stay disabled
+// whatever their bits.
+//
+// SetMask updates the cached aggregate public key incrementally,
+// adding or subtracting only the keys of cosigners whose bits changed,
+// and skipping quickly over unchanged mask bytes.
+// Setting a series of masks that each differ from the last in a few bits,
+// as when verifying many signatures from a mostly-stable group,
+// is therefore cheap even for large cosigner lists.
 func (cos *Cosigners) SetMask(mask []byte) {
+	if cos.cache != nil && cos.cache.lookup(cos, mask) {
+		return
+	}
+	n := len(cos.keys)
+	if chunks := cos.parallelChunks(n); chunks == 1 {
+		// Serial fast path, which avoids allocating.
+		var diff edwards25519.ExtendedGroupElement
+		diff.Zero()
+		cos.enabled += cos.updateMask(mask, 0, n, &diff)
+		cos.aggr.Add(&cos.aggr, &diff)
+	} else {
+		deltas := make([]int, chunks)
+		diff, _ := cos.parallelSum(n, func(chunk, lo, hi int,
+			sum *edwards25519.ExtendedGroupElement) error {
+			deltas[chunk] = cos.updateMask(mask, lo, hi, sum)
+			return nil
+		})
+		cos.aggr.Add(&cos.aggr, &diff)
+		for _, d := range deltas {
+			cos.enabled += d
+		}
+	}
+	if cos.cache != nil {
+		cos.cache.add(cos)
+	}
+}
+
+// SetMaskDelta sets the participation bitmask to newMask
+// exactly as SetMask does,
+// applying only the bits that differ from the current mask
+// as point additions and subtractions, as SetMaskBit does per bit.
+// SetMask itself already works this way,
+// so SetMaskDelta is an alias for it,
+// for callers that want to make the incremental update explicit.
+func (cos *Cosigners) SetMaskDelta(newMask []byte) {
+	cos.SetMask(newMask)
+}
+
+// updateMask installs the bits of mask for cosigners [lo,hi),
+// accumulating into sum the change in their contribution
+// to the aggregate public key,
+// and returns the change in the number of enabled cosigners.
+func (cos *Cosigners) updateMask(mask []byte, lo, hi int,
+	sum *edwards25519.ExtendedGroupElement) (delta int) {
+
 	masklen := len(mask)
-	for i := range cos.keys {
+	for i := lo; i < hi; i++ {
 		byt := i >> 3
+		if i&7 == 0 && cos.sameMaskByte(mask, byt) {
+			i += 7 // no change in this byte
+			continue
+		}
 		bit := byte(1) << uint(i&7)
-		if (byt < masklen) && (mask[byt]&bit != 0) {
+		if (byt < masklen) && (mask[byt]&bit != 0) ||
+			cos.revokedByte(byt)&bit != 0 {
 			// Participant i disabled in new mask.
 			if cos.mask[byt]&bit == 0 {
 				cos.mask[byt] |= bit // disable it
-				cos.aggr.Sub(&cos.aggr, &cos.keys[i])
+				sum.Sub(sum, cos.aggKey(i))
+				delta--
 			}
 		} else {
 			// Participant i enabled in new mask.
 			if cos.mask[byt]&bit != 0 {
 				cos.mask[byt] &^= bit // enable it
-				cos.aggr.Add(&cos.aggr, &cos.keys[i])
+				sum.Add(sum, cos.aggKey(i))
+				delta++
 			}
 		}
 	}
+	return delta
+}
+
+// sameMaskByte reports whether byte byt of mask,
+// interpreted as in SetMask,
+// leaves every cosigner in that byte unchanged.
+func (cos *Cosigners) sameMaskByte(mask []byte, byt int) bool {
+	b := cos.revokedByte(byt)
+	if byt < len(mask) {
+		b |= mask[byt]
+	}
+	used := byte(0xff)
+	if rest := len(cos.keys) - byt<<3; rest < 8 {
+		used = byte(1)<<uint(rest) - 1
+	}
+	return (b^cos.mask[byt])&used == 0
+}
+
+// SetMaskStrict is like SetMask,
+// but accepts only a mask in the exact form that Mask returns:
+// it must be exactly MaskLen bytes long,
+// and any unused high bits of the last byte,
+// beyond the last cosigner, must be set.
+// Otherwise SetMaskStrict leaves the participation bitmask unchanged
+// and returns ErrMaskLength or ErrMaskPadding respectively.
+// Rejecting masks that SetMask would interpret identically
+// ensures that each participation set has exactly one encoding.
+func (cos *Cosigners) SetMaskStrict(mask []byte) error {
+	if len(mask) != cos.MaskLen() {
+		return ErrMaskLength
+	}
+	if pad := len(cos.keys) & 7; pad != 0 {
+		high := byte(0xff) << uint(pad)
+		if mask[len(mask)-1]&high != high {
+			return ErrMaskPadding
+		}
+	}
+	cos.SetMask(mask)
+	return nil
 }
 
 // Mask returns the current cosigner disable-mask
@@ -383,12 +774,33 @@ func (cos *Cosigners) Mask() []byte {
 	return append([]byte{}, cos.mask...) // return copy of internal mask
 }
 
+// MaskInto writes the current cosigner disable-mask, as Mask returns it,
+// into buf and returns the number of bytes written,
+// which is always MaskLen.
+// It lets callers that read the mask frequently reuse a single buffer.
+// MaskInto returns io.ErrShortBuffer, writing nothing,
+// if buf is shorter than MaskLen bytes.
+func (cos *Cosigners) MaskInto(buf []byte) (int, error) {
+	if len(buf) < len(cos.mask) {
+		return 0, io.ErrShortBuffer
+	}
+	return copy(buf, cos.mask), nil
+}
+
 // MaskLen returns the length in bytes
 // of a complete disable-mask for this cosigner list.
 func (cos *Cosigners) MaskLen() int {
 	return (len(cos.keys) + 7) >> 3
 }
 
+// SignatureSize returns the length in bytes
+// of a collective signature for this list of cosigners:
+// a standard Ed25519 signature followed by the participation mask,
+// i.e., ed25519.SignatureSize + MaskLen().
+func (cos *Cosigners) SignatureSize() int {
+	return ed25519.SignatureSize + cos.MaskLen()
+}
+
 // SetMaskBit enables or disables the mask bit for an individual cosigner.
 func (cos *Cosigners) SetMaskBit(signer int, value MaskBit) {
 	byt := signer >> 3
@@ -396,12 +808,14 @@ func (cos *Cosigners) SetMaskBit(signer int, value MaskBit) {
 	if value == Disabled { // disable
 		if cos.mask[byt]&bit == 0 { // was enabled
 			cos.mask[byt] |= bit // disable it
-			cos.aggr.Sub(&cos.aggr, &cos.keys[signer])
+			cos.aggr.Sub(&cos.aggr, cos.aggKey(signer))
+			cos.enabled--
 		}
 	} else { // enable
-		if cos.mask[byt]&bit != 0 { // was disabled
+		if cos.mask[byt]&bit != 0 && cos.revokedByte(byt)&bit == 0 {
 			cos.mask[byt] &^= bit
-			cos.aggr.Add(&cos.aggr, &cos.keys[signer])
+			cos.aggr.Add(&cos.aggr, cos.aggKey(signer))
+			cos.enabled++
 		}
 	}
 }
@@ -413,3 +827,60 @@ func (cos *Cosigners) MaskBit(signer int) (value MaskBit) {
 	bit := byte(1) << uint(signer&7)
 	return (cos.mask[byt] & bit) != 0
 }
+
+// EnabledSigners returns the indices of all cosigners
+// currently marked Enabled in the participation bitmask,
+// in increasing order.
+func (cos *Cosigners) EnabledSigners() []int {
+	return cos.signers(Enabled)
+}
+
+// DisabledSigners returns the indices of all cosigners
+// currently marked Disabled in the participation bitmask,
+// in increasing order.
+func (cos *Cosigners) DisabledSigners() []int {
+	return cos.signers(Disabled)
+}
+
+// String describes the Cosigners object for logging,
+// listing the cosigners currently enabled in the participation bitmask,
+// as in "Cosigners(N=9, enabled=5: [0 2 3 6 8])".
+func (cos *Cosigners) String() string {
+	b := []byte("Cosigners(N=")
+	b = strconv.AppendInt(b, int64(len(cos.keys)), 10)
+	b = append(b, ", enabled="...)
+	b = strconv.AppendInt(b, int64(cos.enabled), 10)
+	b = append(b, ": ["...)
+	sep := false
+	for i := range cos.keys {
+		if cos.MaskBit(i) == Enabled {
+			if sep {
+				b = append(b, ' ')
+			}
+			b = strconv.AppendInt(b, int64(i), 10)
+			sep = true
+		}
+	}
+	return string(append(b, "])"...))
+}
+
+// signers returns the indices of all cosigners whose mask bit is value.
+func (cos *Cosigners) signers(value MaskBit) []int {
+	skip := byte(0x00) // mask byte in which no bit has the wanted value
+	if value == Enabled {
+		skip = 0xff
+	}
+	signers := []int{}
+	for byt, b := range cos.mask {
+		if b == skip {
+			continue
+		}
+		for bit := 0; bit < 8; bit++ {
+			i := byt<<3 | bit
+			if i < len(cos.keys) && MaskBit(b&(1<<uint(bit)) != 0) == value {
+				signers = append(signers, i)
+			}
+		}
+	}
+	return signers
+}
@@ -5,11 +5,24 @@
 package cosi
 
 import (
-	//"encoding/hex"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
 	"testing"
+	"testing/iotest"
 
 	//"golang.org/x/crypto/ed25519"
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
 	"github.com/bford/golang-x-crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
 )
 
 type constReader struct{ val byte }
@@ -21,102 +34,2143 @@ func (cr constReader) Read(buf []byte) (int, error) {
 	return len(buf), nil
 }
 
+// testRand is the source of randomness the tests pass to Commit.
+// It is nil, selecting the default source,
+// except in builds with the cosi_norand tag, which have none.
+var testRand io.Reader
+
 var pubKeys []ed25519.PublicKey
 var priKeys []ed25519.PrivateKey
 
-var rightMessage = []byte("test message")
-var wrongMessage = []byte("wrong message")
+var rightMessage = []byte("test message")
+var wrongMessage = []byte("wrong message")
+
+func genKeys(n int) {
+	for len(priKeys) < n {
+		i := len(priKeys)
+		pub, pri, _ := ed25519.GenerateKey(constReader{byte(i)})
+		pubKeys = append(pubKeys, pub)
+		priKeys = append(priKeys, pri)
+	}
+}
+
+func testCosign(tb testing.TB, message []byte, priKey []ed25519.PrivateKey,
+	cos *Cosigners) []byte {
+
+	n := len(priKey)
+
+	aggK := cos.AggregatePublicKey()
+
+	// Create the individual commits and corresponding secrets
+	// (these would be done by the individual participants in practice)
+	commit := make([]Commitment, n)
+	secret := make([]*Secret, n)
+	for i := range commit {
+		commit[i], secret[i], _ = Commit(testRand)
+	}
+
+	// Leader: combine the individual commits into an aggregate commit
+	aggR := cos.AggregateCommit(commit)
+
+	// Create the individual signature parts
+	sigpart := make([]SignaturePart, n)
+	for i := range sigpart {
+		sigpart[i] = Cosign(priKey[i], secret[i], message, aggK, aggR)
+
+		// verify each part individually
+		if !cos.VerifyPart(message, aggR, i, commit[i], sigpart[i]) {
+			tb.Errorf("signature part %d rejected ", i)
+		}
+	}
+
+	// Leader: combine the signature parts into a collective signature
+	sig := cos.AggregateSignature(aggR, sigpart)
+	return sig
+}
+
+func TestSignVerify(t *testing.T) {
+
+	// Create a number of distinct keypairs
+	n := 10
+	genKeys(n)
+	cosigners := NewCosigners(pubKeys[:n], nil) // all enabled by default
+	if cosigners.CountTotal() != n {
+		t.Errorf("cosigners reports incorrect number of public keys")
+	}
+	if cosigners.CountEnabled() != n {
+		t.Errorf("cosigners reports incorrect number of enabled keys")
+	}
+
+	// collectively sign a test message
+	sig := testCosign(t, rightMessage, priKeys[:n], cosigners)
+	if !cosigners.Verify(rightMessage, sig) {
+		t.Errorf("valid signature rejected")
+	}
+
+	if cosigners.Verify(wrongMessage, sig) {
+		t.Errorf("signature of different message accepted")
+	}
+
+	// now collectively sign with only a partial cosigners set
+	cosigners.SetMaskBit(5, Disabled)
+	if cosigners.CountEnabled() != n-1 {
+		t.Errorf("cosigners reports incorrect number of enabled keys")
+	}
+	sig = testCosign(t, rightMessage, priKeys[:n], cosigners)
+	if cosigners.Verify(rightMessage, sig) {
+		t.Errorf("signature with too few cosigners accepted")
+	}
+
+	// now reduce the verification threshold
+	cosigners.SetPolicy(ThresholdPolicy(n - 1))
+	if !cosigners.Verify(rightMessage, sig) {
+		t.Errorf("valid threshold not accepted")
+	}
+
+	// now remove another cosigner and make sure it breaks again
+	cosigners.SetMaskBit(7, Disabled)
+	if cosigners.CountEnabled() != n-2 {
+		t.Errorf("cosigners reports incorrect number of enabled keys")
+	}
+	sig = testCosign(t, rightMessage, priKeys[:n], cosigners)
+	if cosigners.Verify(rightMessage, sig) {
+		t.Errorf("signature with too few cosigners accepted")
+	}
+}
+
+// invalidPoint is a 32-byte string that does not decode to a curve point.
+var invalidPoint = []byte{2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+func TestNewCosignersErr(t *testing.T) {
+	n := 4
+	genKeys(n)
+
+	keys := append([]ed25519.PublicKey{}, pubKeys[:n]...)
+	cos, err := NewCosignersErr(keys, nil)
+	if err != nil || cos == nil {
+		t.Fatalf("valid keys rejected: %v", err)
+	}
+
+	keys[2] = keys[2][:31]
+	_, err = NewCosignersErr(keys, nil)
+	if ke, ok := err.(*KeyError); !ok || ke.Index != 2 || ke.Err != ErrKeyLength {
+		t.Errorf("short key: got error %v", err)
+	}
+	if NewCosigners(keys, nil) != nil {
+		t.Errorf("NewCosigners accepted a short key")
+	}
+
+	keys[2] = pubKeys[2]
+	keys[3] = invalidPoint
+	_, err = NewCosignersErr(keys, nil)
+	if ke, ok := err.(*KeyError); !ok || ke.Index != 3 || ke.Err != ErrInvalidKey {
+		t.Errorf("invalid point: got error %v", err)
+	}
+}
+
+// smallOrderPoints are the canonical encodings
+// of the eight points of small order on the curve.
+var smallOrderPoints = []string{
+	"0100000000000000000000000000000000000000000000000000000000000000", // order 1
+	"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f", // order 2
+	"0000000000000000000000000000000000000000000000000000000000000000", // order 4
+	"0000000000000000000000000000000000000000000000000000000000000080", // order 4
+	"26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05", // order 8
+	"26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc85", // order 8
+	"c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a", // order 8
+	"c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac03fa", // order 8
+}
+
+func TestNewCosignersStrict(t *testing.T) {
+	n := 4
+	genKeys(n)
+	if _, err := NewCosignersStrict(pubKeys[:n], nil); err != nil {
+		t.Fatalf("legitimate keys rejected: %v", err)
+	}
+
+	for _, point := range smallOrderPoints {
+		key, _ := hex.DecodeString(point)
+		keys := append([]ed25519.PublicKey{}, pubKeys[:n]...)
+		keys[2] = key
+		if _, err := NewCosignersErr(keys, nil); err != nil {
+			t.Errorf("point %s: does not decode: %v", point, err)
+			continue
+		}
+		_, err := NewCosignersStrict(keys, nil)
+		if ke, ok := err.(*KeyError); !ok || ke.Index != 2 || ke.Err != ErrSmallOrderKey {
+			t.Errorf("point %s: got error %v", point, err)
+		}
+	}
+
+	keys := append([]ed25519.PublicKey{}, pubKeys[:n]...)
+	keys[1] = invalidPoint
+	_, err := NewCosignersStrict(keys, nil)
+	if ke, ok := err.(*KeyError); !ok || ke.Index != 1 || ke.Err != ErrInvalidKey {
+		t.Errorf("invalid point: got error %v", err)
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	n := 6
+	genKeys(n)
+
+	keys := append([]ed25519.PublicKey{}, pubKeys[:n]...)
+	keys[4] = append(ed25519.PublicKey{}, keys[1]...)
+	if _, err := NewCosignersErr(keys, nil); err != nil {
+		t.Errorf("NewCosignersErr rejected duplicate keys: %v", err)
+	}
+	_, err := NewCosignersStrict(keys, nil)
+	if de, ok := err.(*DuplicateKeyError); !ok || de.Index != 1 || de.Duplicate != 4 {
+		t.Errorf("exact duplicate: got error %v", err)
+	}
+
+	// Keys differing only in the sign bit are distinct points.
+	keys = append([]ed25519.PublicKey{}, pubKeys[:n]...)
+	keys[4] = append(ed25519.PublicKey{}, keys[1]...)
+	keys[4][31] ^= 0x80
+	if _, err := NewCosignersStrict(keys, nil); err != nil {
+		t.Errorf("distinct but similar keys rejected: %v", err)
+	}
+
+	// A non-canonical encoding is rejected outright.
+	nonCanonical := make(ed25519.PublicKey, 32)
+	for i := range nonCanonical {
+		nonCanonical[i] = 0xff
+	}
+	nonCanonical[0] = 0xf0 // y = p+3
+	keys = append([]ed25519.PublicKey{}, pubKeys[:n]...)
+	keys[5] = nonCanonical
+	if _, err := NewCosignersErr(keys, nil); err != nil {
+		t.Fatalf("non-canonical key does not decode: %v", err)
+	}
+	_, err = NewCosignersStrict(keys, nil)
+	if ke, ok := err.(*KeyError); !ok || ke.Index != 5 || ke.Err != ErrInvalidKey {
+		t.Errorf("non-canonical key: got error %v", err)
+	}
+}
+
+func TestAppendCosigner(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n-2], nil)
+	cos.SetPolicy(ThresholdPolicy(n - 2))
+	sig := testCosign(t, rightMessage, priKeys[:n-2], cos)
+
+	// 8 to 9 cosigners grows the mask, 9 to 10 does not.
+	if err := cos.AppendCosigner(pubKeys[n-2]); err != nil {
+		t.Fatal(err)
+	}
+	if cos.MaskLen() != 2 || cos.MaskBit(n-2) != Enabled ||
+		cos.CountEnabled() != n-1 || cos.CountTotal() != n-1 {
+		t.Errorf("appended cosigner not enabled: mask %x", cos.Mask())
+	}
+	sig = testCosign(t, rightMessage, priKeys[:n-1], cos)
+	if err := cos.AppendCosigner(pubKeys[n-1]); err != nil {
+		t.Fatal(err)
+	}
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("existing signature rejected after append")
+	}
+
+	// The result must match building the full list from scratch.
+	full, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetMask(nil)
+	if !bytes.Equal(cos.AggregatePublicKey(), full.AggregatePublicKey()) ||
+		!bytes.Equal(cos.Mask(), full.Mask()) {
+		t.Errorf("appended cosigners differ from a fresh list")
+	}
+	if !cos.Verify(rightMessage, testCosign(t, rightMessage, priKeys[:n], full)) {
+		t.Errorf("signature by extended list rejected")
+	}
+
+	if err := cos.AppendCosigner(pubKeys[0][:31]); err != ErrKeyLength {
+		t.Errorf("short key: got %v, want ErrKeyLength", err)
+	}
+	if err := cos.AppendCosigner(invalidPoint); err != ErrInvalidKey {
+		t.Errorf("invalid key: got %v, want ErrInvalidKey", err)
+	}
+	if cos.CountTotal() != n {
+		t.Errorf("failed append changed the cosigner list")
+	}
+}
+
+func TestPublicKeys(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := cos.PublicKeys()
+	if len(keys) != n {
+		t.Fatalf("PublicKeys returned %d keys, want %d", len(keys), n)
+	}
+	for i := range keys {
+		if !bytes.Equal(keys[i], pubKeys[i]) {
+			t.Errorf("public key %d does not round-trip", i)
+		}
+	}
+
+	// Modifying the returned keys must not affect the Cosigners object.
+	keys[0][0] ^= 1
+	if !bytes.Equal(cos.PublicKeys()[0], pubKeys[0]) {
+		t.Errorf("PublicKeys exposes internal state")
+	}
+}
+
+func TestCosignerKey(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	for i := 0; i < n; i++ {
+		key, err := cos.CosignerKey(i)
+		if err != nil || !bytes.Equal(key, pubKeys[i]) {
+			t.Errorf("CosignerKey(%d) = %x, %v; want %x", i, key, err,
+				pubKeys[i])
+		}
+	}
+	for _, i := range []int{-1, n, n + 100} {
+		if _, err := cos.CosignerKey(i); err != ErrSignerRange {
+			t.Errorf("CosignerKey(%d): got %v, want ErrSignerRange", i, err)
+		}
+	}
+}
+
+func TestEnabledDisabledSigners(t *testing.T) {
+	n := 12
+	genKeys(n)
+
+	tests := []struct {
+		n        int
+		mask     []byte
+		enabled  []int
+		disabled []int
+	}{
+		{0, nil, []int{}, []int{}},
+		{n, nil, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, []int{}},
+		{n, []byte{0xff, 0xff}, []int{}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		{n, []byte{0x00, 0xf0}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, []int{}},
+		{n, []byte{0x81, 0x04}, []int{1, 2, 3, 4, 5, 6, 8, 9, 11}, []int{0, 7, 10}},
+	}
+	for _, test := range tests {
+		cos, err := NewCosignersErr(pubKeys[:test.n], test.mask)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cos.EnabledSigners(); !reflect.DeepEqual(got, test.enabled) {
+			t.Errorf("mask %x: EnabledSigners = %v, want %v", test.mask, got, test.enabled)
+		}
+		if got := cos.DisabledSigners(); !reflect.DeepEqual(got, test.disabled) {
+			t.Errorf("mask %x: DisabledSigners = %v, want %v", test.mask, got, test.disabled)
+		}
+	}
+}
+
+func TestSignatureSize(t *testing.T) {
+	genKeys(17)
+	for _, test := range []struct{ n, size int }{
+		{0, 64}, {1, 65}, {7, 65}, {8, 65}, {9, 66}, {16, 66}, {17, 67},
+	} {
+		cos, _ := NewCosignersErr(pubKeys[:test.n], nil)
+		if got := cos.SignatureSize(); got != test.size {
+			t.Errorf("%d cosigners: SignatureSize() = %d, want %d",
+				test.n, got, test.size)
+		}
+		if test.n > 0 {
+			sig := testCosign(t, rightMessage, priKeys[:test.n], cos)
+			if len(sig) != cos.SignatureSize() {
+				t.Errorf("%d cosigners: signature has length %d",
+					test.n, len(sig))
+			}
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	n := 9
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0xb2, 0x00})
+	want := "Cosigners(N=9, enabled=5: [0 2 3 6 8])"
+	if got := cos.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := fmt.Sprint(cos); got != want {
+		t.Errorf("fmt.Sprint = %q, want %q", got, want)
+	}
+
+	cos.SetMask([]byte{0xff, 0xff})
+	want = "Cosigners(N=9, enabled=0: [])"
+	if got := cos.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestCountEnabled(t *testing.T) {
+	n := 21
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recount := func() int {
+		count := 0
+		for i := 0; i < n; i++ {
+			if cos.MaskBit(i) == Enabled {
+				count++
+			}
+		}
+		return count
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 1000; iter++ {
+		switch rnd.Intn(3) {
+		case 0:
+			cos.SetMask(nil)
+		case 1:
+			mask := make([]byte, rnd.Intn(cos.MaskLen()+1))
+			rnd.Read(mask)
+			cos.SetMask(mask)
+		default:
+			cos.SetMaskBit(rnd.Intn(n), MaskBit(rnd.Intn(2) == 0))
+		}
+		if got, want := cos.CountEnabled(), recount(); got != want {
+			t.Fatalf("iteration %d: CountEnabled = %d, want %d", iter, got, want)
+		}
+	}
+	if cos.Clone().CountEnabled() != recount() {
+		t.Errorf("Clone does not preserve enabled count")
+	}
+}
+
+func TestSetMaskDelta(t *testing.T) {
+	n := 20
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	rng := rand.New(rand.NewSource(1))
+	mask := make([]byte, cos.MaskLen())
+	for iter := 0; iter < 50; iter++ {
+		// Flip a few bits of the previous mask.
+		for k := rng.Intn(3); k >= 0; k-- {
+			i := rng.Intn(n)
+			mask[i>>3] ^= 1 << uint(i&7)
+		}
+		cos.SetMaskDelta(mask)
+		want, _ := NewCosignersErr(pubKeys[:n], mask)
+		if !bytes.Equal(cos.AggregatePublicKey(), want.AggregatePublicKey()) ||
+			cos.CountEnabled() != want.CountEnabled() ||
+			!bytes.Equal(cos.Mask(), want.Mask()) {
+			t.Fatalf("iteration %d: SetMaskDelta(%x) disagrees with SetMask",
+				iter, mask)
+		}
+	}
+}
+
+func TestSetMaskStrict(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetPolicy(ThresholdPolicy(n - 1))
+	cos.SetMaskBit(1, Disabled)
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	tests := []struct {
+		mask []byte
+		err  error
+	}{
+		{[]byte{0x02, 0xfc}, nil},
+		{[]byte{0x00, 0xff}, nil},
+		{[]byte{0x02, 0x00}, ErrMaskPadding},
+		{[]byte{0x02, 0x7c}, ErrMaskPadding},
+		{[]byte{0x02}, ErrMaskLength},
+		{[]byte{0x02, 0xfc, 0xff}, ErrMaskLength},
+		{nil, ErrMaskLength},
+	}
+	for _, test := range tests {
+		cos.SetMask([]byte{0xff, 0xff})
+		err := cos.SetMaskStrict(test.mask)
+		if err != test.err {
+			t.Errorf("mask %x: got %v, want %v", test.mask, err, test.err)
+		}
+		if err != nil && cos.CountEnabled() != 0 {
+			t.Errorf("mask %x: rejected mask was applied", test.mask)
+		}
+	}
+
+	if !cos.VerifyStrict(rightMessage, sig) {
+		t.Errorf("canonical signature rejected")
+	}
+
+	// Garbage in the unused high bits of the last mask byte
+	// is ignored by Verify but rejected by VerifyStrict.
+	garbled := append([]byte{}, sig...)
+	garbled[len(garbled)-1] &^= 0x40
+	if !cos.Verify(rightMessage, garbled) {
+		t.Errorf("Verify rejected signature with garbled mask padding")
+	}
+	if cos.VerifyStrict(rightMessage, garbled) {
+		t.Errorf("VerifyStrict accepted signature with garbled mask padding")
+	}
+	if cos.VerifyStrict(rightMessage, sig[:40]) {
+		t.Errorf("VerifyStrict accepted truncated signature")
+	}
+}
+
+func TestClone(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	clone := cos.Clone()
+	clone.SetMaskBit(3, Disabled)
+	if cos.MaskBit(3) != Enabled {
+		t.Errorf("SetMaskBit on clone affected the original")
+	}
+	if bytes.Equal(clone.AggregatePublicKey(), cos.AggregatePublicKey()) {
+		t.Errorf("clone shares its aggregate public key with the original")
+	}
+
+	// Verify concurrently on independent clones;
+	// run with -race to check that no state is shared.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(c *Cosigners) {
+			defer wg.Done()
+			if !c.Verify(rightMessage, sig) {
+				t.Errorf("valid signature rejected by clone")
+			}
+		}(cos.Clone())
+	}
+	wg.Wait()
+}
+
+func TestAggregateScalars(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x04})
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		if cos.MaskBit(i) == Enabled {
+			parts[i] = Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		}
+	}
+	parts[2] = []byte("ignored: cosigner 2 is disabled")
+
+	aggS, err := cos.AggregateScalars(parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := cos.AggregateSignature(aggR, parts)
+	if !bytes.Equal(aggS[:], sig[32:64]) {
+		t.Errorf("AggregateScalars differs from the signature's S")
+	}
+
+	parts[3] = parts[3][:31]
+	aggS, err = cos.AggregateScalars(parts)
+	if pe, ok := err.(*PartError); !ok || pe.Index != 3 ||
+		pe.Err != ErrPartLength {
+		t.Errorf("malformed part: got error %v", err)
+	}
+	if aggS != ([32]byte{}) {
+		t.Errorf("partial sum returned with error")
+	}
+	if _, err := cos.AggregateScalars(parts[:1]); err == nil {
+		t.Errorf("missing parts accepted")
+	}
+}
+
+func TestIdentityAggregateKey(t *testing.T) {
+	// Cosigner 1's key is the negation of cosigner 0's,
+	// so together they aggregate to the identity.
+	genKeys(1)
+	neg := append(ed25519.PublicKey{}, pubKeys[0]...)
+	neg[31] ^= 0x80
+	cos, err := NewCosignersErr([]ed25519.PublicKey{pubKeys[0], neg}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetPolicy(ThresholdPolicy(0))
+	if !bytes.Equal(cos.AggregatePublicKey(), identity[:]) {
+		t.Fatalf("keys do not cancel")
+	}
+
+	// Without the private keys, R = [S]B for any S would verify.
+	s := [32]byte{7}
+	var R edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&R, &s)
+	var RBytes [32]byte
+	R.ToBytes(&RBytes)
+	forged := append(append(RBytes[:], s[:]...), 0xfc)
+
+	if cos.Verify(rightMessage, forged) {
+		t.Errorf("Verify accepted identity aggregate key")
+	}
+	if cos.VerifyStream(bytes.NewReader(rightMessage), forged) {
+		t.Errorf("VerifyStream accepted identity aggregate key")
+	}
+	if cos.VerifyReuse(rightMessage, forged, &VerifyScratch{}) {
+		t.Errorf("VerifyReuse accepted identity aggregate key")
+	}
+	if cos.VerifyConstantTime(rightMessage, forged) {
+		t.Errorf("VerifyConstantTime accepted identity aggregate key")
+	}
+	if cos.VerifyCofactored(rightMessage, forged) {
+		t.Errorf("VerifyCofactored accepted identity aggregate key")
+	}
+	if ok, _ := cos.VerifyBatch([][]byte{rightMessage},
+		[][]byte{forged}); ok {
+		t.Errorf("VerifyBatch accepted identity aggregate key")
+	}
+}
+
+func TestReset(t *testing.T) {
+	n := 10
+	genKeys(n)
+	fresh, _ := NewCosignersErr(pubKeys[:n], nil)
+	full := testCosign(t, rightMessage, priKeys[:n], fresh)
+	fresh.SetMask([]byte{0x03})
+	partial := testCosign(t, rightMessage, priKeys[:n], fresh)
+	fresh, _ = NewCosignersErr(pubKeys[:n], nil)
+
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	keys := &cos.keys[0]
+	cos.SetPolicy(ThresholdPolicy(1))
+	cos.SetRejectEmpty(true)
+	if err := cos.SetAbstaining([]int{4}); err != nil {
+		t.Fatal(err)
+	}
+	cos.Verify(rightMessage, partial)
+	cos.Reset()
+
+	if &cos.keys[0] != keys {
+		t.Errorf("Reset reallocated the key list")
+	}
+	if !bytes.Equal(cos.Mask(), fresh.Mask()) || cos.CountEnabled() != n ||
+		!bytes.Equal(cos.AggregatePublicKey(), fresh.AggregatePublicKey()) {
+		t.Errorf("Reset did not enable all cosigners")
+	}
+	if cos.rejectEmpty || cos.Abstaining(4) {
+		t.Errorf("Reset kept per-signature settings")
+	}
+	for _, sig := range [][]byte{full, partial} {
+		if cos.Verify(rightMessage, sig) != fresh.Verify(rightMessage, sig) {
+			t.Errorf("reset object and fresh one disagree")
+		}
+	}
+	if cos.Verify(rightMessage, partial) {
+		t.Errorf("Reset kept the lenient policy")
+	}
+}
+
+func TestSyncCosigners(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetPolicy(ThresholdPolicy(n - 1))
+	full := testCosign(t, rightMessage, priKeys[:n], cos)
+	cos.SetMaskBit(2, Disabled)
+	partial := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	// Hammer Verify from many goroutines with differently-masked signatures;
+	// run with -race to check that the mask updates are serialized.
+	sc := NewSyncCosigners(cos)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				sig := full
+				if (i+j)%2 == 0 {
+					sig = partial
+				}
+				if !sc.Verify(rightMessage, sig) {
+					t.Errorf("valid signature rejected")
+				}
+				if sc.Verify(wrongMessage, sig) {
+					t.Errorf("signature on wrong message accepted")
+				}
+				if c := sc.CountEnabled(); c != n && c != n-1 {
+					t.Errorf("CountEnabled returned %d", c)
+				}
+				if len(sc.Mask()) != cos.MaskLen() {
+					t.Errorf("Mask returned wrong length")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestVerifyBatch(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetPolicy(ThresholdPolicy(n - 3))
+
+	// Sign a number of distinct messages with varying participation masks.
+	var messages, sigs [][]byte
+	for i := 0; i < 8; i++ {
+		cos.SetMask(nil)
+		cos.SetMaskBit(i%n, Disabled)
+		if i%3 == 0 {
+			cos.SetMaskBit((i+5)%n, Disabled)
+		}
+		msg := []byte("batch message " + strconv.Itoa(i))
+		messages = append(messages, msg)
+		sigs = append(sigs, testCosign(t, msg, priKeys[:n], cos))
+	}
+
+	ok, valid := cos.VerifyBatch(messages, sigs)
+	if !ok || valid != nil {
+		t.Errorf("valid batch rejected: %v", valid)
+	}
+
+	// Swap in a signature on the wrong message.
+	messages[5] = wrongMessage
+	ok, valid = cos.VerifyBatch(messages, sigs)
+	if ok {
+		t.Errorf("batch containing a bad signature accepted")
+	}
+	for i := range valid {
+		if valid[i] != (i != 5) {
+			t.Errorf("signature %d reported valid=%v", i, valid[i])
+		}
+	}
+
+	if ok, _ := cos.VerifyBatch(messages, sigs[:3]); ok {
+		t.Errorf("mismatched batch lengths accepted")
+	}
+}
+
+// torsionPoints are points of order 2 and 4.
+var torsionPoints = [][]byte{
+	{0xec, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+	make([]byte, 32),
+}
+
+// addTorsion returns the encoding of point p plus torsionPoints[k].
+func addTorsion(tb testing.TB, p []byte, k int) []byte {
+	P, err := NewPoint(p)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	T, err := NewPoint(torsionPoints[k])
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return AddPoint(P, T).Bytes()
+}
+
+func TestVerifyBatchTorsion(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+
+	// Signatures whose R has a small-order component,
+	// but which are otherwise consistent:
+	// S*B - R - h*A is a small-order point,
+	// so the random combination would vanish for some z.
+	var messages, sigs [][]byte
+	for i := 0; i < 16; i++ {
+		msg := []byte("torsion message " + strconv.Itoa(i))
+		sig := cosignWith(t, cos, func(j int, secret *Secret,
+			aggK, aggR []byte) SignaturePart {
+			return Cosign(priKeys[j], secret, msg,
+				aggK, addTorsion(t, aggR, i%2))
+		})
+		copy(sig, addTorsion(t, sig[:32], i%2))
+		if cos.Verify(msg, sig) {
+			t.Fatalf("torsioned R accepted by Verify")
+		}
+		messages = append(messages, msg)
+		sigs = append(sigs, sig)
+	}
+	good := testCosign(t, rightMessage, priKeys[:n], cos)
+	for i := range sigs {
+		ok, valid := cos.VerifyBatch([][]byte{rightMessage, messages[i]},
+			[][]byte{good, sigs[i]})
+		if ok || !reflect.DeepEqual(valid, []bool{true, false}) {
+			t.Errorf("torsioned R %d: batch %v, %v", i, ok, valid)
+		}
+	}
+
+	// With a small-order component in a public key,
+	// the batch agrees with Verify signature by signature.
+	keys := append([]ed25519.PublicKey{}, pubKeys[:n]...)
+	keys[0] = addTorsion(t, keys[0], 0)
+	mixed, err := NewCosignersErr(keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range messages {
+		msg := messages[i]
+		sig := cosignWith(t, mixed, func(j int, secret *Secret,
+			aggK, aggR []byte) SignaturePart {
+			return Cosign(priKeys[j], secret, msg, aggK, aggR)
+		})
+		ok, _ := mixed.VerifyBatch(messages[i:i+1], [][]byte{sig})
+		if ok != mixed.Verify(messages[i], sig) {
+			t.Errorf("torsioned key, message %d: batch says %v", i, ok)
+		}
+	}
+}
+
+func TestVerifyParts(t *testing.T) {
+	n := 8
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make(map[int][]byte)
+	for i := range commits {
+		parts[i] = Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+	}
+
+	if ok, bad := cos.VerifyParts(rightMessage, aggR, commits, parts); !ok || bad != nil {
+		t.Errorf("valid parts rejected: %v", bad)
+	}
+	if ok, bad := cos.VerifyParts(wrongMessage, aggR, commits, parts); ok || len(bad) != n {
+		t.Errorf("parts on wrong message: got %v, %v", ok, bad)
+	}
+
+	// Corrupt some parts and check that exactly those are reported.
+	good3, good6 := parts[3], parts[6]
+	parts[3] = append([]byte{}, good3...)
+	parts[3][0] ^= 1
+	parts[6] = addOrder(good6)
+	ok, bad := cos.VerifyParts(rightMessage, aggR, commits, parts)
+	if ok || !reflect.DeepEqual(bad, []int{3, 6}) {
+		t.Errorf("corrupted parts: got %v, %v; want false, [3 6]", ok, bad)
+	}
+	parts[3], parts[6] = good3, good6
+
+	parts[n] = parts[0]
+	ok, bad = cos.VerifyParts(rightMessage, aggR, commits, parts)
+	if ok || !reflect.DeepEqual(bad, []int{n}) {
+		t.Errorf("out-of-range signer: got %v, %v", ok, bad)
+	}
+	delete(parts, n)
+
+	if ok, bad := cos.VerifyParts(rightMessage, aggR, commits,
+		map[int][]byte{}); !ok || bad != nil {
+		t.Errorf("empty set of parts rejected")
+	}
+
+	// A commitment with a small-order component added
+	// makes a part that VerifyPart rejects, and so must VerifyParts,
+	// whatever the random combination.
+	for k := 0; k < 16; k++ {
+		torsioned := append([]Commitment{}, commits...)
+		torsioned[5] = addTorsion(t, commits[5], k%2)
+		if cos.VerifyPart(rightMessage, aggR, 5, torsioned[5], parts[5]) {
+			t.Fatalf("torsioned commitment accepted by VerifyPart")
+		}
+		ok, bad := cos.VerifyParts(rightMessage, aggR, torsioned, parts)
+		if ok || !reflect.DeepEqual(bad, []int{5}) {
+			t.Errorf("torsioned commitment: got %v, %v; want false, [5]",
+				ok, bad)
+		}
+	}
+
+	// Likewise for a public key with a small-order component.
+	keys := append([]ed25519.PublicKey{}, pubKeys[:n]...)
+	keys[2] = addTorsion(t, keys[2], 0)
+	mixed, _ := NewCosignersErr(keys, nil)
+	for k := 0; k < 16; k++ {
+		msg := []byte("torsioned key " + strconv.Itoa(k))
+		aggK := mixed.AggregatePublicKey()
+		for i := range commits {
+			commits[i], secrets[i], _ = Commit(testRand)
+		}
+		aggR := mixed.AggregateCommit(commits)
+		for i := range commits {
+			parts[i] = Cosign(priKeys[i], secrets[i], msg, aggK, aggR)
+		}
+		want := []int{}
+		if !mixed.VerifyPart(msg, aggR, 2, commits[2], parts[2]) {
+			want = []int{2}
+		}
+		_, bad := mixed.VerifyParts(msg, aggR, commits, parts)
+		if len(want) == 0 && bad == nil {
+			continue
+		}
+		if !reflect.DeepEqual(bad, want) {
+			t.Errorf("torsioned key: got %v, want %v", bad, want)
+		}
+	}
+
+	// A bad part checked individually is not forgotten
+	// when a later part checked individually is good.
+	keys[1] = addTorsion(t, keys[1], 0)
+	mixed, _ = NewCosignersErr(keys, nil)
+	aggK = mixed.AggregatePublicKey()
+	for k := 0; ; k++ {
+		if k == 64 {
+			t.Fatalf("no valid part for a torsioned key found")
+		}
+		msg := []byte("bad then good " + strconv.Itoa(k))
+		for i := range commits {
+			commits[i], secrets[i], _ = Commit(testRand)
+		}
+		aggR := mixed.AggregateCommit(commits)
+		for i := range commits {
+			parts[i] = Cosign(priKeys[i], secrets[i], msg, aggK, aggR)
+		}
+		if !mixed.VerifyPart(msg, aggR, 2, commits[2], parts[2]) {
+			continue
+		}
+		parts[1][0] ^= 1
+		ok, bad := mixed.VerifyParts(msg, aggR, commits, parts)
+		if ok || !reflect.DeepEqual(bad, []int{1}) {
+			t.Errorf("bad then good torsioned part: got %v, %v; want false, [1]",
+				ok, bad)
+		}
+		break
+	}
+}
+
+// errWriter accepts up to n bytes, then fails.
+type errWriter struct{ n int }
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, io.ErrShortWrite
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestWriteSignature(t *testing.T) {
+	n := 20
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetMaskBit(13, Disabled)
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		if i != 13 {
+			parts[i] = Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		}
+	}
+
+	want := cos.AggregateSignature(aggR, parts)
+	var buf bytes.Buffer
+	written, err := cos.WriteSignature(&buf, aggR, parts)
+	if err != nil || written != len(want) || !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("WriteSignature wrote %d bytes %x, error %v; want %x",
+			written, buf.Bytes(), err, want)
+	}
+
+	// Writer errors propagate along with the count written.
+	written, err = cos.WriteSignature(&errWriter{40}, aggR, parts)
+	if err != io.ErrShortWrite || written != 40 {
+		t.Errorf("failing writer: got %d, %v", written, err)
+	}
+
+	buf.Reset()
+	parts[2] = parts[2][:31]
+	written, err = cos.WriteSignature(&buf, aggR, parts)
+	if pe, ok := err.(*PartError); !ok || pe.Index != 2 || written != 0 ||
+		buf.Len() != 0 {
+		t.Errorf("bad part: wrote %d bytes, error %v", written, err)
+	}
+}
+
+func TestAggregateCommitErr(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits := make([]Commitment, n)
+	for i := range commits {
+		commits[i], _, _ = Commit(testRand)
+	}
+	if _, err := cos.AggregateCommitErr(commits); err != nil {
+		t.Fatalf("valid commits rejected: %v", err)
+	}
+
+	// y = p, which decodes to a valid point but is not reduced.
+	nonCanonical := make([]byte, 32)
+	nonCanonical[0] = 0xed
+	for i := 1; i < 31; i++ {
+		nonCanonical[i] = 0xff
+	}
+	nonCanonical[31] = 0x7f
+
+	tests := []struct {
+		commit Commitment
+		err    error
+	}{
+		{commits[1][:31], ErrCommitLength},
+		{Commitment{}, ErrCommitLength},
+		{nonCanonical, ErrInvalidCommit},
+		{invalidPoint, ErrInvalidCommit},
+	}
+	for _, test := range tests {
+		bad := append([]Commitment{}, commits...)
+		bad[1] = test.commit
+		aggR, err := cos.AggregateCommitErr(bad)
+		ce, ok := err.(*CommitError)
+		if aggR != nil || !ok || ce.Index != 1 || ce.Err != test.err {
+			t.Errorf("commit %x: got error %v, want %v", test.commit, err, test.err)
+		}
+		if cos.AggregateCommit(bad) != nil {
+			t.Errorf("commit %x: AggregateCommit did not return nil", test.commit)
+		}
+
+		// A disabled cosigner's commit is ignored entirely.
+		cos.SetMaskBit(1, Disabled)
+		if _, err := cos.AggregateCommitErr(bad); err != nil {
+			t.Errorf("commit %x of disabled cosigner rejected: %v", test.commit, err)
+		}
+		cos.SetMaskBit(1, Enabled)
+	}
+}
+
+func TestCosignOnce(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+		if secrets[i].Used() {
+			t.Fatalf("fresh secret %d reports itself used", i)
+		}
+	}
+	aggR := cos.AggregateCommit(commits)
+	if !new(Secret).Used() {
+		t.Errorf("zero Secret reports itself unused")
+	}
+
+	// A failed attempt must leave the secret in place.
+	secret := secrets[0]
+	if _, err := CosignOnce(priKeys[0][:63], &secrets[0], rightMessage,
+		aggK, aggR); err != ErrPrivateKeyLength || secrets[0] != secret {
+		t.Errorf("failed CosignOnce: got %v, secret %p", err, secrets[0])
+	}
+
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		var err error
+		parts[i], err = CosignOnce(priKeys[i], &secrets[i], rightMessage,
+			aggK, aggR)
+		if err != nil || secrets[i] != nil {
+			t.Fatalf("CosignOnce %d: error %v, secret %p", i, err, secrets[i])
+		}
+	}
+	if !cos.Verify(rightMessage, cos.AggregateSignature(aggR, parts)) {
+		t.Errorf("signature from CosignOnce rejected")
+	}
+	if _, err := CosignOnce(priKeys[0], &secrets[0], rightMessage,
+		aggK, aggR); err != ErrSecretReused {
+		t.Errorf("CosignOnce with spent secret: got %v", err)
+	}
+
+	// The original pointer now refers to a used secret,
+	// which Cosign must refuse.
+	if !secret.Used() {
+		t.Errorf("spent secret reports itself unused")
+	}
+	expectPanic(t, "Cosign with a reused secret", func() {
+		Cosign(priKeys[0], secret, wrongMessage, aggK, aggR)
+	})
+	if _, err := CosignOnce(priKeys[0], &secret, wrongMessage,
+		aggK, aggR); err != ErrSecretReused || secret != nil {
+		t.Errorf("CosignOnce with used secret: got %v, secret %p",
+			err, secret)
+	}
+}
+
+func TestSetRand(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+
+	commits := func(seed int64) []Commitment {
+		cos.SetRand(rand.New(rand.NewSource(seed)))
+		c := make([]Commitment, n)
+		for i := range c {
+			var err error
+			if c[i], _, err = cos.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return c
+	}
+	a, b, c := commits(1), commits(1), commits(2)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed produced different commits")
+	}
+	if reflect.DeepEqual(a, c) || bytes.Equal(a[0], a[1]) {
+		t.Errorf("commits do not depend on the randomness")
+	}
+	if !reflect.DeepEqual(cos.Clone().rand, cos.rand) {
+		t.Errorf("Clone lost the source of randomness")
+	}
+
+	cos.SetRand(iotest.ErrReader(io.ErrUnexpectedEOF))
+	if _, _, err := cos.Commit(); err != io.ErrUnexpectedEOF {
+		t.Errorf("failing source: got %v", err)
+	}
+	cos.SetRand(testRand)
+	if _, _, err := cos.Commit(); err != nil {
+		t.Errorf("default source: %v", err)
+	}
+}
+
+func TestCheckAggregateCommit(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	commits := make([]Commitment, n)
+	for i := range commits {
+		commits[i], _, _ = Commit(testRand)
+	}
+	cos.SetMaskBit(1, Disabled)
+	aggR := cos.AggregateCommit(commits)
+
+	if !cos.CheckAggregateCommit(commits, aggR) {
+		t.Errorf("correct aggregate commit rejected")
+	}
+
+	tampered := append([]byte{}, aggR...)
+	tampered[0] ^= 1
+	if cos.CheckAggregateCommit(commits, tampered) {
+		t.Errorf("tampered aggregate commit accepted")
+	}
+	if cos.CheckAggregateCommit(commits, aggR[:31]) {
+		t.Errorf("truncated aggregate commit accepted")
+	}
+	if cos.CheckAggregateCommit(commits[:n-1], aggR) {
+		t.Errorf("aggregate commit accepted with missing commits")
+	}
+
+	// An aggregate over a different set of signers must not match.
+	cos.SetMaskBit(1, Enabled)
+	if cos.CheckAggregateCommit(commits, aggR) {
+		t.Errorf("aggregate commit accepted under a different mask")
+	}
+}
+
+func TestVerifyAggregateContext(t *testing.T) {
+	n := 6
+	genKeys(n)
+	claimed := []byte{0x24} // cosigners 2 and 5 disabled
+	leader, _ := NewCosignersErr(pubKeys[:n], claimed)
+	commits := make([]Commitment, n)
+	for i := range commits {
+		commits[i], _, _ = Commit(testRand)
+	}
+	aggR := leader.AggregateCommit(commits)
+	aggK := leader.AggregatePublicKey()
+
+	cosigner, _ := NewCosignersErr(pubKeys[:n], claimed)
+	if !VerifyAggregateContext(cosigner, commits, aggR, aggK) {
+		t.Errorf("consistent context rejected")
+	}
+
+	// A leader that announces one mask but uses another.
+	other, _ := NewCosignersErr(pubKeys[:n], []byte{0x04})
+	otherR, otherK := other.AggregateCommit(commits), other.AggregatePublicKey()
+	if VerifyAggregateContext(cosigner, commits, otherR, otherK) {
+		t.Errorf("context for a different mask accepted")
+	}
+	if VerifyAggregateContext(cosigner, commits, aggR, otherK) {
+		t.Errorf("aggregate key for a different mask accepted")
+	}
+	if VerifyAggregateContext(cosigner, commits, otherR, aggK) {
+		t.Errorf("aggregate commit for a different mask accepted")
+	}
+
+	// A leader that shows the cosigner different commits.
+	shown := append([]Commitment{}, commits...)
+	shown[0], _, _ = Commit(testRand)
+	if VerifyAggregateContext(cosigner, shown, aggR, aggK) {
+		t.Errorf("context for different commits accepted")
+	}
+	if VerifyAggregateContext(cosigner, commits[:n-1], aggR, aggK) {
+		t.Errorf("short commit list accepted")
+	}
+}
+
+func TestValidateCommitment(t *testing.T) {
+	commit, _, _ := Commit(testRand)
+	if err := ValidateCommitment(commit); err != nil {
+		t.Errorf("valid commitment rejected: %v", err)
+	}
+
+	identity := make([]byte, 32)
+	identity[0] = 1
+	negIdentity := append([]byte{}, identity...)
+	negIdentity[31] = 0x80
+
+	// y = p+1, an unreduced encoding of the identity.
+	nonCanonical := bytes.Repeat([]byte{0xff}, 32)
+	nonCanonical[0] = 0xee
+	nonCanonical[31] = 0x7f
+
+	tests := []struct {
+		commit Commitment
+		err    error
+	}{
+		{identity, ErrInvalidCommit},
+		{negIdentity, ErrInvalidCommit},
+		{nonCanonical, ErrInvalidCommit},
+		{invalidPoint, ErrInvalidCommit},
+		{commit[:31], ErrCommitLength},
+		{append(commit[:32:32], 0), ErrCommitLength},
+		{nil, ErrCommitLength},
+	}
+	for _, test := range tests {
+		if err := ValidateCommitment(test.commit); err != test.err {
+			t.Errorf("commit %x: got %v, want %v", test.commit, err, test.err)
+		}
+	}
+}
+
+func TestCommitBatch(t *testing.T) {
+	n := 8
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+
+	commits, secrets, err := CommitBatch(testRand, n)
+	if err != nil || len(commits) != n || len(secrets) != n {
+		t.Fatalf("CommitBatch: %d commits, %d secrets, error %v",
+			len(commits), len(secrets), err)
+	}
+	seen := make(map[string]bool)
+	for i := range commits {
+		if seen[string(commits[i])] || seen[string(secrets[i].reduced[:])] {
+			t.Fatalf("commitment or secret %d repeated", i)
+		}
+		seen[string(commits[i])] = true
+		seen[string(secrets[i].reduced[:])] = true
+		if err := ValidateCommitment(commits[i]); err != nil {
+			t.Errorf("commitment %d invalid: %v", i, err)
+		}
+	}
+
+	// The batch secrets must produce a valid signature,
+	// and each must be invalidated by its use.
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		parts[i], err = CosignErr(priKeys[i], secrets[i], rightMessage,
+			aggK, aggR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = CosignErr(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		if err != ErrSecretReused {
+			t.Errorf("secret %d reuse: got %v, want ErrSecretReused", i, err)
+		}
+	}
+	sig := cos.AggregateSignature(aggR, parts)
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("signature from batch commitments rejected")
+	}
+
+	if _, _, err := CommitBatch(constReader{0}, 0); err != nil {
+		t.Errorf("CommitBatch(0): %v", err)
+	}
+}
+
+func TestSetHash(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+
+	// A domain-separated variant of SHA-512.
+	customHash := func() hash.Hash {
+		h := sha512.New()
+		h.Write([]byte("custom challenge"))
+		return h
+	}
+	if err := cos.SetHash(customHash); err != nil {
+		t.Fatal(err)
+	}
+	aggK := cos.AggregatePublicKey()
+
+	cosign := func(newHash func() hash.Hash) []byte {
+		commits := make([]Commitment, n)
+		secrets := make([]*Secret, n)
+		for i := range commits {
+			commits[i], secrets[i], _ = Commit(testRand)
+		}
+		aggR := cos.AggregateCommit(commits)
+		parts := make([]SignaturePart, n)
+		for i := range parts {
+			var err error
+			parts[i], err = CosignHash(newHash, priKeys[i], secrets[i],
+				rightMessage, aggK, aggR)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cos.AggregateSignature(aggR, parts)
+	}
+
+	sig := cosign(customHash)
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("signature with matching custom hash rejected")
+	}
+	if cos.Verify(wrongMessage, sig) {
+		t.Errorf("signature with custom hash accepted on wrong message")
+	}
+	if ok, _ := cos.VerifyBatch([][]byte{rightMessage}, [][]byte{sig}); !ok {
+		t.Errorf("batch verification ignored custom hash")
+	}
+	if !cos.Clone().Verify(rightMessage, sig) {
+		t.Errorf("Clone lost custom hash")
+	}
+
+	// Mismatched hashes on the signing and verifying sides must fail.
+	if cos.Verify(rightMessage, cosign(nil)) {
+		t.Errorf("SHA-512 signature accepted by custom-hash verifier")
+	}
+	cos.SetHash(nil)
+	if cos.Verify(rightMessage, sig) {
+		t.Errorf("custom-hash signature accepted by SHA-512 verifier")
+	}
+	if !cos.Verify(rightMessage, cosign(nil)) {
+		t.Errorf("default hash not restored")
+	}
+
+	if err := cos.SetHash(sha256.New); err != ErrHashSize {
+		t.Errorf("SetHash(sha256.New): got %v, want ErrHashSize", err)
+	}
+	_, secret, _ := Commit(testRand)
+	_, err := CosignHash(sha256.New, priKeys[0], secret, rightMessage,
+		aggK, Commitment(aggK))
+	if err != ErrHashSize {
+		t.Errorf("CosignHash(sha256.New): got %v, want ErrHashSize", err)
+	}
+}
+
+func TestVerifyWithOrderings(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x02})
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	// The signing order, its reverse, and one with keys 0 and 1 swapped,
+	// under which the mask names cosigner 0 as the absent one.
+	signed := pubKeys[:n]
+	reversed := []ed25519.PublicKey{pubKeys[3], pubKeys[2], pubKeys[1],
+		pubKeys[0]}
+	swapped := []ed25519.PublicKey{pubKeys[1], pubKeys[0], pubKeys[2],
+		pubKeys[3]}
+	orderings := [][]ed25519.PublicKey{reversed, swapped, signed}
+
+	policy := WithPolicy(ThresholdPolicy(3))
+	if ok, i := VerifyWithOrderings(rightMessage, sig, orderings,
+		policy); !ok || i != 2 {
+		t.Errorf("VerifyWithOrderings = %v, %d; want true, 2", ok, i)
+	}
+	if ok, i := VerifyWithOrderings(rightMessage, sig, orderings[:2],
+		policy); ok || i != -1 {
+		t.Errorf("without the signing order: %v, %d; want false, -1", ok, i)
+	}
+	if ok, i := VerifyWithOrderings(wrongMessage, sig, orderings,
+		policy); ok || i != -1 {
+		t.Errorf("wrong message: %v, %d; want false, -1", ok, i)
+	}
+	if ok, _ := VerifyWithOrderings(rightMessage, sig, nil, policy); ok {
+		t.Errorf("signature accepted with no orderings")
+	}
+
+	// Without a policy option, every cosigner must have participated.
+	if ok, _ := VerifyWithOrderings(rightMessage, sig, orderings); ok {
+		t.Errorf("partial signature accepted under the default policy")
+	}
+	// A full signature verifies under any ordering, so the first is used.
+	full := testCosign(t, rightMessage, priKeys[:n],
+		NewCosigners(pubKeys[:n], nil))
+	if ok, i := VerifyWithOrderings(rightMessage, full, orderings); !ok || i != 0 {
+		t.Errorf("full signature: %v, %d; want true, 0", ok, i)
+	}
+}
+
+func TestVerifyDebug(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	ok, expectedR, gotR := cos.VerifyDebug(rightMessage, sig)
+	if !ok || expectedR != gotR || !bytes.Equal(gotR[:], sig[:32]) {
+		t.Errorf("valid signature: ok %v, expected R %x, got R %x",
+			ok, expectedR, gotR)
+	}
+
+	tampered := append([]byte{}, sig...)
+	tampered[40] ^= 1
+	ok, expectedR, gotR = cos.VerifyDebug(rightMessage, tampered)
+	if ok || expectedR == gotR || !bytes.Equal(gotR[:], sig[:32]) {
+		t.Errorf("tampered signature: ok %v, expected R %x, got R %x",
+			ok, expectedR, gotR)
+	}
+	if ok, expectedR, gotR = cos.VerifyDebug(wrongMessage, sig); ok ||
+		expectedR == gotR {
+		t.Errorf("wrong message: ok %v, expected R %x, got R %x",
+			ok, expectedR, gotR)
+	}
+
+	// A signature failing only the Policy has matching commits.
+	cos.SetMaskBit(0, Disabled)
+	partial := testCosign(t, rightMessage, priKeys[:n], cos)
+	if ok, expectedR, gotR = cos.VerifyDebug(rightMessage, partial); ok ||
+		expectedR != gotR {
+		t.Errorf("insufficient signers: ok %v, expected R %x, got R %x",
+			ok, expectedR, gotR)
+	}
 
-func genKeys(n int) {
-	for len(priKeys) < n {
-		i := len(priKeys)
-		pub, pri, _ := ed25519.GenerateKey(constReader{byte(i)})
-		pubKeys = append(pubKeys, pub)
-		priKeys = append(priKeys, pri)
+	if ok, expectedR, _ = cos.VerifyDebug(rightMessage, sig[:64]); ok ||
+		expectedR != ([32]byte{}) {
+		t.Errorf("malformed signature: ok %v, expected R %x", ok, expectedR)
 	}
 }
 
-func testCosign(tb testing.TB, message []byte, priKey []ed25519.PrivateKey,
-	cos *Cosigners) []byte {
+func TestVerifyDetailed(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetMaskBit(3, Disabled)
+	sig := cosignWith(t, cos, func(i int, secret *Secret,
+		aggK, aggR []byte) SignaturePart {
+		return Cosign(priKeys[i], secret, rightMessage, aggK, aggR)
+	})
+	tampered := append([]byte{}, sig...)
+	tampered[33] ^= 1
 
-	n := len(priKey)
+	tests := []struct {
+		policy             Policy
+		sig                []byte
+		cryptoOK, policyOK bool
+		err                error
+	}{
+		{ThresholdPolicy(4), sig, true, true, nil},
+		{ThresholdPolicy(5), sig, true, false, nil},
+		{ThresholdPolicy(4), tampered, false, true, nil},
+		{ThresholdPolicy(5), tampered, false, false, nil},
+		{ThresholdPolicy(4), sig[:len(sig)-1], false, false, ErrSignatureLength},
+	}
+	for i, test := range tests {
+		cos.SetPolicy(test.policy)
+		cos.SetMask(nil)
+		cryptoOK, policyOK, err := cos.VerifyDetailed(rightMessage, test.sig)
+		if cryptoOK != test.cryptoOK || policyOK != test.policyOK ||
+			err != test.err {
+			t.Errorf("test %d: got (%v, %v, %v), want (%v, %v, %v)", i,
+				cryptoOK, policyOK, err,
+				test.cryptoOK, test.policyOK, test.err)
+		}
+		if err == nil && !reflect.DeepEqual(cos.DisabledSigners(), []int{3}) {
+			t.Errorf("test %d: mask not updated: %x", i, cos.Mask())
+		}
+		if (cryptoOK && policyOK) != cos.Verify(rightMessage, test.sig) {
+			t.Errorf("test %d: disagrees with Verify", i)
+		}
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetMaskBit(1, Disabled)
+	sig := cosignWith(t, cos, func(i int, secret *Secret,
+		aggK, aggR []byte) SignaturePart {
+		return Cosign(priKeys[i], secret, rightMessage, aggK, aggR)
+	})
+
+	// Check [S]B == R + [c]K by hand.
+	check := func(message []byte) bool {
+		c := cos.Challenge(message, sig[:32])
+		var K, R edwards25519.ExtendedGroupElement
+		var KBytes, RBytes, S [32]byte
+		copy(KBytes[:], cos.AggregatePublicKey())
+		copy(RBytes[:], sig[:32])
+		copy(S[:], sig[32:64])
+		if !K.FromBytes(&KBytes) || !R.FromBytes(&RBytes) {
+			t.Fatal("aggregate key or commit does not decode")
+		}
+		var SB, RcK edwards25519.ExtendedGroupElement
+		var zero, RBack [32]byte
+		var proj edwards25519.ProjectiveGroupElement
+		edwards25519.GeScalarMultBase(&SB, &S)
+		edwards25519.GeDoubleScalarMultVartime(&proj, &c, &K, &zero)
+		proj.ToExtended(&RcK)
+		RcK.Add(&RcK, &R)
+		var SBBytes [32]byte
+		SB.ToBytes(&SBBytes)
+		RcK.ToBytes(&RBack)
+		return SBBytes == RBack
+	}
+	if !check(rightMessage) {
+		t.Errorf("challenge does not reproduce a valid verification")
+	}
+	if check(wrongMessage) {
+		t.Errorf("challenge on wrong message verifies")
+	}
+}
+
+func TestVerifyAggregate(t *testing.T) {
+	n := 6
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetPolicy(ThresholdPolicy(0))
+	for _, mask := range [][]byte{{0xc1}, {0xc5}, {0xfe}} {
+		cos.SetMask(mask)
+		aggK := cos.AggregatePublicKey()
+		sig := cosignWith(t, cos, func(i int, secret *Secret,
+			k, r []byte) SignaturePart {
+			return Cosign(priKeys[i], secret, rightMessage, k, r)
+		})
+
+		for _, msg := range [][]byte{rightMessage, wrongMessage} {
+			if VerifyAggregate(msg, sig, aggK) != cos.Verify(msg, sig) {
+				t.Errorf("mask %x: VerifyAggregate disagrees with Verify",
+					mask)
+			}
+		}
+		if !VerifyAggregate(rightMessage, sig[:64], aggK) {
+			t.Errorf("mask %x: signature without mask rejected", mask)
+		}
+
+		// Any other aggregate key must fail.
+		cos.SetMask(nil)
+		if VerifyAggregate(rightMessage, sig, cos.AggregatePublicKey()) {
+			t.Errorf("mask %x: signature accepted under wrong key", mask)
+		}
+		if VerifyAggregate(rightMessage, sig, invalidPoint) ||
+			VerifyAggregate(rightMessage, sig, aggK[:31]) ||
+			VerifyAggregate(rightMessage, sig[:63], aggK) {
+			t.Errorf("mask %x: malformed input accepted", mask)
+		}
+	}
+}
+
+// addOrder returns the 32-byte little-endian scalar s + l,
+// which is congruent to s but not reduced.
+func addOrder(s []byte) []byte {
+	sum := make([]byte, 32)
+	carry := 0
+	for i := range sum {
+		v := int(s[i]) + int(groupOrder[i]) + carry
+		sum[i] = byte(v)
+		carry = v >> 8
+	}
+	return sum
+}
+
+func TestValidateSignatureForm(t *testing.T) {
+	scalar := func(s [32]byte, delta int) []byte {
+		sig := make([]byte, 64+1)
+		carry := delta
+		for i := range s {
+			v := int(s[i]) + carry
+			sig[32+i] = byte(v)
+			carry = v >> 8
+		}
+		return sig
+	}
+	var top [32]byte // 2^253-1, which passes a check of the top 3 bits
+	for i := range top {
+		top[i] = 0xff
+	}
+	top[31] = 0x1f
+
+	tests := []struct {
+		sig     []byte
+		maskLen int
+		err     error
+	}{
+		{scalar([32]byte{}, 0), 1, nil},
+		{scalar(groupOrder, -1), 1, nil},
+		{scalar(groupOrder, 0), 1, ErrSignatureScalar},
+		{scalar(groupOrder, 1), 1, ErrSignatureScalar},
+		{scalar(top, 0), 1, ErrSignatureScalar},
+		{scalar(groupOrder, -1), 0, ErrSignatureLength},
+		{scalar(groupOrder, -1), 2, ErrSignatureLength},
+		{nil, 0, ErrSignatureLength},
+	}
+	for i, test := range tests {
+		if err := validateSignatureForm(test.sig, test.maskLen); err != test.err {
+			t.Errorf("test %d: got %v, want %v", i, err, test.err)
+		}
+	}
+
+	// Adding the group order to S yields a signature
+	// that a top-bits check alone would accept.
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+	malleated := append([]byte{}, sig...)
+	copy(malleated[32:64], addOrder(sig[32:64]))
+	if malleated[63]&224 != 0 {
+		t.Fatalf("malleated S out of range: %x", malleated[32:64])
+	}
+	if !cos.Verify(rightMessage, sig) {
+		t.Fatalf("valid signature rejected")
+	}
+	if cos.Verify(rightMessage, malleated) ||
+		cos.VerifyCofactored(rightMessage, malleated) ||
+		cos.VerifyConstantTime(rightMessage, malleated) ||
+		VerifyAggregate(rightMessage, malleated, cos.AggregatePublicKey()) {
+		t.Errorf("signature with S+l accepted")
+	}
+	if ok, _ := cos.VerifyBatch([][]byte{rightMessage}, [][]byte{malleated}); ok {
+		t.Errorf("signature with S+l accepted in batch")
+	}
+	if _, _, err := cos.VerifyDetailed(rightMessage, malleated); err != ErrSignatureScalar {
+		t.Errorf("VerifyDetailed: got %v, want ErrSignatureScalar", err)
+	}
+}
 
+func TestVerifyPartRange(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
 	aggK := cos.AggregatePublicKey()
+	c, s, _ := Commit(testRand)
+	part := Cosign(priKeys[0], s, rightMessage, aggK, c)
+	if !cos.VerifyPart(rightMessage, c, 0, c, part) {
+		t.Fatalf("valid part rejected")
+	}
+	for _, i := range []int{-1, n, 1 << 30} {
+		if cos.VerifyPart(rightMessage, c, i, c, part) {
+			t.Errorf("part accepted for signer index %d", i)
+		}
+	}
+}
 
-	// Create the individual commits and corresponding secrets
-	// (these would be done by the individual participants in practice)
-	commit := make([]Commitment, n)
-	secret := make([]*Secret, n)
-	for i := range commit {
-		commit[i], secret[i], _ = Commit(nil)
+func TestVerifyPartStandalone(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	for i := range commits {
+		part := Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		bad := append([]byte{}, part...)
+		bad[5] ^= 1
+		for _, test := range []struct {
+			msg    []byte
+			signer int
+			part   []byte
+		}{
+			{rightMessage, i, part},
+			{wrongMessage, i, part},
+			{rightMessage, i, bad},
+			{rightMessage, (i + 1) % n, part},
+		} {
+			want := cos.VerifyPart(test.msg, aggR, test.signer, commits[i],
+				test.part)
+			got := VerifyPartStandalone(pubKeys[test.signer], test.msg,
+				aggK, aggR, commits[i], test.part)
+			if got != want {
+				t.Errorf("part %d as signer %d: VerifyPartStandalone = %v, "+
+					"VerifyPart = %v", i, test.signer, got, want)
+			}
+		}
+		if VerifyPartStandalone(pubKeys[i], rightMessage, aggK[:31], aggR,
+			commits[i], part) {
+			t.Errorf("part %d accepted with short aggregate key", i)
+		}
+		if VerifyPartStandalone(invalidPoint, rightMessage, aggK, aggR,
+			commits[i], part) {
+			t.Errorf("part %d accepted with invalid public key", i)
+		}
 	}
+}
 
-	// Leader: combine the individual commits into an aggregate commit
-	aggR := cos.AggregateCommit(commit)
+func TestVerifyPartMalleability(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	for i := range commits {
+		part := Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		if !cos.VerifyPart(rightMessage, aggR, i, commits[i], part) {
+			t.Fatalf("valid part %d rejected", i)
+		}
 
-	// Create the individual signature parts
-	sigpart := make([]SignaturePart, n)
-	for i := range sigpart {
-		sigpart[i] = Cosign(priKey[i], secret[i], message, aggK, aggR)
+		// S+l is congruent to S and passes a top-bits check,
+		// but is not canonical.
+		malleated := addOrder(part)
+		if malleated[31]&224 != 0 {
+			t.Fatalf("malleated part out of range: %x", malleated)
+		}
+		if cos.VerifyPart(rightMessage, aggR, i, commits[i], malleated) {
+			t.Errorf("part %d with S+l accepted", i)
+		}
+	}
+}
 
-		// verify each part individually
-		if !cos.VerifyPart(message, aggR, i, commit[i], sigpart[i]) {
-			tb.Errorf("signature part %d rejected ", i)
+func TestVerifyConstantTime(t *testing.T) {
+	n := 11
+	genKeys(n)
+	signer, _ := NewCosignersErr(pubKeys[:n], nil)
+	a, _ := NewCosignersErr(pubKeys[:n], nil)
+	b, _ := NewCosignersErr(pubKeys[:n], nil)
+	a.SetPolicy(ThresholdPolicy(7))
+	b.SetPolicy(ThresholdPolicy(7))
+
+	accepted := 0
+	rnd := rand.New(rand.NewSource(2))
+	for iter := 0; iter < 40; iter++ {
+		mask := make([]byte, signer.MaskLen())
+		rnd.Read(mask)
+		signer.SetMask(mask)
+		sig := cosignWith(t, signer, func(i int, secret *Secret,
+			aggK, aggR []byte) SignaturePart {
+			return Cosign(priKeys[i], secret, rightMessage, aggK, aggR)
+		})
+
+		tests := [][]byte{sig}
+		bad := append([]byte{}, sig...)
+		bad[40] ^= 1
+		tests = append(tests, bad, sig[:len(sig)-1])
+		for j, test := range tests {
+			for _, msg := range [][]byte{rightMessage, wrongMessage} {
+				want := a.Verify(msg, test)
+				got := b.VerifyConstantTime(msg, test)
+				if got != want {
+					t.Fatalf("iteration %d.%d: VerifyConstantTime = %v, "+
+						"Verify = %v", iter, j, got, want)
+				}
+				if got {
+					accepted++
+				}
+				if !bytes.Equal(a.Mask(), b.Mask()) ||
+					!bytes.Equal(a.AggregatePublicKey(),
+						b.AggregatePublicKey()) ||
+					a.CountEnabled() != b.CountEnabled() {
+					t.Fatalf("iteration %d.%d: resulting state differs",
+						iter, j)
+				}
+			}
 		}
 	}
+	if accepted == 0 {
+		t.Errorf("no signature satisfied the policy")
+	}
+}
 
-	// Leader: combine the signature parts into a collective signature
-	sig := cos.AggregateSignature(aggR, sigpart)
-	return sig
+func TestVerifyCofactored(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+	if !cos.VerifyCofactored(rightMessage, sig) {
+		t.Errorf("valid signature rejected")
+	}
+	if cos.VerifyCofactored(wrongMessage, sig) {
+		t.Errorf("signature on wrong message accepted")
+	}
+
+	// Have one cosigner add a point of small order to its commit.
+	// The resulting signature satisfies only the cofactored equation.
+	for _, point := range smallOrderPoints[1:] {
+		var T, R edwards25519.ExtendedGroupElement
+		var b [32]byte
+		tb, _ := hex.DecodeString(point)
+		copy(b[:], tb)
+		T.FromBytes(&b)
+
+		aggK := cos.AggregatePublicKey()
+		commits := make([]Commitment, n)
+		secrets := make([]*Secret, n)
+		for i := range commits {
+			commits[i], secrets[i], _ = Commit(testRand)
+		}
+		copy(b[:], commits[0])
+		R.FromBytes(&b)
+		R.Add(&R, &T)
+		R.ToBytes(&b)
+		commits[0] = b[:]
+
+		aggR := cos.AggregateCommit(commits)
+		parts := make([]SignaturePart, n)
+		for i := range parts {
+			parts[i] = Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		}
+		sig := cos.AggregateSignature(aggR, parts)
+
+		if cos.Verify(rightMessage, sig) {
+			t.Errorf("point %s: strict verification accepted torsioned R", point)
+		}
+		if !cos.VerifyCofactored(rightMessage, sig) {
+			t.Errorf("point %s: cofactored verification rejected torsioned R", point)
+		}
+		if cos.VerifyCofactored(wrongMessage, sig) {
+			t.Errorf("point %s: signature on wrong message accepted", point)
+		}
+	}
 }
 
-func TestSignVerify(t *testing.T) {
+func TestStream(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	// Create a number of distinct keypairs
+	message := bytes.Repeat([]byte("streamed message "), 1<<16)
+	sig := testCosign(t, message, priKeys[:n], cos)
+
+	if !cos.Verify(message, sig) {
+		t.Errorf("valid signature rejected")
+	}
+	if !cos.VerifyStream(iotest.HalfReader(bytes.NewReader(message)), sig) {
+		t.Errorf("valid signature rejected by VerifyStream")
+	}
+	if cos.VerifyStream(bytes.NewReader(message[1:]), sig) {
+		t.Errorf("VerifyStream accepted signature on different message")
+	}
+	if cos.VerifyStream(iotest.TimeoutReader(bytes.NewReader(message)), sig) {
+		t.Errorf("VerifyStream accepted signature despite read error")
+	}
+
+	// Streamed and buffered cosigning must produce identical parts.
+	aggK := cos.AggregatePublicKey()
+	commit, secret, _ := Commit(constReader{1})
+	commits := []Commitment{commit, commit, commit, commit, commit}
+	aggR := cos.AggregateCommit(commits)
+	part := Cosign(priKeys[0], secret, message, aggK, aggR)
+
+	_, secret, _ = Commit(constReader{1})
+	_, err = CosignStream(priKeys[0], secret,
+		iotest.TimeoutReader(bytes.NewReader(message)), aggK, aggR)
+	if err == nil {
+		t.Errorf("CosignStream ignored read error")
+	}
+	streamPart, err := CosignStream(priKeys[0], secret,
+		iotest.OneByteReader(bytes.NewReader(message[:1000])), aggK, aggR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(part, streamPart) {
+		t.Errorf("signature parts on different messages match")
+	}
+
+	_, secret, _ = Commit(constReader{1})
+	streamPart, err = CosignStream(priKeys[0], secret,
+		bytes.NewReader(message), aggK, aggR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(part, streamPart) {
+		t.Errorf("streamed and buffered signature parts differ")
+	}
+}
+
+func TestMulti(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	header, body := []byte("header: value\r\n\r\n"), rightMessage
+	message := append(append([]byte{}, header...), body...)
+	parts := [][]byte{header, nil, body}
+
+	sig := testCosign(t, message, priKeys[:n], cos)
+	if !cos.VerifyMulti(parts, sig) {
+		t.Errorf("multi-part verification rejected a valid signature")
+	}
+	if cos.VerifyMulti([][]byte{body, header}, sig) {
+		t.Errorf("multi-part verification accepted reordered fragments")
+	}
+	if !cos.VerifyMulti([][]byte{message}, sig) || cos.VerifyMulti(nil, sig) {
+		t.Errorf("single-fragment verification differs from Verify")
+	}
+
+	// Multi-part and concatenated cosigning must produce identical parts,
+	// and hence identical signatures.
+	sig = cosignWith(t, cos, func(i int, secret *Secret, aggK, aggR []byte) SignaturePart {
+		part, err := CosignMulti(priKeys[i], secret, parts, aggK, aggR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return part
+	})
+	if !cos.Verify(message, sig) {
+		t.Errorf("multi-part signature rejected by Verify")
+	}
+
+	aggK := cos.AggregatePublicKey()
+	c, secret, _ := Commit(constReader{2})
+	aggR := cos.AggregateCommit([]Commitment{c, c, c, c})
+	part := Cosign(priKeys[0], secret, message, aggK, aggR)
+	_, secret, _ = Commit(constReader{2})
+	multiPart, _ := CosignMulti(priKeys[0], secret, parts, aggK, aggR)
+	if !bytes.Equal(part, multiPart) {
+		t.Errorf("multi-part and concatenated signature parts differ")
+	}
+	if _, err := CosignMulti(priKeys[0], secret, parts, aggK, aggR); err != ErrSecretReused {
+		t.Errorf("reused secret: got %v", err)
+	}
+}
+
+func TestVerifySplit(t *testing.T) {
 	n := 10
 	genKeys(n)
-	cosigners := NewCosigners(pubKeys[:n], nil) // all enabled by default
-	if cosigners.CountTotal() != n {
-		t.Errorf("cosigners reports incorrect number of public keys")
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x21, 0x00})
+	cos.SetPolicy(ThresholdPolicy(n - 2))
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	cases := []struct {
+		message, core, mask []byte
+	}{
+		{rightMessage, sig[:64], sig[64:]},
+		{wrongMessage, sig[:64], sig[64:]},
+		{rightMessage, sig[:64], []byte{0x20, 0x00}},
+		{rightMessage, sig[:64], []byte{0x23, 0x00}},
+		{rightMessage, sig[:64], sig[64:65]},
+		{rightMessage, sig[:63], sig[64:]},
+		{rightMessage, sig[1:], sig[64:]},
 	}
-	if cosigners.CountEnabled() != n {
-		t.Errorf("cosigners reports incorrect number of enabled keys")
+	for i, c := range cases {
+		joined := append(append([]byte{}, c.core...), c.mask...)
+		want := cos.Verify(c.message, joined)
+		if got := cos.VerifySplit(c.message, c.core, c.mask); got != want {
+			t.Errorf("case %d: VerifySplit returned %v, Verify %v",
+				i, got, want)
+		}
+	}
+	if !cos.VerifySplit(rightMessage, sig[:64], sig[64:]) {
+		t.Errorf("valid split signature rejected")
 	}
+	if cos.MaskBit(0) != Disabled || cos.MaskBit(1) != Enabled {
+		t.Errorf("VerifySplit did not set the mask")
+	}
+}
 
-	// collectively sign a test message
-	sig := testCosign(t, rightMessage, priKeys[:n], cosigners)
-	if !cosigners.Verify(rightMessage, sig) {
-		t.Errorf("valid signature rejected")
+// TestArrivalOrder checks that aggregation depends only on
+// which commit or part sits at which index,
+// not on the order in which concurrently-arriving entries were stored,
+// with both serial and parallel summation.
+func TestArrivalOrder(t *testing.T) {
+	n := 37
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetMaskBit(5, Disabled)
+	cos.SetMaskBit(30, Disabled)
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		if cos.MaskBit(i) == Enabled {
+			parts[i] = Cosign(priKeys[i], secrets[i], rightMessage,
+				aggK, aggR)
+		}
+	}
+	sig := cos.AggregateSignature(aggR, parts)
+	if !cos.VerifyWithPolicy(rightMessage, sig, ThresholdPolicy(n-2)) {
+		t.Fatalf("reference signature rejected")
 	}
 
-	if cosigners.Verify(wrongMessage, sig) {
-		t.Errorf("signature of different message accepted")
+	rng := rand.New(rand.NewSource(1))
+	for _, threshold := range []int{0, 1} {
+		cos.SetParallelThreshold(threshold)
+		for trial := 0; trial < 5; trial++ {
+			// Store each entry from its own goroutine,
+			// started in a random order.
+			order := rng.Perm(n)
+			gotCommits := make([]Commitment, n)
+			gotParts := make([]SignaturePart, n)
+			var wg sync.WaitGroup
+			for _, i := range order {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					gotCommits[i] = commits[i]
+					gotParts[i] = parts[i]
+				}(i)
+			}
+			wg.Wait()
+
+			if got := cos.AggregateCommit(gotCommits); !bytes.Equal(got, aggR) {
+				t.Errorf("threshold %d, order %v: aggregate commit differs",
+					threshold, order)
+			}
+			got := cos.AggregateSignature(aggR, gotParts)
+			if !bytes.Equal(got, sig) {
+				t.Errorf("threshold %d, order %v: signature differs",
+					threshold, order)
+			}
+
+			acc := cos.NewAccumulator()
+			for _, i := range order {
+				if cos.MaskBit(i) == Enabled {
+					acc.AddPart(i, parts[i])
+				}
+			}
+			if got, _ := acc.Finalize(aggR); !bytes.Equal(got, sig) {
+				t.Errorf("order %v: accumulated signature differs", order)
+			}
+		}
 	}
+}
 
-	// now collectively sign with only a partial cosigners set
-	cosigners.SetMaskBit(5, Disabled)
-	if cosigners.CountEnabled() != n-1 {
-		t.Errorf("cosigners reports incorrect number of enabled keys")
+func TestVerifyLenient(t *testing.T) {
+	n := 7
+	genKeys(n + 10)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x02})
+	cos.SetPolicy(ThresholdPolicy(n - 1))
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	// Grow the group to 17 cosigners, and a 3-byte mask.
+	for i := n; i < n+10; i++ {
+		if err := cos.AppendCosigner(pubKeys[i]); err != nil {
+			t.Fatal(err)
+		}
 	}
-	sig = testCosign(t, rightMessage, priKeys[:n], cosigners)
-	if cosigners.Verify(rightMessage, sig) {
-		t.Errorf("signature with too few cosigners accepted")
+	if cos.Verify(rightMessage, sig) {
+		t.Errorf("Verify accepted a short mask")
+	}
+	if !cos.VerifyLenient(rightMessage, sig) {
+		t.Errorf("VerifyLenient rejected a short mask")
+	}
+	if cos.CountEnabled() != n-1 || cos.MaskBit(1) != Disabled ||
+		cos.MaskBit(n) != Disabled || cos.MaskBit(n+9) != Disabled {
+		t.Errorf("cosigners missing from the mask not disabled: %x",
+			cos.Mask())
+	}
+	if cos.VerifyLenient(wrongMessage, sig) {
+		t.Errorf("VerifyLenient accepted the wrong message")
 	}
 
-	// now reduce the verification threshold
-	cosigners.SetPolicy(ThresholdPolicy(n - 1))
-	if !cosigners.Verify(rightMessage, sig) {
-		t.Errorf("valid threshold not accepted")
+	// The same signature with explicit all-disabled trailing bytes.
+	full := append(append([]byte{}, sig...), 0xff, 0xff)
+	if !cos.VerifyLenient(rightMessage, full) ||
+		!cos.Verify(rightMessage, full) {
+		t.Errorf("fully-padded signature rejected")
 	}
 
-	// now remove another cosigner and make sure it breaks again
-	cosigners.SetMaskBit(7, Disabled)
-	if cosigners.CountEnabled() != n-2 {
-		t.Errorf("cosigners reports incorrect number of enabled keys")
+	// The policy applies to the current group.
+	cos.SetPolicy(QuorumPolicy(1, 2))
+	if cos.VerifyLenient(rightMessage, sig) {
+		t.Errorf("old signature satisfied the grown group's quorum")
 	}
-	sig = testCosign(t, rightMessage, priKeys[:n], cosigners)
-	if cosigners.Verify(rightMessage, sig) {
-		t.Errorf("signature with too few cosigners accepted")
+
+	cos.SetPolicy(ThresholdPolicy(0))
+	if cos.VerifyLenient(rightMessage, sig[:63]) {
+		t.Errorf("truncated signature accepted")
+	}
+	if cos.VerifyLenient(rightMessage, append(full, 0xff)) {
+		t.Errorf("overlong mask accepted")
+	}
+}
+
+func TestAggregateCommitFunc(t *testing.T) {
+	n := 20
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x81, 0x00, 0x04})
+	commits := make([]Commitment, n)
+	for i := range commits {
+		commits[i], _, _ = Commit(testRand)
+	}
+
+	var calls []int
+	get := func(i int) (Commitment, bool) {
+		calls = append(calls, i)
+		return commits[i], true
+	}
+	aggR, err := cos.AggregateCommitFunc(get)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(aggR, cos.AggregateCommit(commits)) {
+		t.Errorf("AggregateCommitFunc and AggregateCommit differ")
+	}
+	if !reflect.DeepEqual(calls, cos.EnabledSigners()) {
+		t.Errorf("callback called for %v, want enabled signers %v",
+			calls, cos.EnabledSigners())
+	}
+
+	// Errors name the offending cosigner, as AggregateCommitErr does.
+	commits[5] = invalidPoint
+	_, err1 := cos.AggregateCommitFunc(get)
+	_, err2 := cos.AggregateCommitErr(commits)
+	if !reflect.DeepEqual(err1, err2) {
+		t.Errorf("invalid commit: got %v, want %v", err1, err2)
+	}
+	_, err = cos.AggregateCommitFunc(func(i int) (Commitment, bool) {
+		return commits[i], i != 3
+	})
+	if ce, ok := err.(*CommitError); !ok || ce.Index != 3 ||
+		ce.Err != ErrCommitLength {
+		t.Errorf("missing commit: got %v", err)
 	}
 }
 
@@ -163,6 +2217,28 @@ func benchVerifyCached(b *testing.B, nsigners int) {
 	}
 }
 
+// benchVerifyAllocs compares the allocations of Verify and VerifyReuse;
+// run it with -benchmem.
+func benchVerifyAllocs(b *testing.B, nsigners int, reuse bool) {
+	genKeys(nsigners)
+	cosigners := NewCosigners(pubKeys[:nsigners], nil)
+	sig := testCosign(b, rightMessage, priKeys[:nsigners], cosigners)
+	var scratch VerifyScratch
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ok := false
+		if reuse {
+			ok = cosigners.VerifyReuse(rightMessage, sig, &scratch)
+		} else {
+			ok = cosigners.Verify(rightMessage, sig)
+		}
+		if !ok {
+			b.Errorf("%d-signer signature rejected", nsigners)
+		}
+	}
+}
+
 func benchVerifyWorst(b *testing.B, nsigners int) {
 	genKeys(nsigners)                                  // make sure we have enough keypairs
 	cosigners := NewCosigners(pubKeys[:nsigners], nil) // all enabled
@@ -222,6 +2298,22 @@ func BenchmarkSign1000Individual(b *testing.B) {
 	benchSignInd(b, 1000)
 }
 
+// Commitment benchmarks
+
+func BenchmarkCommit100Separate(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 100; j++ {
+			Commit(testRand)
+		}
+	}
+}
+
+func BenchmarkCommit100Batch(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		CommitBatch(testRand, 100)
+	}
+}
+
 // Verification benchmarks
 
 func BenchmarkVerify1CollectiveCache(b *testing.B) {
@@ -260,6 +2352,14 @@ func BenchmarkVerify100Individual(b *testing.B) {
 	benchVerifyInd(b, 100)
 }
 
+func BenchmarkVerify100Allocs(b *testing.B) {
+	benchVerifyAllocs(b, 100, false)
+}
+
+func BenchmarkVerify100Reuse(b *testing.B) {
+	benchVerifyAllocs(b, 100, true)
+}
+
 func BenchmarkVerify1000CollectiveCache(b *testing.B) {
 	benchVerifyCached(b, 1000)
 }
@@ -271,3 +2371,46 @@ func BenchmarkVerify1000CollectiveWorst(b *testing.B) {
 func BenchmarkVerify1000Individual(b *testing.B) {
 	benchVerifyInd(b, 1000)
 }
+
+func TestVerifyReuse(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	var scratch VerifyScratch
+	for i := 0; i < 2; i++ {
+		if !cos.VerifyReuse(rightMessage, sig, &scratch) {
+			t.Errorf("valid signature rejected")
+		}
+		if cos.VerifyReuse(wrongMessage, sig, &scratch) {
+			t.Errorf("signature verified on wrong message")
+		}
+		if cos.VerifyReuse(rightMessage, sig[:64], &scratch) {
+			t.Errorf("truncated signature accepted")
+		}
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		cos.VerifyReuse(rightMessage, sig, &scratch)
+	})
+	if allocs != 0 {
+		t.Errorf("VerifyReuse allocates %v times per call", allocs)
+	}
+
+	// A custom hash still gives the same answers as Verify.
+	newHash := func() hash.Hash {
+		h := sha512.New()
+		h.Write([]byte("VerifyReuse test"))
+		return h
+	}
+	cos.SetHash(newHash)
+	sig = cosignWith(t, cos, func(i int, secret *Secret, aggK, aggR []byte) SignaturePart {
+		part, _ := CosignHash(newHash, priKeys[i], secret, rightMessage,
+			aggK, aggR)
+		return part
+	})
+	if !cos.VerifyReuse(rightMessage, sig, &scratch) ||
+		cos.VerifyReuse(wrongMessage, sig, &scratch) {
+		t.Errorf("VerifyReuse disagrees with Verify under SetHash")
+	}
+}
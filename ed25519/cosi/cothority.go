@@ -0,0 +1,30 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+// ConvertCothority converts a collective signature
+// between the format of this package
+// and that of the CoSi implementation used by the DEDIS cothority,
+// go.dedis.ch/kyber/v3/sign/cosi, in either direction.
+// The two formats differ only in the meaning of the participation mask:
+// both pack it little-endian after the 64-byte Ed25519 signature,
+// but kyber sets a cosigner's bit when it is enabled,
+// while this package sets it when the cosigner is disabled.
+// Inverting every mask bit therefore converts between them,
+// and also maps kyber's zero padding bits to the set padding bits
+// this package uses.
+//
+// ConvertCothority returns a new slice, leaving sig unchanged,
+// or nil if sig is too short to be a signature in either format.
+func ConvertCothority(sig []byte) []byte {
+	if len(sig) < 64 {
+		return nil
+	}
+	out := append([]byte{}, sig...)
+	for i := 64; i < len(out); i++ {
+		out[i] = ^out[i]
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bufio"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+func TestCothority(t *testing.T) {
+	// cothority.input holds signatures produced by
+	// go.dedis.ch/kyber/v3/sign/cosi;
+	// testdata/cothority_gen.go generated it.
+	f, err := os.Open("testdata/cothority.input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		parts := strings.Split(scanner.Text(), ":")
+		if len(parts) != 3 {
+			t.Fatalf("bad number of parts on line %d", lineNo)
+		}
+		message, _ := hex.DecodeString(parts[0])
+		var keys []ed25519.PublicKey
+		for _, k := range strings.Split(parts[1], ",") {
+			key, _ := hex.DecodeString(k)
+			keys = append(keys, key)
+		}
+		sig, _ := hex.DecodeString(parts[2])
+
+		cos, err := NewCosignersErr(keys, nil,
+			WithPolicy(ThresholdPolicy(1)))
+		if err != nil {
+			t.Fatalf("line %d: %v", lineNo, err)
+		}
+		converted := ConvertCothority(sig)
+		if !cos.Verify(message, converted) {
+			t.Errorf("line %d: converted signature rejected", lineNo)
+		}
+		if cos.Verify(message, sig) {
+			t.Errorf("line %d: unconverted signature accepted", lineNo)
+		}
+		if cos.Verify(message[1:], converted) {
+			t.Errorf("line %d: signature accepted on wrong message", lineNo)
+		}
+		if string(ConvertCothority(converted)) != string(sig) {
+			t.Errorf("line %d: conversion is not an involution", lineNo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if lineNo == 0 {
+		t.Fatal("no test vectors")
+	}
+
+	if ConvertCothority(make([]byte, 63)) != nil {
+		t.Errorf("short signature converted")
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/sha512"
+	"strconv"
+	"sync"
+
+	//"golang.org/x/crypto/ed25519"
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// deterministicDomain separates deterministic CoSi nonces
+// from the nonces standard Ed25519 derives from the same private key.
+const deterministicDomain = "CoSi Ed25519 deterministic nonce\x00"
+
+// maxUsedDeterministic is the number of deterministic secrets
+// whose use this package remembers.
+const maxUsedDeterministic = 1 << 16
+
+// CommitDeterministic is an alternative to Commit
+// that derives the one-time secret deterministically
+// from the cosigner's private key and the message to be signed,
+// much as standard Ed25519 derives its per-message nonces
+// by hashing the second half of the expanded private key
+// together with the message.
+// The hash is prefixed with a CoSi-specific domain tag,
+// so that the nonce differs from the one ed25519.Sign would use
+// on the same message.
+// It is mainly useful for reproducible testing,
+// and in environments lacking a trustworthy source of randomness.
+// A cosigner that belongs to more than one group
+// should use Cosigners.CommitDeterministic instead,
+// which also binds the nonce to the group.
+//
+// Deterministic commits are considerably more fragile
+// in collective signing than in individual signing.
+// The aggregate commit and participation mask that go into each Cosign
+// depend on the other cosigners,
+// so if the same cosigner ever signs the same message twice
+// in rounds with different challenges,
+// it produces two signature parts using the same secret,
+// from which its private key is easily computed.
+// To guard against this, the returned Secret may only be used
+// on the same message passed to CommitDeterministic,
+// and this package remembers the challenge scalar
+// each of the most recent 65536 deterministic secrets was used with,
+// whatever signing function used it:
+// Cosign panics, and CosignErr and its relatives return ErrSecretReused,
+// if a remembered secret is used again with a different challenge,
+// including one differing only in its domain separation, hash function,
+// or MuSig coefficient.
+// This protection cannot extend across processes or machines,
+// nor to secrets forgotten to make room for newer ones;
+// callers that may restart a signing round after a crash
+// or from another replica must use Commit instead.
+func CommitDeterministic(privateKey ed25519.PrivateKey,
+	message []byte) (Commitment, *Secret) {
+
+	return commitDeterministicKey(privateKey, &[32]byte{}, message)
+}
+
+// CommitDeterministic is like the standalone CommitDeterministic function,
+// but also hashes the GroupID of cos into the one-time secret,
+// so that the same cosigner signing the same message for different groups,
+// whose rounds necessarily have different challenges,
+// uses a different secret for each group
+// even across processes that do not share this package's memory
+// of the secrets already used.
+// The same security tradeoffs apply otherwise.
+func (cos *Cosigners) CommitDeterministic(privateKey ed25519.PrivateKey,
+	message []byte) (Commitment, *Secret) {
+
+	group := cos.GroupID()
+	return commitDeterministicKey(privateKey, &group, message)
+}
+
+// commitDeterministicKey checks and expands privateKey
+// for commitDeterministic.
+func commitDeterministicKey(privateKey ed25519.PrivateKey, group *[32]byte,
+	message []byte) (Commitment, *Secret) {
+
+	if l := len(privateKey); l != ed25519.PrivateKeySize {
+		panic("cosi: bad private key length: " + strconv.Itoa(l))
+	}
+
+	_, prefix := expandPrivateKey(privateKey)
+	return commitDeterministic(&prefix, group, message)
+}
+
+// commitDeterministic is CommitDeterministic
+// given the nonce-derivation prefix of the expanded private key.
+func commitDeterministic(prefix, group *[32]byte,
+	message []byte) (Commitment, *Secret) {
+
+	h := sha512.New()
+	h.Write([]byte(deterministicDomain))
+	h.Write(prefix[:])
+	h.Write(group[:])
+	h.Write(message)
+	var secretFull [64]byte
+	h.Sum(secretFull[:0])
+
+	var secret Secret
+	edwards25519.ScReduce(&secret.reduced, &secretFull)
+	secret.valid = true
+	secret.deterministic = true
+	secret.message = sha512.Sum512(message)
+
+	// compute R, the individual Schnorr commit to our one-time secret
+	var R edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&R, &secret.reduced)
+	R.ToBytes(&secret.commit)
+
+	return append(Commitment{}, secret.commit[:]...), &secret
+}
+
+// usedDeterministic records, for each of the most recent
+// deterministic commitments used to sign so far,
+// the challenge scalar it was used with.
+// The order slice is a ring of the recorded commitments,
+// oldest first from next, from which the oldest is evicted
+// once maxUsedDeterministic are recorded.
+var usedDeterministic struct {
+	sync.Mutex
+	m     map[[32]byte][32]byte
+	order [][32]byte
+	next  int
+}
+
+// checkMessage returns ErrSecretMessage
+// if a deterministic secret is about to be used
+// on a message, with SHA-512 digest message,
+// other than the one it was derived from.
+func (secret *Secret) checkMessage(message *[64]byte) error {
+	if secret.deterministic && *message != secret.message {
+		return ErrSecretMessage
+	}
+	return nil
+}
+
+// recordChallenge returns ErrSecretReused
+// if a deterministic secret is about to be used
+// with a challenge scalar c different from the one
+// an identical secret was previously used with,
+// and otherwise records c for the secret.
+// It does nothing for secrets from Commit.
+func (secret *Secret) recordChallenge(c *[32]byte) error {
+	if !secret.deterministic {
+		return nil
+	}
+
+	used := &usedDeterministic
+	used.Lock()
+	defer used.Unlock()
+	if prev, ok := used.m[secret.commit]; ok {
+		if prev != *c {
+			return ErrSecretReused
+		}
+		return nil
+	}
+	if used.m == nil {
+		used.m = make(map[[32]byte][32]byte)
+	}
+	if len(used.order) < maxUsedDeterministic {
+		used.order = append(used.order, secret.commit)
+	} else {
+		delete(used.m, used.order[used.next])
+		used.order[used.next] = secret.commit
+		used.next = (used.next + 1) % maxUsedDeterministic
+	}
+	used.m[secret.commit] = *c
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+func expectPanic(t *testing.T, what string, f func()) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s did not panic", what)
+		}
+	}()
+	f()
+}
+
+func TestCommitDeterministic(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1, _ := cos.CommitDeterministic(priKeys[0], rightMessage)
+	c2, _ := cos.CommitDeterministic(priKeys[0], rightMessage)
+	if !bytes.Equal(c1, c2) {
+		t.Errorf("deterministic commits differ")
+	}
+	c3, _ := cos.CommitDeterministic(priKeys[0], wrongMessage)
+	c4, _ := cos.CommitDeterministic(priKeys[1], rightMessage)
+	if bytes.Equal(c1, c3) || bytes.Equal(c1, c4) {
+		t.Errorf("deterministic commits collide")
+	}
+
+	// The nonce is neither the one ed25519.Sign uses on the same message
+	// nor the one used for another group.
+	if bytes.Equal(c1, ed25519.Sign(priKeys[0], rightMessage)[:32]) {
+		t.Errorf("deterministic commit equals the Ed25519 nonce commitment")
+	}
+	other, _ := NewCosignersErr(pubKeys[:n-1], nil)
+	c5, _ := other.CommitDeterministic(priKeys[0], rightMessage)
+	if bytes.Equal(c1, c5) {
+		t.Errorf("deterministic commits for different groups collide")
+	}
+
+	// The standalone function binds no group, but is equally reproducible.
+	s1, _ := CommitDeterministic(priKeys[0], rightMessage)
+	s2, _ := CommitDeterministic(priKeys[0], rightMessage)
+	if !bytes.Equal(s1, s2) || bytes.Equal(s1, c1) || bytes.Equal(s1, c5) {
+		t.Errorf("standalone deterministic commits wrong")
+	}
+	expectPanic(t, "CommitDeterministic with a short private key", func() {
+		CommitDeterministic(priKeys[0][:10], rightMessage)
+	})
+
+	// A complete signing round using deterministic commits.
+	message := []byte("deterministic round")
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i] = cos.CommitDeterministic(priKeys[i], message)
+	}
+	aggK := cos.AggregatePublicKey()
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		parts[i] = Cosign(priKeys[i], secrets[i], message, aggK, aggR)
+	}
+	sig := cos.AggregateSignature(aggR, parts)
+	if !cos.Verify(message, sig) {
+		t.Errorf("deterministically-committed signature rejected")
+	}
+
+	// Re-running the identical round yields the identical signature.
+	for i := range commits {
+		_, secrets[i] = cos.CommitDeterministic(priKeys[i], message)
+		parts[i] = Cosign(priKeys[i], secrets[i], message, aggK, aggR)
+	}
+	if !bytes.Equal(sig, cos.AggregateSignature(aggR, parts)) {
+		t.Errorf("deterministic signature not reproducible")
+	}
+
+	_, secret := cos.CommitDeterministic(priKeys[0], message)
+	expectPanic(t, "Cosign on a different message", func() {
+		Cosign(priKeys[0], secret, wrongMessage, aggK, aggR)
+	})
+
+	// Re-signing the same message in a round with a different
+	// aggregate commit would leak the private key.
+	commits[1], _, _ = Commit(testRand)
+	otherR := cos.AggregateCommit(commits)
+	_, secret = cos.CommitDeterministic(priKeys[0], message)
+	expectPanic(t, "Cosign with a different aggregate commit", func() {
+		Cosign(priKeys[0], secret, message, aggK, otherR)
+	})
+}
+
+func TestDeterministicAcrossModes(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+
+	// Deterministic secrets are tracked across tests,
+	// so sign a message no other test signs deterministically.
+	message := []byte("deterministic secret in every mode")
+	commits := make([]Commitment, n)
+	for i := range commits {
+		commits[i], _ = cos.CommitDeterministic(priKeys[i], message)
+	}
+	aggR := cos.AggregateCommit(commits)
+	_, secret := cos.CommitDeterministic(priKeys[0], message)
+	if _, err := CosignErr(priKeys[0], secret, message, aggK, aggR); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same aggregate values under any other challenge
+	// would reuse the nonce, and are refused.
+	cosigns := []struct {
+		name   string
+		cosign func(secret *Secret) (SignaturePart, error)
+	}{
+		{"CosignCtx", func(secret *Secret) (part SignaturePart, err error) {
+			defer func() {
+				if e, ok := recover().(error); ok {
+					err = e
+				}
+			}()
+			return CosignCtx(priKeys[0], secret, message, []byte("ctx"),
+				aggK, aggR), nil
+		}},
+		{"CosignMaskBound", func(secret *Secret) (SignaturePart, error) {
+			return CosignMaskBound(priKeys[0], secret, message, cos.Mask(),
+				aggK, aggR)
+		}},
+		{"CosignIndexed", func(secret *Secret) (SignaturePart, error) {
+			return CosignIndexed(priKeys[0], secret, 0, message, aggK, aggR)
+		}},
+		{"CosignHash", func(secret *Secret) (SignaturePart, error) {
+			customHash := func() hash.Hash {
+				h := sha512.New()
+				h.Write([]byte("custom challenge"))
+				return h
+			}
+			return CosignHash(customHash, priKeys[0], secret, message,
+				aggK, aggR)
+		}},
+	}
+	for _, test := range cosigns {
+		_, secret := cos.CommitDeterministic(priKeys[0], message)
+		if _, err := test.cosign(secret); err != ErrSecretReused {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				ErrSecretReused)
+		}
+		if secret.Used() {
+			t.Errorf("%s: refused secret was consumed", test.name)
+		}
+	}
+}
+
+func TestUsedDeterministicBound(t *testing.T) {
+	var c [32]byte
+	secret := func(i int) *Secret {
+		s := &Secret{deterministic: true}
+		copy(s.commit[:], "evict")
+		binary.BigEndian.PutUint32(s.commit[28:], uint32(i))
+		return s
+	}
+	for i := 0; i <= maxUsedDeterministic; i++ {
+		if err := secret(i).recordChallenge(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	usedDeterministic.Lock()
+	size := len(usedDeterministic.m)
+	usedDeterministic.Unlock()
+	if size > maxUsedDeterministic {
+		t.Errorf("remembered %d deterministic secrets, want at most %d",
+			size, maxUsedDeterministic)
+	}
+
+	// The newest secrets are still remembered.
+	c[0] = 1
+	if err := secret(maxUsedDeterministic).recordChallenge(&c); err != ErrSecretReused {
+		t.Errorf("newest secret reused: got %v, want %v", err, ErrSecretReused)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/sha512"
+	"strconv"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// dom2 returns the RFC 8032 domain-separation prefix
+// for the Ed25519ph (phflag 1) and Ed25519ctx (phflag 0) variants.
+func dom2(phflag byte, ctx []byte) []byte {
+	const prefix = "SigEd25519 no Ed25519 collisions"
+	dom := make([]byte, 0, len(prefix)+2+len(ctx))
+	dom = append(dom, prefix...)
+	dom = append(dom, phflag, byte(len(ctx)))
+	return append(dom, ctx...)
+}
+
+// CosignPrehashed is like Cosign,
+// but produces a signature part in the Ed25519ph mode of RFC 8032:
+// digest must be the 64-byte SHA-512 hash of the message,
+// which is signed in place of the message itself
+// together with the Ed25519ph domain-separation prefix.
+// This allows very large messages to be hashed once, up front,
+// and only their digest distributed to cosigners.
+// The commit and aggregation steps are the same as for Cosign,
+// but the resulting collective signature must be checked
+// with VerifyPrehashed rather than Verify.
+//
+// CosignPrehashed panics if len(digest) is not sha512.Size.
+func CosignPrehashed(privateKey ed25519.PrivateKey, secret *Secret, digest []byte,
+	aggregateK ed25519.PublicKey, aggregateR Commitment) SignaturePart {
+
+	if l := len(digest); l != sha512.Size {
+		panic("ed25519: bad prehashed digest length: " + strconv.Itoa(l))
+	}
+	part, err := cosignDom(nil, privateKey, secret, dom2(1, nil), digest,
+		aggregateK, aggregateR)
+	if err != nil {
+		panic(err)
+	}
+	return part
+}
+
+// VerifyPrehashed is like Verify,
+// but checks a collective signature produced with CosignPrehashed,
+// in the Ed25519ph mode of RFC 8032.
+// The digest must be the 64-byte SHA-512 hash of the message.
+func (cos *Cosigners) VerifyPrehashed(digest, sig []byte) bool {
+	if len(digest) != sha512.Size {
+		return false
+	}
+	return cos.verifyDom(dom2(1, nil), digest, sig)
+}
+
+// ctxDom returns the Ed25519ctx domain-separation prefix for ctx,
+// or nil for an empty context, which denotes plain Ed25519.
+func ctxDom(ctx []byte) []byte {
+	if len(ctx) == 0 {
+		return nil
+	}
+	return dom2(0, ctx)
+}
+
+// CosignCtx is like Cosign,
+// but binds the signature part to the context string ctx
+// using the Ed25519ctx mode of RFC 8032.
+// Applications that share the same cosigner keys
+// can use distinct contexts to ensure that a collective signature
+// produced for one application cannot be replayed in another.
+// The resulting collective signature must be checked
+// with VerifyCtx and the same context.
+// As in Go's standard crypto/ed25519 package,
+// an empty context denotes plain Ed25519,
+// making CosignCtx equivalent to Cosign.
+//
+// CosignCtx panics if ctx is longer than 255 bytes.
+func CosignCtx(privateKey ed25519.PrivateKey, secret *Secret, message, ctx []byte,
+	aggregateK ed25519.PublicKey, aggregateR Commitment) SignaturePart {
+
+	if l := len(ctx); l > 255 {
+		panic("ed25519: bad context length: " + strconv.Itoa(l))
+	}
+	part, err := cosignDom(nil, privateKey, secret, ctxDom(ctx), message,
+		aggregateK, aggregateR)
+	if err != nil {
+		panic(err)
+	}
+	return part
+}
+
+// VerifyCtx is like Verify,
+// but checks a collective signature produced with CosignCtx
+// under the context string ctx.
+// It returns false if ctx is longer than 255 bytes.
+func (cos *Cosigners) VerifyCtx(message, ctx, sig []byte) bool {
+	if len(ctx) > 255 {
+		return false
+	}
+	return cos.verifyDom(ctxDom(ctx), message, sig)
+}
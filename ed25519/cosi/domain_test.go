@@ -0,0 +1,133 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto"
+	stded25519 "crypto/ed25519"
+	"crypto/sha512"
+	"testing"
+)
+
+// cosignWith runs a complete signing round over all enabled cosigners,
+// producing each signature part with the given function.
+func cosignWith(tb testing.TB, cos *Cosigners,
+	part func(i int, secret *Secret, aggK, aggR []byte) SignaturePart) []byte {
+
+	n := cos.CountTotal()
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		parts[i] = part(i, secrets[i], aggK, aggR)
+	}
+	return cos.AggregateSignature(aggR, parts)
+}
+
+func TestPrehashed(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha512.Sum512(rightMessage)
+	sig := cosignWith(t, cos, func(i int, secret *Secret, aggK, aggR []byte) SignaturePart {
+		return CosignPrehashed(priKeys[i], secret, digest[:], aggK, aggR)
+	})
+	if !cos.VerifyPrehashed(digest[:], sig) {
+		t.Errorf("valid prehashed signature rejected")
+	}
+	if cos.Verify(digest[:], sig) || cos.Verify(rightMessage, sig) {
+		t.Errorf("prehashed signature accepted in pure mode")
+	}
+	wrong := sha512.Sum512(wrongMessage)
+	if cos.VerifyPrehashed(wrong[:], sig) {
+		t.Errorf("prehashed signature on different message accepted")
+	}
+	if cos.VerifyPrehashed(digest[:32], sig) {
+		t.Errorf("short digest accepted")
+	}
+
+	// With a single cosigner, the collective signature
+	// is a standard RFC 8032 Ed25519ph signature.
+	single, err := NewCosignersErr(pubKeys[:1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig = cosignWith(t, single, func(i int, secret *Secret, aggK, aggR []byte) SignaturePart {
+		return CosignPrehashed(priKeys[i], secret, digest[:], aggK, aggR)
+	})
+	err = stded25519.VerifyWithOptions(stded25519.PublicKey(pubKeys[0]),
+		digest[:], sig[:64], &stded25519.Options{Hash: crypto.SHA512})
+	if err != nil {
+		t.Errorf("standard Ed25519ph verifier rejected signature: %v", err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foo, bar := []byte("foo"), []byte("bar")
+	sig := cosignWith(t, cos, func(i int, secret *Secret, aggK, aggR []byte) SignaturePart {
+		return CosignCtx(priKeys[i], secret, rightMessage, foo, aggK, aggR)
+	})
+	if !cos.VerifyCtx(rightMessage, foo, sig) {
+		t.Errorf("valid signature under context rejected")
+	}
+	if cos.VerifyCtx(rightMessage, bar, sig) {
+		t.Errorf("signature under context foo accepted under context bar")
+	}
+	if cos.VerifyCtx(rightMessage, nil, sig) || cos.Verify(rightMessage, sig) {
+		t.Errorf("signature under context foo accepted without context")
+	}
+	if cos.VerifyCtx(wrongMessage, foo, sig) {
+		t.Errorf("signature on different message accepted")
+	}
+
+	// An empty context is plain Ed25519.
+	sig = cosignWith(t, cos, func(i int, secret *Secret, aggK, aggR []byte) SignaturePart {
+		return CosignCtx(priKeys[i], secret, rightMessage, nil, aggK, aggR)
+	})
+	if !cos.Verify(rightMessage, sig) || cos.VerifyCtx(rightMessage, foo, sig) {
+		t.Errorf("empty context not treated as plain Ed25519")
+	}
+
+	long := make([]byte, 256)
+	if cos.VerifyCtx(rightMessage, long, sig) {
+		t.Errorf("overlong context accepted")
+	}
+	_, secret, _ := Commit(testRand)
+	expectPanic(t, "CosignCtx with overlong context", func() {
+		CosignCtx(priKeys[0], secret, rightMessage, long,
+			cos.AggregatePublicKey(), sig[:32])
+	})
+
+	// With a single cosigner, the collective signature
+	// is a standard RFC 8032 Ed25519ctx signature.
+	single, err := NewCosignersErr(pubKeys[:1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig = cosignWith(t, single, func(i int, secret *Secret, aggK, aggR []byte) SignaturePart {
+		return CosignCtx(priKeys[i], secret, rightMessage, foo, aggK, aggR)
+	})
+	err = stded25519.VerifyWithOptions(stded25519.PublicKey(pubKeys[0]),
+		rightMessage, sig[:64], &stded25519.Options{Context: "foo"})
+	if err != nil {
+		t.Errorf("standard Ed25519ctx verifier rejected signature: %v", err)
+	}
+}
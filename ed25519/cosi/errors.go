@@ -0,0 +1,268 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"errors"
+	"strconv"
+)
+
+var (
+	// ErrKeyLength indicates a public key that is not
+	// exactly ed25519.PublicKeySize bytes long.
+	ErrKeyLength = errors.New("cosi: bad public key length")
+
+	// ErrInvalidKey indicates a public key that does not decode
+	// to a valid point on the Ed25519 curve.
+	ErrInvalidKey = errors.New("cosi: invalid public key")
+
+	// ErrSmallOrderKey indicates a public key
+	// that is the identity or another point of small order.
+	ErrSmallOrderKey = errors.New("cosi: public key of small order")
+
+	// ErrDuplicateKey indicates a public key
+	// that appears more than once in a cosigner list.
+	ErrDuplicateKey = errors.New("cosi: duplicate public key")
+
+	// ErrKeyProof indicates a missing or invalid
+	// proof of possession of a public key's private key.
+	ErrKeyProof = errors.New("cosi: invalid proof of possession")
+
+	// ErrPrivateKeyLength indicates a private key that is not
+	// exactly ed25519.PrivateKeySize bytes long.
+	ErrPrivateKeyLength = errors.New("cosi: bad private key length")
+
+	// ErrCommitLength indicates a commitment that is not
+	// exactly ed25519.PublicKeySize bytes long.
+	ErrCommitLength = errors.New("cosi: bad commitment length")
+
+	// ErrInvalidCommit indicates a commitment that is not
+	// the canonical encoding of a point on the Ed25519 curve,
+	// or that encodes the identity point.
+	ErrInvalidCommit = errors.New("cosi: invalid commitment")
+
+	// ErrCommitNonCanonical, ErrCommitIdentity and ErrCommitNotOnCurve
+	// are the specific reasons a CommitError
+	// may give for an ErrInvalidCommit:
+	// a y-coordinate not reduced modulo the field prime,
+	// the identity point,
+	// or a y-coordinate for which there is no point on the curve.
+	ErrCommitNonCanonical = errors.New("cosi: non-canonical commitment encoding")
+	ErrCommitIdentity     = errors.New("cosi: commitment is the identity point")
+	ErrCommitNotOnCurve   = errors.New("cosi: commitment not on the curve")
+
+	// ErrSignatureLength indicates a collective signature
+	// whose length does not match the number of cosigners.
+	ErrSignatureLength = errors.New("cosi: bad collective signature length")
+
+	// ErrSignatureScalar indicates a collective signature
+	// whose S component is not a scalar reduced below the group order,
+	// which an attacker could produce by adding the group order
+	// to the S of a valid signature.
+	ErrSignatureScalar = errors.New("cosi: non-canonical signature scalar")
+
+	// ErrNotMuSig indicates a request for a MuSig key coefficient
+	// from a Cosigners object not in MuSig mode.
+	ErrNotMuSig = errors.New("cosi: MuSig aggregation not enabled")
+
+	// ErrMergeMuSig indicates an attempt to merge cosigner groups
+	// at least one of which uses MuSig aggregation.
+	ErrMergeMuSig = errors.New("cosi: cannot merge MuSig cosigner groups")
+
+	// ErrInvalidPoint indicates a byte string passed to NewPoint
+	// that is not the canonical encoding of a point on the Ed25519 curve.
+	ErrInvalidPoint = errors.New("cosi: invalid point encoding")
+
+	// ErrInvalidScalar indicates a byte string passed to NewScalar
+	// that is not a 32-byte scalar reduced below the group order.
+	ErrInvalidScalar = errors.New("cosi: invalid scalar encoding")
+
+	// ErrMaskLength indicates a participation mask
+	// that is not exactly the expected length.
+	ErrMaskLength = errors.New("cosi: bad participation mask length")
+
+	// ErrMaskPadding indicates a participation mask
+	// whose unused high bits, beyond the last cosigner, are not all set.
+	ErrMaskPadding = errors.New("cosi: bad participation mask padding")
+
+	// ErrUnknownKey indicates a public key not in the cosigner list.
+	ErrUnknownKey = errors.New("cosi: public key not in cosigner list")
+
+	// ErrSignerRange indicates a cosigner index
+	// outside the list of cosigners.
+	ErrSignerRange = errors.New("cosi: cosigner index out of range")
+
+	// ErrDuplicateSigner indicates a cosigner index
+	// listed more than once.
+	ErrDuplicateSigner = errors.New("cosi: duplicate cosigner index")
+
+	// ErrTooManySigners indicates a cosigner list too large
+	// for an operation that is exponential in its size.
+	ErrTooManySigners = errors.New("cosi: too many cosigners to enumerate")
+
+	// ErrSignerDisabled indicates a signature part
+	// from a cosigner disabled in the participation mask.
+	ErrSignerDisabled = errors.New("cosi: cosigner is disabled")
+
+	// ErrDuplicatePart indicates a second signature part
+	// from the same cosigner.
+	ErrDuplicatePart = errors.New("cosi: duplicate signature part")
+
+	// ErrPartLength indicates a signature part
+	// that is not exactly 32 bytes long.
+	ErrPartLength = errors.New("cosi: bad signature part length")
+
+	// ErrInvalidPart indicates a signature part
+	// that does not verify against the cosigner's public key and commitment.
+	ErrInvalidPart = errors.New("cosi: invalid signature part")
+
+	// ErrDuplicateCommit indicates a second commitment
+	// from the same cosigner in one signing round.
+	ErrDuplicateCommit = errors.New("cosi: duplicate commitment")
+
+	// ErrNoRand indicates a call that needs randomness
+	// made without a source of randomness
+	// in a build with the cosi_norand tag, which has no default source.
+	ErrNoRand = errors.New("cosi: no default random source in cosi_norand build")
+
+	// ErrNoCommit indicates an attempt to cosign
+	// without a fresh commitment pending.
+	ErrNoCommit = errors.New("cosi: no pending commitment")
+
+	// ErrRoundState indicates a signing round operation
+	// invoked before or after the step at which it is permitted.
+	ErrRoundState = errors.New("cosi: signing round step out of order")
+
+	// ErrMaskChanged indicates that the participation mask
+	// was changed after it was fixed for a signing round.
+	ErrMaskChanged = errors.New("cosi: participation mask changed during signing round")
+
+	// ErrIncomplete indicates an attempt to finalize a collective signature
+	// before every enabled cosigner's signature part has been added.
+	ErrIncomplete = errors.New("cosi: missing signature parts")
+
+	// ErrSecretReused indicates an attempt to use a Secret
+	// that was already used to produce a signature part,
+	// or a deterministic Secret with a different challenge
+	// than an identical one was previously used with.
+	// Using a Secret more than once would reveal the cosigner's private key.
+	ErrSecretReused = errors.New("cosi: cosigning Secret reused")
+
+	// ErrSecretMessage indicates an attempt to use a deterministic Secret
+	// to sign a message other than the one it was derived from.
+	ErrSecretMessage = errors.New("cosi: deterministic Secret used on a different message")
+
+	// ErrHashSize indicates a challenge hash function
+	// whose digests are not exactly 64 bytes long.
+	ErrHashSize = errors.New("cosi: challenge hash must produce 64-byte digests")
+
+	// ErrEncodingVersion indicates a binary Cosigners encoding
+	// produced by an incompatible version of this package.
+	ErrEncodingVersion = errors.New("cosi: unsupported Cosigners encoding version")
+
+	// ErrEncoding indicates a malformed binary Cosigners encoding.
+	ErrEncoding = errors.New("cosi: malformed Cosigners encoding")
+
+	// ErrUnknownPolicy indicates a policy specification
+	// naming no policy registered with RegisterPolicy.
+	ErrUnknownPolicy = errors.New("cosi: unknown policy name")
+
+	// ErrPolicySpec indicates a policy specification
+	// whose arguments the named policy cannot parse.
+	ErrPolicySpec = errors.New("cosi: malformed policy specification")
+
+	// ErrSecretEncoding indicates a malformed binary Secret encoding,
+	// or one produced by an incompatible version of this package.
+	ErrSecretEncoding = errors.New("cosi: malformed Secret encoding")
+
+	// ErrInconsistent indicates a Cosigners object whose cached aggregate
+	// public key or enabled count does not match its participation mask.
+	ErrInconsistent = errors.New("cosi: cached aggregate inconsistent with mask")
+)
+
+// KeyError reports a public key that could not be used
+// to construct a Cosigners object,
+// together with the position of that key in the public key list.
+type KeyError struct {
+	Index int   // index of the offending key in the public key list
+	Err   error // ErrKeyLength, ErrInvalidKey, ErrSmallOrderKey, or ErrKeyProof
+}
+
+func (e *KeyError) Error() string {
+	return e.Err.Error() + " at index " + strconv.Itoa(e.Index)
+}
+
+// Unwrap returns the underlying reason the key was rejected.
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateKeyError reports a public key
+// that appears more than once in a cosigner list,
+// together with the positions of its first two occurrences.
+type DuplicateKeyError struct {
+	Index     int // index of the first occurrence of the key
+	Duplicate int // index of the second occurrence of the key
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return ErrDuplicateKey.Error() + " at indices " +
+		strconv.Itoa(e.Index) + " and " + strconv.Itoa(e.Duplicate)
+}
+
+// Unwrap returns ErrDuplicateKey.
+func (e *DuplicateKeyError) Unwrap() error {
+	return ErrDuplicateKey
+}
+
+// CommitError reports a cosigner's commitment
+// that AggregateCommitErr could not use,
+// together with the index of the cosigner that supplied it.
+//
+// When Err is ErrInvalidCommit, Reason may further distinguish
+// a malformed encoding, as a buggy client might send,
+// from a well-formed but unusable point, as an attacker might.
+// errors.Is matches both Err and Reason.
+type CommitError struct {
+	Index  int   // index of the offending cosigner
+	Err    error // ErrCommitLength or ErrInvalidCommit
+	Reason error // ErrCommitNonCanonical, ErrCommitIdentity, ErrCommitNotOnCurve, or nil
+}
+
+func (e *CommitError) Error() string {
+	msg := e.Err.Error()
+	if e.Reason != nil {
+		msg = e.Reason.Error()
+	}
+	return msg + " from cosigner " + strconv.Itoa(e.Index)
+}
+
+// Unwrap returns the underlying reason the commitment was rejected.
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the specific Reason
+// the commitment was rejected.
+func (e *CommitError) Is(target error) bool {
+	return e.Reason != nil && target == e.Reason
+}
+
+// PartError reports a cosigner's signature part
+// that AggregateSignatureErr could not use,
+// together with the index of the cosigner that supplied it.
+type PartError struct {
+	Index int   // index of the offending cosigner
+	Err   error // ErrPartLength
+}
+
+func (e *PartError) Error() string {
+	return e.Err.Error() + " from cosigner " + strconv.Itoa(e.Index)
+}
+
+// Unwrap returns the underlying reason the signature part was rejected.
+func (e *PartError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,182 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"errors"
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+func TestErrors(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		parts[i], err = CosignErr(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, secret, _ := Commit(testRand)
+	_, detSecret := cos.CommitDeterministic(priKeys[0], rightMessage)
+	badCommits := append([]Commitment{}, commits...)
+	badCommits[2] = invalidPoint
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"short public key", second(NewCosignersErr(
+			[]ed25519.PublicKey{pubKeys[0][:31]}, nil)), ErrKeyLength},
+		{"invalid public key", second(NewCosignersErr(
+			[]ed25519.PublicKey{invalidPoint}, nil)), ErrInvalidKey},
+		{"short commit", second(cos.AggregateCommitErr(
+			[]Commitment{{}, {}, {}, {}})), ErrCommitLength},
+		{"invalid commit", second(cos.AggregateCommitErr(badCommits)),
+			ErrInvalidCommit},
+		{"short private key", second(CosignErr(priKeys[0][:63], secret,
+			rightMessage, aggK, aggR)), ErrPrivateKeyLength},
+		{"short aggregate commit", second(CosignErr(priKeys[0], secret,
+			rightMessage, aggK, aggR[:31])), ErrCommitLength},
+		{"reused secret", second(CosignErr(priKeys[0], secrets[0],
+			rightMessage, aggK, aggR)), ErrSecretReused},
+		{"deterministic secret on other message", second(CosignErr(priKeys[0],
+			detSecret, wrongMessage, aggK, aggR)), ErrSecretMessage},
+		{"short signature part", second(cos.AggregateSignatureErr(aggR,
+			[]SignaturePart{parts[0], parts[1][:31], parts[2], parts[3]})),
+			ErrPartLength},
+		{"missing signature part", second(cos.AggregateSignatureErr(aggR,
+			parts[:3])), ErrPartLength},
+		{"short aggregate commit", second(cos.AggregateSignatureErr(aggR[:31],
+			parts)), ErrCommitLength},
+	}
+	for _, test := range tests {
+		if !errors.Is(test.err, test.want) {
+			t.Errorf("%s: got error %v, want %v", test.name, test.err, test.want)
+		}
+	}
+
+	// A short commit slice is an error, not a panic,
+	// even when the commits are aggregated in parallel.
+	_, err = cos.AggregateCommitErr(commits[:2])
+	if ce, ok := err.(*CommitError); !ok || ce.Index != 2 ||
+		!errors.Is(err, ErrCommitLength) {
+		t.Errorf("short commit slice: got error %v", err)
+	}
+	if cos.AggregateCommit(commits[:2]) != nil {
+		t.Errorf("short commit slice aggregated")
+	}
+	genKeys(32)
+	big, _ := NewCosignersErr(pubKeys[:32], nil, WithParallelThreshold(1))
+	bigCommits := make([]Commitment, 20)
+	for i := range bigCommits {
+		bigCommits[i], _, _ = Commit(testRand)
+	}
+	_, err = big.AggregateCommitErr(bigCommits)
+	if ce, ok := err.(*CommitError); !ok || ce.Index != 20 ||
+		!errors.Is(err, ErrCommitLength) {
+		t.Errorf("short commit slice in parallel: got error %v", err)
+	}
+	cos.SetMaskBit(3, Disabled)
+	if _, err := cos.AggregateCommitErr(commits[:3]); err != nil {
+		t.Errorf("commit slice missing only a disabled cosigner: %v", err)
+	}
+	cos.SetMaskBit(3, Enabled)
+
+	_, err = cos.AggregateSignatureErr(aggR, []SignaturePart{parts[0], nil, parts[2], parts[3]})
+	if pe, ok := err.(*PartError); !ok || pe.Index != 1 {
+		t.Errorf("bad signature part: got error %v", err)
+	}
+	if _, err := cos.AggregateSignatureErr(aggR, parts); err != nil {
+		t.Errorf("valid signature parts rejected: %v", err)
+	}
+	if _, err := CosignErr(priKeys[0], secret, rightMessage, aggK, aggR); err != nil {
+		t.Errorf("valid secret rejected after earlier errors: %v", err)
+	}
+}
+
+// second returns the error result of a two-valued call.
+func second(_ interface{}, err error) error {
+	return err
+}
+
+func TestCommitErrorReason(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	commits := make([]Commitment, n)
+	for i := range commits {
+		commits[i], _, _ = Commit(testRand)
+	}
+
+	// y = p+1 is a non-canonical encoding of the identity's y = 1.
+	nonCanonical := make([]byte, 32)
+	nonCanonical[0] = 0xee
+	for i := 1; i < 31; i++ {
+		nonCanonical[i] = 0xff
+	}
+	nonCanonical[31] = 0x7f
+
+	tests := []struct {
+		commit Commitment
+		reason error
+	}{
+		{nonCanonical, ErrCommitNonCanonical},
+		{invalidPoint, ErrCommitNotOnCurve},
+		{identity[:], ErrCommitIdentity},
+	}
+	for _, test := range tests {
+		bad := append([]Commitment{}, commits...)
+		bad[1] = test.commit
+		_, err := cos.AggregateCommitErr(bad)
+		ce, ok := err.(*CommitError)
+		if !ok || ce.Index != 1 || ce.Err != ErrInvalidCommit ||
+			ce.Reason != test.reason {
+			t.Errorf("commit %x: got %#v, want reason %v",
+				test.commit, err, test.reason)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidCommit) || !errors.Is(err, test.reason) {
+			t.Errorf("commit %x: errors.Is fails for %v", test.commit, err)
+		}
+		if err.Error() != test.reason.Error()+" from cosigner 1" {
+			t.Errorf("commit %x: message %q", test.commit, err.Error())
+		}
+		_, err = cos.AggregateCommitFunc(func(i int) (Commitment, bool) {
+			return bad[i], true
+		})
+		if !errors.Is(err, test.reason) {
+			t.Errorf("commit %x: AggregateCommitFunc got %v",
+				test.commit, err)
+		}
+
+		// The validation-only functions keep the general error.
+		if err := ValidateCommitment(test.commit); err != ErrInvalidCommit {
+			t.Errorf("commit %x: ValidateCommitment got %v", test.commit, err)
+		}
+	}
+
+	_, err := cos.AggregateCommitErr([]Commitment{commits[0], nil, commits[2]})
+	if ce, ok := err.(*CommitError); !ok || ce.Err != ErrCommitLength ||
+		ce.Reason != nil || errors.Is(err, ErrInvalidCommit) {
+		t.Errorf("short commit: got %#v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package cosi_test
 
 import (
+	"crypto/rand"
 	"fmt"
 
 	//"golang.org/x/crypto/ed25519"
@@ -39,8 +40,8 @@ func Sign(message []byte, pubKeys []ed25519.PublicKey,
 	priKey1, priKey2 ed25519.PrivateKey) []byte {
 
 	// Each cosigner first needs to produce a per-message commit.
-	commit1, secret1, _ := cosi.Commit(nil)
-	commit2, secret2, _ := cosi.Commit(nil)
+	commit1, secret1, _ := cosi.Commit(rand.Reader)
+	commit2, secret2, _ := cosi.Commit(rand.Reader)
 	commits := []cosi.Commitment{commit1, commit2}
 
 	// The leader then combines these into an aggregate commit.
@@ -0,0 +1,145 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// IndexedVersion is the version byte that indexed collective signatures
+// carry between the Ed25519 signature and the participation mask.
+// It differs from MaskBoundVersion,
+// so that the two kinds of signature cannot be mistaken for each other.
+const IndexedVersion = 2
+
+// indexedDomain separates indexed challenges
+// from those of every other signing mode.
+const indexedDomain = "CoSi Ed25519 indexed signature\x00"
+
+// indexDom returns the domain-separation prefix
+// binding a challenge to the index of the cosigner that signs it.
+func indexDom(signer int) []byte {
+	dom := make([]byte, len(indexedDomain)+4)
+	copy(dom, indexedDomain)
+	binary.BigEndian.PutUint32(dom[len(indexedDomain):], uint32(signer))
+	return dom
+}
+
+// CosignIndexed is like CosignErr,
+// but folds the cosigner's index signer in the cosigner list
+// into its challenge,
+// producing a part of an indexed collective signature.
+//
+// Every cosigner thus answers a different challenge,
+// so a signature part is attributable to one position in the list:
+// it verifies with VerifyPartIndexed only for the index it was made for,
+// and cannot be presented as another cosigner's part,
+// even one holding the same public key.
+//
+// This diverges from plain CoSi:
+// an indexed collective signature is not an Ed25519 signature,
+// and cannot be checked by an ordinary Ed25519 verifier
+// even when all cosigners participate.
+// The leader combines the parts with AggregateSignatureIndexed,
+// and verifiers must check the result with VerifyIndexed.
+func CosignIndexed(privateKey ed25519.PrivateKey, secret *Secret,
+	signer int, message []byte, aggregateK ed25519.PublicKey,
+	aggregateR Commitment) (SignaturePart, error) {
+
+	if signer < 0 {
+		return nil, ErrSignerRange
+	}
+	return cosignDom(nil, privateKey, secret, indexDom(signer), message,
+		aggregateK, aggregateR)
+}
+
+// VerifyPartIndexed is like VerifyPart,
+// but checks a signature part produced by CosignIndexed
+// for the cosigner at index signer.
+func (cos *Cosigners) VerifyPartIndexed(message, aggR Commitment,
+	signer int, indR, indS []byte) bool {
+
+	if signer < 0 || signer >= len(cos.keys) {
+		return false
+	}
+	return cos.verify(indexDom(signer), message, aggR, indR, indS,
+		*cos.aggKey(signer))
+}
+
+// AggregateSignatureIndexed combines signature parts
+// produced by CosignIndexed into an indexed collective signature,
+// exactly as AggregateSignatureErr does for ordinary parts,
+// and returns the same errors.
+// An indexed signature is laid out as R || S || IndexedVersion || mask,
+// one byte longer than an ordinary collective signature.
+func (cos *Cosigners) AggregateSignatureIndexed(aggregateR Commitment,
+	sigParts []SignaturePart) ([]byte, error) {
+
+	sig, err := cos.AggregateSignatureErr(aggregateR, sigParts)
+	if err != nil {
+		return nil, err
+	}
+	indexed := make([]byte, 0, len(sig)+1)
+	indexed = append(indexed, sig[:ed25519.SignatureSize]...)
+	indexed = append(indexed, IndexedVersion)
+	return append(indexed, sig[ed25519.SignatureSize:]...), nil
+}
+
+// VerifyIndexed is like Verify,
+// but checks an indexed collective signature
+// produced with AggregateSignatureIndexed.
+// Since each participating cosigner answered its own challenge,
+// VerifyIndexed checks S*B = R + sum(k_i*A_i) over the participants,
+// which costs one hash per participant
+// and a multi-scalar multiplication
+// rather than the single scalar multiplication of Verify.
+// It returns false if the signature does not carry IndexedVersion,
+// and, like Verify, if no cosigner participated
+// or the participants' aggregate public key is the identity,
+// since R = S*B would then verify for any S.
+func (cos *Cosigners) VerifyIndexed(message, sig []byte) bool {
+	if len(sig) != ed25519.SignatureSize+1+cos.MaskLen() ||
+		sig[ed25519.SignatureSize] != IndexedVersion ||
+		!scMinimal(sig[32:64]) {
+		return false
+	}
+	cos.SetMask(sig[ed25519.SignatureSize+1:])
+	if !cos.checkPolicyMessage(cos.policy, message) ||
+		cos.enabled == 0 || isIdentityPoint(&cos.aggr) {
+		return false
+	}
+
+	var aggK [32]byte
+	cos.aggr.ToBytes(&aggK)
+	scalars := make([][32]byte, 0, cos.enabled)
+	points := make([]edwards25519.ExtendedGroupElement, 0, cos.enabled)
+	for i := range cos.keys {
+		if cos.MaskBit(i) == Disabled {
+			continue
+		}
+		h := newHram(cos.newHash, indexDom(i), sig[:32], aggK[:])
+		h.Write(message)
+		scalars = append(scalars, reduceHram(h))
+
+		A := *cos.aggKey(i)
+		edwards25519.FeNeg(&A.X, &A.X)
+		edwards25519.FeNeg(&A.T, &A.T)
+		points = append(points, A)
+	}
+
+	var S [32]byte
+	copy(S[:], sig[32:64])
+	var projR edwards25519.ProjectiveGroupElement
+	edwards25519.GeMultiScalarMultVartime(&projR, scalars, points, &S)
+
+	var checkR [32]byte
+	projR.ToBytes(&checkR)
+	return subtle.ConstantTimeCompare(sig[:32], checkR[:]) == 1
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"testing"
+)
+
+func TestIndexed(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x02})
+	cos.SetPolicy(ThresholdPolicy(3))
+
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggK, aggR := cos.AggregatePublicKey(), cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		if cos.MaskBit(i) == Disabled {
+			continue
+		}
+		part, err := CosignIndexed(priKeys[i], secrets[i], i, rightMessage,
+			aggK, aggR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[i] = part
+	}
+
+	// Each part verifies only at its own index.
+	for i, part := range parts {
+		if part == nil {
+			continue
+		}
+		if !cos.VerifyPartIndexed(rightMessage, aggR, i, commits[i], part) {
+			t.Errorf("part %d rejected", i)
+		}
+		if cos.VerifyPart(rightMessage, aggR, i, commits[i], part) {
+			t.Errorf("part %d accepted as an ordinary part", i)
+		}
+		for j := range parts {
+			if j != i && cos.VerifyPartIndexed(rightMessage, aggR, j,
+				commits[i], part) {
+				t.Errorf("part %d accepted as part %d", i, j)
+			}
+		}
+	}
+
+	// A part made for another index is refused
+	// even under the signer's own key.
+	c, s, _ := Commit(testRand)
+	aggR2 := cos.AggregateCommit([]Commitment{c, nil, c, c})
+	wrong, _ := CosignIndexed(priKeys[0], s, 2, rightMessage, aggK, aggR2)
+	if cos.VerifyPartIndexed(rightMessage, aggR2, 0, c, wrong) {
+		t.Errorf("part made for index 2 accepted at index 0")
+	}
+	if _, err := CosignIndexed(priKeys[0], s, -1, rightMessage, aggK,
+		aggR2); err != ErrSignerRange {
+		t.Errorf("negative index: got %v", err)
+	}
+
+	sig, err := cos.AggregateSignatureIndexed(aggR, parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cos.VerifyIndexed(rightMessage, sig) {
+		t.Errorf("indexed signature rejected")
+	}
+	if cos.VerifyIndexed(wrongMessage, sig) {
+		t.Errorf("indexed signature accepted on wrong message")
+	}
+	if cos.Verify(rightMessage, append(sig[:64:64], sig[65:]...)) {
+		t.Errorf("indexed signature accepted as an ordinary one")
+	}
+
+	bad := append([]byte{}, sig...)
+	bad[64] = MaskBoundVersion
+	if cos.VerifyIndexed(rightMessage, bad) {
+		t.Errorf("signature with wrong version accepted")
+	}
+	bad = append([]byte{}, sig...)
+	bad[65] = 0x00
+	if cos.VerifyIndexed(rightMessage, bad) {
+		t.Errorf("signature with altered mask accepted")
+	}
+
+	// With no participants, R = S*B would verify for any S,
+	// so such a signature is refused even under a policy that allows it.
+	S, _ := NewScalar(scOne[:])
+	forged := append(ScalarMulBase(S).Bytes(), scOne[:]...)
+	forged = append(forged, IndexedVersion, 0xff)
+	cos.SetPolicy(ThresholdPolicy(0))
+	if cos.VerifyIndexed(rightMessage, forged) {
+		t.Errorf("forged signature with no participants accepted")
+	}
+}
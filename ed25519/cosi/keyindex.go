@@ -0,0 +1,109 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/sha512"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// keyIndex returns the map from each cosigner's public key encoding
+// to its index, building it on first use after any change to the key list.
+// If a key appears more than once, the map holds its first index.
+func (cos *Cosigners) keyIndex() map[string]int {
+	if cos.index == nil {
+		cos.index = make(map[string]int, len(cos.keys))
+		var keyBytes [32]byte
+		for i := len(cos.keys) - 1; i >= 0; i-- {
+			cos.keys[i].ToBytes(&keyBytes)
+			cos.index[string(keyBytes[:])] = i
+		}
+	}
+	return cos.index
+}
+
+// IndexOf returns the index of the cosigner with the given public key,
+// in the canonical encoding that PublicKeys returns,
+// or false if the key is not in the cosigner list.
+// If the key appears more than once, IndexOf returns its first occurrence.
+//
+// The first lookup after the cosigner list is created or changed
+// builds an index of all the keys, taking time linear in their number;
+// later lookups take constant time.
+// Lookups are not constant-time in the cryptographic sense,
+// but public keys are not secret.
+func (cos *Cosigners) IndexOf(publicKey ed25519.PublicKey) (int, bool) {
+	i, ok := cos.keyIndex()[string(publicKey)]
+	return i, ok
+}
+
+// MaskBitByKey returns whether the cosigner with the given public key
+// is Enabled or Disabled in the participation bitmask,
+// so that policies can refer to cosigners by identity rather than index.
+// It returns ErrUnknownKey if publicKey is not in the cosigner list,
+// in the canonical encoding that PublicKeys returns.
+// It looks the key up as IndexOf does.
+func (cos *Cosigners) MaskBitByKey(publicKey ed25519.PublicKey) (MaskBit, error) {
+	i, ok := cos.IndexOf(publicKey)
+	if !ok {
+		return Disabled, ErrUnknownKey
+	}
+	return cos.MaskBit(i), nil
+}
+
+// SetMaskByKeys sets the participation bitmask
+// to enable exactly the cosigners with the given public keys,
+// in the canonical encoding that PublicKeys returns,
+// and disable all others,
+// for callers that know who signed by identity rather than by index.
+// It looks each key up as IndexOf does,
+// so if a key appears more than once in the cosigner list,
+// only its first occurrence is enabled.
+// Cosigners revoked by DisablePermanently stay disabled, as with SetMask.
+// SetMaskByKeys returns ErrUnknownKey, leaving the mask unchanged,
+// if any of the keys is not in the cosigner list.
+func (cos *Cosigners) SetMaskByKeys(signers []ed25519.PublicKey) error {
+	mask := make([]byte, cos.MaskLen())
+	for i := range mask {
+		mask[i] = 0xff // all disabled
+	}
+	for _, publicKey := range signers {
+		i, ok := cos.IndexOf(publicKey)
+		if !ok {
+			return ErrUnknownKey
+		}
+		mask[i>>3] &^= 1 << uint(i&7)
+	}
+	cos.SetMask(mask)
+	return nil
+}
+
+// groupIDDomain separates group identifiers
+// from every other use of SHA-512 in this package.
+const groupIDDomain = "CoSi Ed25519 group ID\x00"
+
+// GroupID returns a fingerprint of the cosigner list,
+// the SHA-512/256 hash of a fixed domain-separation tag
+// followed by every cosigner's public key in order,
+// so that verifiers can check which group produced a signature
+// against an expected fingerprint before trusting it.
+// GroupID depends only on the public keys and their order:
+// not on the participation mask, the policy, or the MuSig mode,
+// and not on whether the object was created by NewCosignersErr
+// or by UnmarshalBinary.
+func (cos *Cosigners) GroupID() [32]byte {
+	h := sha512.New512_256()
+	h.Write([]byte(groupIDDomain))
+	var keyBytes [32]byte
+	for i := range cos.keys {
+		cos.keys[i].ToBytes(&keyBytes)
+		h.Write(keyBytes[:])
+	}
+	var id [32]byte
+	h.Sum(id[:0])
+	return id
+}
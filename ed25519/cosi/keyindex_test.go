@@ -0,0 +1,177 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+func TestMaskBitByKey(t *testing.T) {
+	n := 6
+	genKeys(n + 1)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x04})
+
+	for i := 0; i < n; i++ {
+		bit, err := cos.MaskBitByKey(pubKeys[i])
+		if err != nil || bit != cos.MaskBit(i) {
+			t.Errorf("key %d: got %v, %v; want %v",
+				i, bit, err, cos.MaskBit(i))
+		}
+	}
+	cos.SetMaskBit(2, Enabled)
+	if bit, _ := cos.MaskBitByKey(pubKeys[2]); bit != Enabled {
+		t.Errorf("lookup does not follow the mask")
+	}
+
+	if _, err := cos.MaskBitByKey(pubKeys[n]); err != ErrUnknownKey {
+		t.Errorf("absent key: got %v", err)
+	}
+	if _, err := cos.MaskBitByKey(pubKeys[0][:31]); err != ErrUnknownKey {
+		t.Errorf("short key: got %v", err)
+	}
+
+	// The index must pick up appended cosigners.
+	clone := cos.Clone()
+	if err := cos.AppendCosigner(pubKeys[n]); err != nil {
+		t.Fatal(err)
+	}
+	if bit, err := cos.MaskBitByKey(pubKeys[n]); err != nil || bit != Enabled {
+		t.Errorf("appended key: got %v, %v", bit, err)
+	}
+	if _, err := clone.MaskBitByKey(pubKeys[n]); err != ErrUnknownKey {
+		t.Errorf("key appended to original found in clone: %v", err)
+	}
+
+	// ... and keys replaced by UnmarshalBinary.
+	other, _ := NewCosignersErr(pubKeys[n:n+1], nil)
+	data, _ := other.MarshalBinary()
+	if err := cos.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cos.MaskBitByKey(pubKeys[0]); err != ErrUnknownKey {
+		t.Errorf("key found after UnmarshalBinary: %v", err)
+	}
+	if _, err := cos.MaskBitByKey(pubKeys[n]); err != nil {
+		t.Errorf("loaded key: %v", err)
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	n := 9
+	genKeys(n + 1)
+	keys := append(pubKeys[:n:n], pubKeys[3]) // 3 appears twice
+	cos, err := NewCosignersErr(keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < n; i++ {
+		if j, ok := cos.IndexOf(pubKeys[i]); !ok || j != i {
+			t.Errorf("key %d: got %d, %v", i, j, ok)
+		}
+	}
+	if _, ok := cos.IndexOf(pubKeys[n]); ok {
+		t.Errorf("absent key found")
+	}
+	if _, ok := cos.IndexOf(nil); ok {
+		t.Errorf("empty key found")
+	}
+
+	// Appending invalidates the index, but never renumbers existing keys.
+	cos.AppendCosigner(pubKeys[n])
+	if j, ok := cos.IndexOf(pubKeys[n]); !ok || j != n+1 {
+		t.Errorf("appended key: got %d, %v", j, ok)
+	}
+	if j, ok := cos.IndexOf(pubKeys[3]); !ok || j != 3 {
+		t.Errorf("duplicated key: got %d, %v", j, ok)
+	}
+
+	// Merging builds a fresh index for the combined list.
+	other, _ := NewCosignersErr(pubKeys[:2], nil)
+	merged, err := MergeGroups(other, cos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, ok := merged.IndexOf(pubKeys[n]); !ok || j != n+3 {
+		t.Errorf("merged key: got %d, %v", j, ok)
+	}
+}
+
+func TestSetMaskByKeys(t *testing.T) {
+	n := 10
+	genKeys(n + 1)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+
+	signers := []ed25519.PublicKey{pubKeys[9], pubKeys[2], pubKeys[8],
+		pubKeys[2]}
+	if err := cos.SetMaskByKeys(signers); err != nil {
+		t.Fatal(err)
+	}
+	if got := cos.EnabledSigners(); !reflect.DeepEqual(got, []int{2, 8, 9}) {
+		t.Errorf("enabled signers %v, want [2 8 9]", got)
+	}
+	byIndex, _ := NewCosignersErr(pubKeys[:n], []byte{0xfb, 0xfc})
+	if !bytes.Equal(cos.Mask(), byIndex.Mask()) ||
+		!bytes.Equal(cos.AggregatePublicKey(), byIndex.AggregatePublicKey()) {
+		t.Errorf("mask by keys differs from mask by index")
+	}
+
+	// An unknown key leaves the mask unchanged.
+	mask := cos.Mask()
+	err := cos.SetMaskByKeys([]ed25519.PublicKey{pubKeys[0], pubKeys[n]})
+	if err != ErrUnknownKey {
+		t.Errorf("unknown key: got %v", err)
+	}
+	if !bytes.Equal(cos.Mask(), mask) {
+		t.Errorf("unknown key changed the mask")
+	}
+
+	if err := cos.SetMaskByKeys(nil); err != nil || cos.CountEnabled() != 0 {
+		t.Errorf("empty signer set: %v, %d enabled", err, cos.CountEnabled())
+	}
+}
+
+func TestGroupID(t *testing.T) {
+	n := 5
+	genKeys(n + 1)
+	a, _ := NewCosignersErr(pubKeys[:n], nil)
+	b, _ := NewCosignersErr(pubKeys[:n], []byte{0x13})
+	id := a.GroupID()
+	if b.GroupID() != id {
+		t.Errorf("identical key lists have different IDs")
+	}
+
+	b.SetMaskBit(0, Enabled)
+	b.SetMuSig(true)
+	b.SetPolicy(ThresholdPolicy(1))
+	if b.GroupID() != id {
+		t.Errorf("ID depends on mask, mode, or policy")
+	}
+
+	data, _ := a.MarshalBinary()
+	var loaded Cosigners
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.GroupID() != id {
+		t.Errorf("ID does not survive serialization")
+	}
+
+	reordered := append([]ed25519.PublicKey{}, pubKeys[:n]...)
+	reordered[1], reordered[2] = reordered[2], reordered[1]
+	c, _ := NewCosignersErr(reordered, nil)
+	if c.GroupID() == id {
+		t.Errorf("reordered key list has the same ID")
+	}
+	a.AppendCosigner(pubKeys[n])
+	if a.GroupID() == id {
+		t.Errorf("extended key list has the same ID")
+	}
+}
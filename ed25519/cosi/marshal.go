@@ -0,0 +1,239 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// encodingVersion is the current version of the binary Cosigners encoding.
+const encodingVersion = 1
+
+// MarshalBinary encodes the Cosigners object's public keys,
+// in already-decoded form, together with its current participation mask.
+// The Policy is not included in the encoding.
+//
+// The encoding consists of a version byte,
+// a 4-byte big-endian count of public keys,
+// the affine x and y coordinates of each public key, 64 bytes per key,
+// and finally the participation mask.
+// Reloading this encoding with UnmarshalBinary is much faster
+// than calling NewCosignersErr on the original public keys,
+// since it avoids decompressing every point.
+func (cos *Cosigners) MarshalBinary() ([]byte, error) {
+	n := len(cos.keys)
+	data := make([]byte, 5, 5+64*n+len(cos.mask))
+	data[0] = encodingVersion
+	binary.BigEndian.PutUint32(data[1:5], uint32(n))
+
+	var x, y [32]byte
+	for i := range cos.keys {
+		cos.keys[i].ToAffineBytes(&x, &y)
+		data = append(data, x[:]...)
+		data = append(data, y[:]...)
+	}
+	data = append(data, cos.mask...)
+	return data, nil
+}
+
+// UnmarshalBinary replaces the Cosigners object's public keys and mask
+// with those in data, as previously encoded by MarshalBinary.
+// It keeps the object's current Policy,
+// or installs the default Policy if there is none,
+// but clears any abstention or witness set,
+// whose indices referred to the replaced keys.
+// UnmarshalBinary returns ErrEncodingVersion
+// if data was produced by an incompatible version of this package,
+// and ErrEncoding if data is otherwise malformed
+// or contains a point not on the curve.
+func (cos *Cosigners) UnmarshalBinary(data []byte) error {
+	if len(data) < 5 {
+		return ErrEncoding
+	}
+	if data[0] != encodingVersion {
+		return ErrEncodingVersion
+	}
+	n := binary.BigEndian.Uint32(data[1:5])
+	data = data[5:]
+	masklen := (uint64(n) + 7) >> 3
+	if uint64(len(data)) != 64*uint64(n)+masklen {
+		return ErrEncoding
+	}
+
+	keys := make([]edwards25519.ExtendedGroupElement, n)
+	var x, y [32]byte
+	for i := range keys {
+		copy(x[:], data[:32])
+		copy(y[:], data[32:64])
+		data = data[64:]
+		if !keys[i].FromAffineBytes(&x, &y) {
+			return ErrEncoding
+		}
+	}
+
+	// Start with an all-disabled participation mask, then set it correctly
+	cos.keys = keys
+	cos.index = nil
+	cos.torsionFree = nil
+	cos.revoked = nil
+	cos.abstain = nil
+	cos.witness = nil
+	if cos.weighted != nil {
+		cos.weighKeys()
+	}
+	cos.mask = make([]byte, masklen)
+	for i := range cos.mask {
+		cos.mask[i] = 0xff // all disabled
+	}
+	cos.aggr.Zero()
+	cos.enabled = 0
+	if cos.cache != nil {
+		cos.cache = newMaskCache(cos.cache.size)
+	}
+	cos.SetMask(data)
+
+	if cos.policy == nil {
+		cos.policy = fullPolicy{}
+	}
+	return nil
+}
+
+// Validate checks the internal consistency of the Cosigners object,
+// as a sanity check after UnmarshalBinary or other manipulation.
+// It returns a *KeyError wrapping ErrInvalidKey
+// for the first stored public key that is not a valid curve point,
+// ErrMaskLength or ErrMaskPadding if the participation mask is malformed,
+// and ErrInconsistent if the cached aggregate public key
+// or number of enabled cosigners differs from one recomputed from scratch
+// from the public keys and the participation mask.
+// Validate is not constant-time, and takes time linear
+// in the number of cosigners.
+func (cos *Cosigners) Validate() error {
+	var x, y, xy, zt [32]byte
+	var fe edwards25519.FieldElement
+	for i := range cos.keys {
+		// The affine coordinates must lie on the curve,
+		// and the extended coordinate T must equal XY/Z.
+		var P edwards25519.ExtendedGroupElement
+		key := &cos.keys[i]
+		key.ToAffineBytes(&x, &y)
+		edwards25519.FeMul(&fe, &key.X, &key.Y)
+		edwards25519.FeToBytes(&xy, &fe)
+		edwards25519.FeMul(&fe, &key.Z, &key.T)
+		edwards25519.FeToBytes(&zt, &fe)
+		if !P.FromAffineBytes(&x, &y) || xy != zt {
+			return &KeyError{i, ErrInvalidKey}
+		}
+	}
+
+	if len(cos.mask) != cos.MaskLen() {
+		return ErrMaskLength
+	}
+	if pad := len(cos.keys) & 7; pad != 0 {
+		high := byte(0xff) << uint(pad)
+		if cos.mask[len(cos.mask)-1]&high != high {
+			return ErrMaskPadding
+		}
+	}
+
+	var sum edwards25519.ExtendedGroupElement
+	sum.Zero()
+	enabled := 0
+	for i := range cos.keys {
+		if cos.MaskBit(i) == Enabled {
+			sum.Add(&sum, cos.aggKey(i))
+			enabled++
+		}
+	}
+	var want, got [32]byte
+	sum.ToBytes(&want)
+	cos.aggr.ToBytes(&got)
+	if want != got || enabled != cos.enabled {
+		return ErrInconsistent
+	}
+	return nil
+}
+
+// secretEncodingVersion is the current version of the binary Secret encoding.
+const secretEncodingVersion = 1
+
+// secretDeterministic flags the encoding of a deterministic Secret.
+const secretDeterministic = 1
+
+// MarshalBinary encodes an unused Secret,
+// so that a cosigner can persist it between Commit and Cosign
+// and resume the signing round after a crash.
+// It returns ErrSecretReused if the Secret has already been used.
+//
+// WARNING: a Secret must be used at most once,
+// or anyone who sees both signature parts can compute the private key.
+// The Secret object enforces this by erasing itself when used,
+// but the package cannot track copies made with MarshalBinary.
+// The caller must store the encoding encrypted,
+// restore it with UnmarshalBinary at most once,
+// and destroy every stored copy before sending the resulting signature part,
+// so that no copy survives to be restored and used again.
+// If in doubt, discard the Secret and restart the round with a fresh Commit.
+//
+// The encoding consists of a version byte, a flags byte,
+// and the 32-byte secret scalar,
+// followed for a Secret from CommitDeterministic
+// by its commitment and the digest of the message it may sign.
+func (secret *Secret) MarshalBinary() ([]byte, error) {
+	if !secret.valid {
+		return nil, ErrSecretReused
+	}
+	data := make([]byte, 2, 2+32+32+64)
+	data[0] = secretEncodingVersion
+	data = append(data, secret.reduced[:]...)
+	if secret.deterministic {
+		data[1] = secretDeterministic
+		data = append(data, secret.commit[:]...)
+		data = append(data, secret.message[:]...)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary replaces the Secret with the one encoded in data
+// by MarshalBinary, ready to be used by exactly one Cosign call,
+// after which it is erased as usual.
+// See MarshalBinary for the care persisting a Secret requires.
+// UnmarshalBinary returns ErrSecretEncoding, leaving the Secret unchanged,
+// if data is malformed, including if its secret scalar is not reduced
+// or, for a deterministic Secret, does not match its commitment.
+func (secret *Secret) UnmarshalBinary(data []byte) error {
+	if len(data) < 2+32 || data[0] != secretEncodingVersion {
+		return ErrSecretEncoding
+	}
+	var s Secret
+	copy(s.reduced[:], data[2:34])
+	if !scMinimal(s.reduced[:]) {
+		return ErrSecretEncoding
+	}
+	switch {
+	case data[1] == 0 && len(data) == 2+32:
+	case data[1] == secretDeterministic && len(data) == 2+32+32+64:
+		s.deterministic = true
+		copy(s.commit[:], data[34:66])
+		copy(s.message[:], data[66:])
+
+		var R edwards25519.ExtendedGroupElement
+		var commit [32]byte
+		edwards25519.GeScalarMultBase(&R, &s.reduced)
+		R.ToBytes(&commit)
+		if subtle.ConstantTimeCompare(commit[:], s.commit[:]) != 1 {
+			return ErrSecretEncoding
+		}
+	default:
+		return ErrSecretEncoding
+	}
+	s.valid = true
+	*secret = s
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetMaskBit(3, Disabled)
+	cos.SetMaskBit(9, Disabled)
+	cos.SetPolicy(ThresholdPolicy(n - 2))
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	data, err := cos.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded Cosigners
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.CountTotal() != n {
+		t.Errorf("loaded %d keys, want %d", loaded.CountTotal(), n)
+	}
+	if !bytes.Equal(loaded.Mask(), cos.Mask()) {
+		t.Errorf("mask does not round-trip")
+	}
+	if !bytes.Equal(loaded.AggregatePublicKey(), cos.AggregatePublicKey()) {
+		t.Errorf("aggregate public key does not round-trip")
+	}
+	keys := loaded.PublicKeys()
+	for i := range keys {
+		if !bytes.Equal(keys[i], pubKeys[i]) {
+			t.Errorf("public key %d does not round-trip", i)
+		}
+	}
+
+	// The policy is not encoded, so the loaded object uses the default.
+	if loaded.Verify(rightMessage, sig) {
+		t.Errorf("signature accepted under default policy")
+	}
+	loaded.SetPolicy(ThresholdPolicy(n - 2))
+	if !loaded.Verify(rightMessage, sig) {
+		t.Errorf("valid signature rejected after reload")
+	}
+
+	// Roles recorded for the old keys do not carry over to the new ones.
+	if err := cos.SetAbstaining([]int{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cos.SetWitnesses([]int{2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cos.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if cos.Abstaining(1) || cos.Witness(2) {
+		t.Errorf("UnmarshalBinary kept stale abstention or witness set")
+	}
+
+	bad := []struct {
+		data []byte
+		err  error
+	}{
+		{nil, ErrEncoding},
+		{data[:4], ErrEncoding},
+		{data[:len(data)-1], ErrEncoding},
+		{append(data[:len(data):len(data)], 0), ErrEncoding},
+		{append([]byte{encodingVersion + 1}, data[1:]...), ErrEncodingVersion},
+	}
+	for i, test := range bad {
+		if err := new(Cosigners).UnmarshalBinary(test.data); err != test.err {
+			t.Errorf("bad encoding %d: got %v, want %v", i, err, test.err)
+		}
+	}
+
+	// Corrupting a coordinate leaves a point off the curve.
+	corrupt := append([]byte{}, data...)
+	corrupt[5] ^= 1
+	if err := new(Cosigners).UnmarshalBinary(corrupt); err != ErrEncoding {
+		t.Errorf("off-curve point: got %v, want %v", err, ErrEncoding)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x08, 0x02})
+	data, _ := cos.MarshalBinary()
+	var loaded Cosigners
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatalf("freshly loaded Cosigners: %v", err)
+	}
+	loaded.SetMuSig(true)
+	if err := loaded.Validate(); err != nil {
+		t.Errorf("MuSig Cosigners: %v", err)
+	}
+	loaded.SetMuSig(false)
+
+	// Corrupt the cached aggregate.
+	c := loaded.Clone()
+	c.aggr = c.keys[0]
+	if err := c.Validate(); err != ErrInconsistent {
+		t.Errorf("corrupt aggregate: got %v", err)
+	}
+
+	// Corrupt the cached enabled count.
+	c = loaded.Clone()
+	c.enabled--
+	if err := c.Validate(); err != ErrInconsistent {
+		t.Errorf("corrupt count: got %v", err)
+	}
+
+	// Corrupt the mask behind SetMask's back.
+	c = loaded.Clone()
+	c.mask[0] ^= 0x01
+	if err := c.Validate(); err != ErrInconsistent {
+		t.Errorf("corrupt mask: got %v", err)
+	}
+	c.mask[0] ^= 0x01
+	c.mask[1] &^= 0x80
+	if err := c.Validate(); err != ErrMaskPadding {
+		t.Errorf("corrupt mask padding: got %v", err)
+	}
+
+	// Corrupt a key so it is no longer on the curve,
+	// and another so its extended coordinates disagree.
+	c = loaded.Clone()
+	c.keys[4].Y[0]++
+	if err := c.Validate(); err == nil {
+		t.Errorf("off-curve key accepted")
+	} else if ke, ok := err.(*KeyError); !ok || ke.Index != 4 || ke.Err != ErrInvalidKey {
+		t.Errorf("off-curve key: got %v", err)
+	}
+	c = loaded.Clone()
+	c.keys[7].T[0]++
+	if err := c.Validate(); err == nil {
+		t.Errorf("inconsistent key coordinates accepted")
+	} else if ke, ok := err.(*KeyError); !ok || ke.Index != 7 {
+		t.Errorf("inconsistent key coordinates: got %v", err)
+	}
+}
+
+func TestSecretMarshalBinary(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	// Deterministic secrets are tracked across tests,
+	// so sign a message no other test signs deterministically.
+	message := []byte("message signed after a restart")
+	commits[2], secrets[2] = cos.CommitDeterministic(priKeys[2], message)
+	aggR := cos.AggregateCommit(commits)
+
+	// Persist every secret, "crash", and resume from the encodings.
+	parts := make([]SignaturePart, n)
+	for i, secret := range secrets {
+		data, err := secret.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var restored Secret
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatalf("secret %d: %v", i, err)
+		}
+		parts[i], err = CosignErr(priKeys[i], &restored, message,
+			aggK, aggR)
+		if err != nil {
+			t.Fatalf("secret %d: %v", i, err)
+		}
+		if !restored.Used() {
+			t.Errorf("restored secret %d not invalidated after use", i)
+		}
+		if _, err := restored.MarshalBinary(); err != ErrSecretReused {
+			t.Errorf("used secret %d marshaled: got %v", i, err)
+		}
+	}
+	if !cos.Verify(message, cos.AggregateSignature(aggR, parts)) {
+		t.Errorf("signature from restored secrets rejected")
+	}
+
+	// A restored deterministic secret keeps its message binding.
+	data, _ := secrets[2].MarshalBinary()
+	var restored Secret
+	restored.UnmarshalBinary(data)
+	if _, err := CosignErr(priKeys[2], &restored, rightMessage,
+		aggK, aggR); err != ErrSecretMessage {
+		t.Errorf("restored deterministic secret on wrong message: got %v", err)
+	}
+
+	// Malformed encodings are refused.
+	good, _ := secrets[0].MarshalBinary()
+	bad := [][]byte{
+		nil,
+		good[:33],
+		append(append([]byte{}, good...), 0),
+		append([]byte{2}, good[1:]...),
+		append([]byte{1, 1}, good[2:]...),
+		append(good[:2:2], bytes.Repeat([]byte{0xff}, 32)...),
+	}
+	wrongCommit := append([]byte{}, data...)
+	wrongCommit[34] ^= 1
+	bad = append(bad, wrongCommit)
+	for i, data := range bad {
+		var s Secret
+		if err := s.UnmarshalBinary(data); err != ErrSecretEncoding {
+			t.Errorf("bad encoding %d: got %v", i, err)
+		}
+		if !s.Used() {
+			t.Errorf("bad encoding %d yielded a usable secret", i)
+		}
+	}
+}
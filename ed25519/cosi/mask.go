@@ -0,0 +1,128 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+// MaskBuilder assembles a participation bitmask
+// for a cosigner list of a given size,
+// in the packed little-endian form that SetMask accepts and Mask returns.
+// A new MaskBuilder starts with every cosigner Enabled,
+// as SetMask(nil) does.
+type MaskBuilder struct {
+	n    int
+	mask []byte
+}
+
+// NewMaskBuilder returns a MaskBuilder for a list of n cosigners.
+func NewMaskBuilder(n int) *MaskBuilder {
+	b := &MaskBuilder{n: n, mask: make([]byte, (n+7)>>3)}
+	b.EnableAll()
+	return b
+}
+
+// Enable marks cosigner i as Enabled.
+// It returns ErrSignerRange if i is not a valid cosigner index.
+func (b *MaskBuilder) Enable(i int) error {
+	if i < 0 || i >= b.n {
+		return ErrSignerRange
+	}
+	b.mask[i>>3] &^= byte(1) << uint(i&7)
+	return nil
+}
+
+// Disable marks cosigner i as Disabled.
+// It returns ErrSignerRange if i is not a valid cosigner index.
+func (b *MaskBuilder) Disable(i int) error {
+	if i < 0 || i >= b.n {
+		return ErrSignerRange
+	}
+	b.mask[i>>3] |= byte(1) << uint(i&7)
+	return nil
+}
+
+// EnableAll marks every cosigner as Enabled.
+func (b *MaskBuilder) EnableAll() {
+	for i := range b.mask {
+		b.mask[i] = 0
+	}
+	if pad := b.n & 7; pad != 0 {
+		b.mask[len(b.mask)-1] = byte(0xff) << uint(pad)
+	}
+}
+
+// DisableAll marks every cosigner as Disabled.
+func (b *MaskBuilder) DisableAll() {
+	for i := range b.mask {
+		b.mask[i] = 0xff
+	}
+}
+
+// Build returns the participation bitmask built so far.
+// Its length is the MaskLen of a list of n cosigners,
+// and any unused high bits of its last byte are set,
+// so that SetMaskStrict accepts it.
+// The MaskBuilder may continue to be used afterwards
+// without affecting the returned mask.
+func (b *MaskBuilder) Build() []byte {
+	return append([]byte{}, b.mask...)
+}
+
+// MaskEqual reports whether the participation bitmask
+// enables exactly the same cosigners as the packed mask other,
+// which is interpreted as in SetMask:
+// cosigners beyond the end of a short mask count as Enabled,
+// and bits beyond the last cosigner are ignored.
+func (cos *Cosigners) MaskEqual(other []byte) bool {
+	for i := range cos.keys {
+		if cos.MaskBit(i) != packedMaskBit(other, i) {
+			return false
+		}
+	}
+	return true
+}
+
+// MaskSubset reports whether every cosigner Enabled
+// in the packed mask required, interpreted as in SetMask,
+// is also Enabled in the participation bitmask,
+// i.e., whether the required cosigners are a subset
+// of those currently participating.
+func (cos *Cosigners) MaskSubset(required []byte) bool {
+	for i := range cos.keys {
+		if packedMaskBit(required, i) == Enabled &&
+			cos.MaskBit(i) == Disabled {
+			return false
+		}
+	}
+	return true
+}
+
+// MaskDiff compares the participation bitmask,
+// typically as set by the last Verify,
+// against the packed reference mask expected,
+// interpreted as in SetMask.
+// It returns, in increasing order, the indices of the cosigners
+// that are Enabled but were not expected to be,
+// and those that were expected to be Enabled but are not.
+// Both are empty if and only if MaskEqual(expected) is true.
+func (cos *Cosigners) MaskDiff(expected []byte) (added, missing []int) {
+	for i := range cos.keys {
+		got, want := cos.MaskBit(i), packedMaskBit(expected, i)
+		if got == Enabled && want == Disabled {
+			added = append(added, i)
+		} else if got == Disabled && want == Enabled {
+			missing = append(missing, i)
+		}
+	}
+	return added, missing
+}
+
+// packedMaskBit returns cosigner i's bit in a packed mask,
+// treating cosigners beyond the end of the mask as Enabled.
+func packedMaskBit(mask []byte, i int) MaskBit {
+	byt := i >> 3
+	if byt >= len(mask) {
+		return Enabled
+	}
+	return mask[byt]&(1<<uint(i&7)) != 0
+}
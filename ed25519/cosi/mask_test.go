@@ -0,0 +1,176 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestMaskBuilder(t *testing.T) {
+	tests := []struct {
+		n       int
+		enabled []int
+		mask    []byte
+	}{
+		{0, nil, []byte{}},
+		{3, nil, []byte{0xff}},
+		{3, []int{0, 1, 2}, []byte{0xf8}},
+		{8, []int{1, 7}, []byte{0x7d}},
+		{8, []int{0, 2, 4, 6}, []byte{0xaa}},
+		{10, []int{0, 9}, []byte{0xfe, 0xfd}},
+		{16, []int{8, 9, 10, 11, 12, 13, 14, 15}, []byte{0xff, 0x00}},
+	}
+	for _, test := range tests {
+		b := NewMaskBuilder(test.n)
+		b.DisableAll()
+		for _, i := range test.enabled {
+			if err := b.Enable(i); err != nil {
+				t.Fatal(err)
+			}
+		}
+		mask := b.Build()
+		if !bytes.Equal(mask, test.mask) {
+			t.Errorf("n=%d enabled %v: got mask %x, want %x",
+				test.n, test.enabled, mask, test.mask)
+		}
+
+		// The built mask must be the canonical form Mask returns.
+		genKeys(test.n)
+		cos, _ := NewCosignersErr(pubKeys[:test.n], nil)
+		if err := cos.SetMaskStrict(mask); err != nil {
+			t.Errorf("n=%d: SetMaskStrict rejected built mask: %v",
+				test.n, err)
+		}
+		if !bytes.Equal(cos.Mask(), mask) {
+			t.Errorf("n=%d: Mask() = %x, want %x", test.n, cos.Mask(), mask)
+		}
+
+		b.EnableAll()
+		for _, i := range test.enabled {
+			b.Disable(i)
+		}
+		cos.SetMask(b.Build())
+		if len(cos.DisabledSigners()) != len(test.enabled) {
+			t.Errorf("n=%d: Disable produced mask %x", test.n, b.Build())
+		}
+	}
+
+	b := NewMaskBuilder(10)
+	if !bytes.Equal(b.Build(), []byte{0x00, 0xfc}) {
+		t.Errorf("new builder is not all-enabled: %x", b.Build())
+	}
+	for _, i := range []int{-1, 10, 16} {
+		if b.Enable(i) != ErrSignerRange || b.Disable(i) != ErrSignerRange {
+			t.Errorf("index %d not rejected", i)
+		}
+	}
+	mask := b.Build()
+	mask[0] = 0xff
+	if b.Build()[0] != 0 {
+		t.Errorf("Build exposes internal state")
+	}
+}
+
+func TestMaskEqualSubset(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x0f, 0x02})
+	// Enabled: 4, 5, 6, 7, 8.
+
+	tests := []struct {
+		mask          []byte
+		equal, subset bool
+	}{
+		{[]byte{0x0f, 0x02}, true, true},
+		{[]byte{0x0f, 0xfe}, true, true},       // padding bits ignored
+		{[]byte{0x0f, 0x02, 0x00}, true, true}, // extra bytes ignored
+		{[]byte{0x0f, 0x03}, false, true},      // proper subset
+		{[]byte{0xff, 0xff}, false, true},      // empty set
+		{[]byte{0x1f, 0x02}, false, true},
+		{[]byte{0x0e, 0x02}, false, false}, // requires disabled cosigner 0
+		{[]byte{0x0f}, false, false},       // short mask enables 8 and 9
+		{nil, false, false},
+	}
+	for _, test := range tests {
+		if got := cos.MaskEqual(test.mask); got != test.equal {
+			t.Errorf("MaskEqual(%x) = %v, want %v", test.mask, got, test.equal)
+		}
+		if got := cos.MaskSubset(test.mask); got != test.subset {
+			t.Errorf("MaskSubset(%x) = %v, want %v", test.mask, got, test.subset)
+		}
+	}
+
+	cos.SetMask(nil)
+	if !cos.MaskEqual(nil) || !cos.MaskEqual([]byte{0x00}) ||
+		!cos.MaskSubset(nil) {
+		t.Errorf("all-enabled mask does not match short masks")
+	}
+}
+
+func TestMaskDiff(t *testing.T) {
+	n := 10
+	genKeys(n)
+	signers, _ := NewCosignersErr(pubKeys[:n], []byte{0x0f, 0x02})
+	// Enabled: 4, 5, 6, 7, 8.
+	sig := cosignWith(t, signers, func(i int, secret *Secret, aggK, aggR []byte) SignaturePart {
+		return Cosign(priKeys[i], secret, rightMessage, aggK, aggR)
+	})
+
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetPolicy(ThresholdPolicy(5))
+	if !cos.Verify(rightMessage, sig) {
+		t.Fatalf("signature rejected")
+	}
+
+	tests := []struct {
+		expected       []byte
+		added, missing []int
+	}{
+		{[]byte{0x0f, 0x02}, nil, nil},                 // exact match
+		{[]byte{0x0f, 0x03}, []int{8}, nil},            // addition
+		{[]byte{0x0e, 0x02}, nil, []int{0}},            // omission
+		{[]byte{0x8e, 0x01}, []int{7, 8}, []int{0, 9}}, // both
+		{nil, nil, []int{0, 1, 2, 3, 9}},
+	}
+	for _, test := range tests {
+		added, missing := cos.MaskDiff(test.expected)
+		if !reflect.DeepEqual(added, test.added) ||
+			!reflect.DeepEqual(missing, test.missing) {
+			t.Errorf("MaskDiff(%x) = %v, %v, want %v, %v", test.expected,
+				added, missing, test.added, test.missing)
+		}
+	}
+}
+
+func TestMaskInto(t *testing.T) {
+	n := 11
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x42, 0x02})
+
+	buf := []byte{0xaa, 0xaa, 0xaa}
+	written, err := cos.MaskInto(buf)
+	if err != nil || written != cos.MaskLen() {
+		t.Fatalf("MaskInto returned %d, %v", written, err)
+	}
+	if !bytes.Equal(buf[:written], cos.Mask()) || buf[2] != 0xaa {
+		t.Errorf("MaskInto wrote %x, Mask returns %x", buf, cos.Mask())
+	}
+
+	short := []byte{0xaa}
+	if written, err := cos.MaskInto(short); err != io.ErrShortBuffer ||
+		written != 0 || short[0] != 0xaa {
+		t.Errorf("short buffer: got %d, %v, wrote %x", written, err, short)
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		cos.MaskInto(buf)
+	})
+	if allocs != 0 {
+		t.Errorf("MaskInto allocated %v times", allocs)
+	}
+}
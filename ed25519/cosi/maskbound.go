@@ -0,0 +1,92 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/sha512"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// MaskBoundVersion is the version byte that mask-bound collective signatures
+// carry between the Ed25519 signature and the participation mask.
+const MaskBoundVersion = 1
+
+// maskBoundDomain separates mask-bound challenges
+// from those of every other signing mode.
+const maskBoundDomain = "CoSi Ed25519 mask-bound signature\x00"
+
+// maskDom returns the domain-separation prefix binding a challenge
+// to the participation mask, via its SHA-512 hash.
+func maskDom(mask []byte) []byte {
+	digest := sha512.Sum512(mask)
+	dom := make([]byte, 0, len(maskBoundDomain)+1+len(digest))
+	dom = append(dom, maskBoundDomain...)
+	dom = append(dom, MaskBoundVersion)
+	return append(dom, digest[:]...)
+}
+
+// CosignMaskBound is like CosignErr,
+// but also signs a hash of the participation mask
+// the leader announced for the signing round,
+// producing a part of a mask-bound collective signature.
+//
+// In an ordinary collective signature the mask is bound
+// only through the aggregate public key it selects,
+// so a leader could present any other mask selecting the same aggregate,
+// such as one differing only in its unused padding bits,
+// or one naming a single cosigner whose key is the sum of others' keys.
+// A mask-bound signature commits to the exact mask bits,
+// so the participant claim cannot be changed after the fact.
+// The cosigner should check the announced mask,
+// for example with VerifyAggregateContext, before calling CosignMaskBound.
+//
+// The leader combines the parts with AggregateSignatureMaskBound,
+// and verifiers must check the result with VerifyMaskBound.
+func CosignMaskBound(privateKey ed25519.PrivateKey, secret *Secret,
+	message, mask []byte, aggregateK ed25519.PublicKey,
+	aggregateR Commitment) (SignaturePart, error) {
+
+	return cosignDom(nil, privateKey, secret, maskDom(mask), message,
+		aggregateK, aggregateR)
+}
+
+// AggregateSignatureMaskBound combines signature parts
+// produced by CosignMaskBound into a mask-bound collective signature,
+// exactly as AggregateSignatureErr does for ordinary parts,
+// and returns the same errors.
+// A mask-bound signature is laid out as R || S || MaskBoundVersion || mask,
+// one byte longer than an ordinary collective signature,
+// so that neither kind can be mistaken for the other.
+func (cos *Cosigners) AggregateSignatureMaskBound(aggregateR Commitment,
+	sigParts []SignaturePart) ([]byte, error) {
+
+	sig, err := cos.AggregateSignatureErr(aggregateR, sigParts)
+	if err != nil {
+		return nil, err
+	}
+	bound := make([]byte, 0, len(sig)+1)
+	bound = append(bound, sig[:ed25519.SignatureSize]...)
+	bound = append(bound, MaskBoundVersion)
+	return append(bound, sig[ed25519.SignatureSize:]...), nil
+}
+
+// VerifyMaskBound is like Verify,
+// but checks a mask-bound collective signature
+// produced with AggregateSignatureMaskBound.
+// It returns false if the signature does not carry MaskBoundVersion,
+// or if its mask differs in any bit,
+// including unused padding bits,
+// from the mask the cosigners signed.
+func (cos *Cosigners) VerifyMaskBound(message, sig []byte) bool {
+	if len(sig) != ed25519.SignatureSize+1+cos.MaskLen() ||
+		sig[ed25519.SignatureSize] != MaskBoundVersion {
+		return false
+	}
+	mask := sig[ed25519.SignatureSize+1:]
+	return cos.verifySplit(maskDom(mask), message,
+		sig[:ed25519.SignatureSize], mask)
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// cosignMaskBound produces a mask-bound collective signature on message
+// by the cosigners enabled in cos, using the private keys in priKey.
+func cosignMaskBound(tb testing.TB, cos *Cosigners, priKey []ed25519.PrivateKey,
+	message []byte) []byte {
+
+	n := cos.CountTotal()
+	aggK := cos.AggregatePublicKey()
+	mask := cos.Mask()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		if cos.MaskBit(i) == Disabled {
+			continue
+		}
+		part, err := CosignMaskBound(priKey[i], secrets[i], message, mask,
+			aggK, aggR)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		parts[i] = part
+	}
+	sig, err := cos.AggregateSignatureMaskBound(aggR, parts)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return sig
+}
+
+func TestMaskBound(t *testing.T) {
+	// Cosigner 2's key is the sum of cosigners 0 and 1's keys,
+	// so the masks {0, 1} and {2} select the same aggregate public key.
+	genKeys(2)
+	A, _ := NewPoint(pubKeys[0])
+	B, _ := NewPoint(pubKeys[1])
+	keys := []ed25519.PublicKey{pubKeys[0], pubKeys[1], AddPoint(A, B).Bytes()}
+	cos, err := NewCosignersErr(keys, []byte{0x04})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetPolicy(ThresholdPolicy(1))
+	pri := []ed25519.PrivateKey{priKeys[0], priKeys[1], nil}
+
+	// An ordinary signature by 0 and 1 lets the leader
+	// claim instead that 2 signed alone, or fiddle with padding bits.
+	commits := make([]Commitment, 3)
+	secrets := make([]*Secret, 3)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggK, aggR := cos.AggregatePublicKey(), cos.AggregateCommit(commits)
+	parts := []SignaturePart{
+		Cosign(pri[0], secrets[0], rightMessage, aggK, aggR),
+		Cosign(pri[1], secrets[1], rightMessage, aggK, aggR),
+		nil,
+	}
+	sig := cos.AggregateSignature(aggR, parts)
+	if !cos.Verify(rightMessage, sig) {
+		t.Fatalf("ordinary signature rejected")
+	}
+	forged := append(append([]byte{}, sig[:64]...), 0xfb)
+	if !cos.Verify(rightMessage, forged) {
+		t.Errorf("expected ordinary signature to accept a changed mask")
+	}
+
+	// A mask-bound signature rejects every other mask.
+	cos.SetMask([]byte{0x04})
+	sig = cosignMaskBound(t, cos, pri, rightMessage)
+	if len(sig) != cos.SignatureSize()+1 || sig[64] != MaskBoundVersion {
+		t.Fatalf("bad mask-bound signature layout %x", sig)
+	}
+	if !cos.VerifyMaskBound(rightMessage, sig) {
+		t.Fatalf("mask-bound signature rejected")
+	}
+	if cos.MaskBit(0) != Enabled || cos.MaskBit(2) != Disabled {
+		t.Errorf("VerifyMaskBound did not set the mask")
+	}
+	if cos.VerifyMaskBound(wrongMessage, sig) {
+		t.Errorf("mask-bound signature accepted on wrong message")
+	}
+	for _, mask := range []byte{0xfb, 0x04, 0x05, 0x00, 0xfd} {
+		bad := append(append([]byte{}, sig[:65]...), mask)
+		if cos.VerifyMaskBound(rightMessage, bad) {
+			t.Errorf("mask-bound signature accepted with mask %#x", mask)
+		}
+	}
+
+	// The two kinds of signature are not interchangeable.
+	if cos.Verify(rightMessage, sig) {
+		t.Errorf("mask-bound signature accepted by Verify")
+	}
+	plain := append(append([]byte{}, sig[:64]...), sig[65:]...)
+	if cos.Verify(rightMessage, plain) {
+		t.Errorf("stripped mask-bound signature accepted by Verify")
+	}
+	bad := append([]byte{}, sig...)
+	bad[64] = MaskBoundVersion + 1
+	if cos.VerifyMaskBound(rightMessage, bad) {
+		t.Errorf("unknown version accepted")
+	}
+	if cos.VerifyMaskBound(rightMessage, sig[:len(sig)-1]) {
+		t.Errorf("truncated signature accepted")
+	}
+
+	// Parts must be aggregated as for ordinary signatures.
+	if _, err := cos.AggregateSignatureMaskBound(aggR[:31], parts); err != ErrCommitLength {
+		t.Errorf("short commit: got %v", err)
+	}
+	if !bytes.Equal(maskDom([]byte{0x04}), maskDom([]byte{0x04})) ||
+		bytes.Equal(maskDom([]byte{0x04}), maskDom([]byte{0xfc})) {
+		t.Errorf("mask domain does not depend on the mask")
+	}
+}
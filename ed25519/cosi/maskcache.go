@@ -0,0 +1,97 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"container/list"
+
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// maskCache memoizes the aggregate public key for recently-used masks,
+// evicting the least recently used entry once it reaches its size limit.
+type maskCache struct {
+	size    int
+	entries map[string]*list.Element
+	lru     list.List // of *maskCacheEntry, most recently used first
+	scratch []byte    // buffer for normalizing masks
+}
+
+type maskCacheEntry struct {
+	mask    string
+	aggr    edwards25519.ExtendedGroupElement
+	enabled int
+}
+
+func newMaskCache(size int) *maskCache {
+	return &maskCache{size: size, entries: make(map[string]*list.Element)}
+}
+
+// SetMaskCache enables memoization of the aggregate public key
+// for up to size distinct participation masks,
+// so that SetMask, and hence Verify,
+// can switch to a recently-used mask in constant time
+// rather than adding or subtracting the public key
+// of every cosigner whose participation changed.
+// When the cache is full, the least recently used mask is evicted,
+// which bounds its memory use even under adversarially-chosen masks.
+// This is worthwhile mainly for large cosigner lists
+// whose signatures alternate among a handful of masks.
+// A size of zero or less disables and discards the cache.
+func (cos *Cosigners) SetMaskCache(size int) {
+	if size <= 0 {
+		cos.cache = nil
+		return
+	}
+	cos.cache = newMaskCache(size)
+}
+
+// lookup normalizes mask as SetMask interprets it,
+// and if the resulting mask is in the cache,
+// installs it and its cached aggregate into cos and returns true.
+func (c *maskCache) lookup(cos *Cosigners, mask []byte) bool {
+	if cap(c.scratch) < len(cos.mask) {
+		c.scratch = make([]byte, len(cos.mask))
+	}
+	norm := c.scratch[:len(cos.mask)]
+	copy(norm, mask)
+	for i := len(mask); i < len(norm); i++ {
+		norm[i] = 0 // missing bytes are enabled
+	}
+	for i := range norm {
+		norm[i] |= cos.revokedByte(i)
+	}
+	if pad := len(cos.keys) & 7; pad != 0 {
+		norm[len(norm)-1] |= byte(0xff) << uint(pad)
+	}
+
+	elem, ok := c.entries[string(norm)]
+	if !ok {
+		return false
+	}
+	c.lru.MoveToFront(elem)
+	e := elem.Value.(*maskCacheEntry)
+	copy(cos.mask, norm)
+	cos.aggr = e.aggr
+	cos.enabled = e.enabled
+	return true
+}
+
+// add records the current mask and aggregate of cos in the cache.
+func (c *maskCache) add(cos *Cosigners) {
+	e := &maskCacheEntry{string(cos.mask), cos.aggr, cos.enabled}
+	if elem, ok := c.entries[e.mask]; ok {
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+		return
+	}
+	if c.lru.Len() >= c.size {
+		oldest := c.lru.Back()
+		delete(c.entries, oldest.Value.(*maskCacheEntry).mask)
+		c.lru.Remove(oldest)
+	}
+	c.entries[e.mask] = c.lru.PushFront(e)
+}
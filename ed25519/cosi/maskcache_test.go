@@ -0,0 +1,130 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestMaskCache(t *testing.T) {
+	n := 20
+	genKeys(n)
+	plain, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := plain.Clone()
+	cached.SetMaskCache(3)
+
+	// Draw masks from a small pool so that some hit the cache
+	// and others force evictions.
+	rnd := rand.New(rand.NewSource(1))
+	pool := make([][]byte, 5)
+	for i := range pool {
+		pool[i] = make([]byte, rnd.Intn(plain.MaskLen()+1))
+		rnd.Read(pool[i])
+	}
+	for iter := 0; iter < 200; iter++ {
+		mask := pool[rnd.Intn(len(pool))]
+		if rnd.Intn(4) == 0 {
+			bit := rnd.Intn(n)
+			plain.SetMaskBit(bit, Disabled)
+			cached.SetMaskBit(bit, Disabled)
+		} else {
+			plain.SetMask(mask)
+			cached.SetMask(mask)
+		}
+		if !bytes.Equal(plain.Mask(), cached.Mask()) ||
+			!bytes.Equal(plain.AggregatePublicKey(), cached.AggregatePublicKey()) ||
+			plain.CountEnabled() != cached.CountEnabled() {
+			t.Fatalf("iteration %d: cached state differs for mask %x", iter, mask)
+		}
+		if cached.cache.lru.Len() > 3 {
+			t.Fatalf("cache grew to %d entries", cached.cache.lru.Len())
+		}
+	}
+
+	cached.SetPolicy(ThresholdPolicy(0))
+	plain.SetPolicy(ThresholdPolicy(0))
+	sig := testCosign(t, rightMessage, priKeys[:n], plain)
+	if !cached.Verify(rightMessage, sig) || !cached.Verify(rightMessage, sig) {
+		t.Errorf("valid signature rejected with mask cache")
+	}
+
+	cached.SetMaskCache(0)
+	if cached.cache != nil {
+		t.Errorf("SetMaskCache(0) did not disable the cache")
+	}
+}
+
+func benchMaskAlternate(b *testing.B, cacheSize int) {
+	n := 1000
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	cos.SetMaskCache(cacheSize)
+
+	masks := [2][]byte{make([]byte, cos.MaskLen()), make([]byte, cos.MaskLen())}
+	for i := range masks[0] {
+		masks[0][i] = 0x55
+		masks[1][i] = 0xaa
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cos.SetMask(masks[i&1])
+	}
+}
+
+func BenchmarkSetMaskAlternateUncached(b *testing.B) {
+	benchMaskAlternate(b, 0)
+}
+
+func BenchmarkSetMaskAlternateCached(b *testing.B) {
+	benchMaskAlternate(b, 2)
+}
+
+// benchMaskOneBit measures setMask on a large cosigner list
+// when successive masks differ in a single bit,
+// as when verifying a stream of signatures from a stable group.
+func benchMaskOneBit(b *testing.B, setMask func(*Cosigners, []byte)) {
+	n := 4096
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	masks := [2][]byte{make([]byte, cos.MaskLen()), make([]byte, cos.MaskLen())}
+	masks[1][n>>4] = 0x10
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		setMask(cos, masks[i&1])
+	}
+}
+
+func BenchmarkSetMaskOneBit(b *testing.B) {
+	benchMaskOneBit(b, (*Cosigners).SetMask)
+}
+
+func BenchmarkSetMaskDeltaOneBit(b *testing.B) {
+	benchMaskOneBit(b, (*Cosigners).SetMaskDelta)
+}
+
+// BenchmarkSetMaskOneBitFromScratch measures the same mask changes
+// when the aggregate public key is recomputed from scratch each time,
+// as SetMask did before it updated the aggregate incrementally.
+func BenchmarkSetMaskOneBitFromScratch(b *testing.B) {
+	benchMaskOneBit(b, func(cos *Cosigners, mask []byte) {
+		cos.aggr.Zero()
+		for i := range cos.mask {
+			cos.mask[i] = 0xff
+		}
+		cos.enabled = 0
+		cos.SetMask(mask)
+	})
+}
@@ -0,0 +1,129 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// MergeGroups returns a new Cosigners object
+// for the union of two disjoint cosigner groups,
+// listing a's cosigners first, at their original indices,
+// followed by b's cosigners, whose indices are shifted by a.CountTotal().
+// The merged participation mask likewise holds a's current mask bits
+// followed by b's, so it has a.CountTotal()+b.CountTotal() bits.
+// The merged object uses the default Policy and hash,
+// and reuses the already-decoded public keys of a and b.
+//
+// MergeGroups does not check that the groups are in fact disjoint.
+// It returns ErrMergeMuSig if either group uses MuSig aggregation,
+// since MuSig coefficients depend on the entire key list.
+//
+// To produce a collective signature verifiable against the merged group,
+// each group's leader aggregates its own cosigners' commits,
+// AggregateCommitMerged combines the two aggregate commits,
+// and all cosigners sign using the merged aggregate commit
+// and the merged object's AggregatePublicKey.
+// Each leader then calls AggregateSignature on its own group,
+// passing its own group's aggregate commit,
+// and AggregateSignatureMerged combines the two resulting signatures.
+func MergeGroups(a, b *Cosigners) (*Cosigners, error) {
+	if a.weighted != nil || b.weighted != nil {
+		return nil, ErrMergeMuSig
+	}
+
+	na, nb := len(a.keys), len(b.keys)
+	cos := &Cosigners{}
+	cos.keys = make([]edwards25519.ExtendedGroupElement, 0, na+nb)
+	cos.keys = append(cos.keys, a.keys...)
+	cos.keys = append(cos.keys, b.keys...)
+
+	cos.mask = make([]byte, (na+nb+7)>>3)
+	for i := range cos.mask {
+		cos.mask[i] = 0xff // all disabled
+	}
+	cos.aggr.Zero()
+	cos.SetMask(mergeMasks(na, a.mask, nb, b.mask))
+
+	cos.policy = fullPolicy{}
+	return cos, nil
+}
+
+// mergeMasks concatenates the bits of a mask for na cosigners
+// with those of a mask for nb cosigners.
+func mergeMasks(na int, maskA []byte, nb int, maskB []byte) []byte {
+	mask := make([]byte, (na+nb+7)>>3)
+	for i := 0; i < na; i++ {
+		if packedMaskBit(maskA, i) == Disabled {
+			mask[i>>3] |= byte(1) << uint(i&7)
+		}
+	}
+	for i := 0; i < nb; i++ {
+		if packedMaskBit(maskB, i) == Disabled {
+			j := na + i
+			mask[j>>3] |= byte(1) << uint(j&7)
+		}
+	}
+	if pad := (na + nb) & 7; pad != 0 {
+		mask[len(mask)-1] |= byte(0xff) << uint(pad)
+	}
+	return mask
+}
+
+// AggregateCommitMerged combines the aggregate commits of two groups
+// into the aggregate commit for their merged group,
+// as described in MergeGroups.
+// It returns ErrCommitLength or ErrInvalidCommit
+// if either aggregate commit is malformed.
+func AggregateCommitMerged(aggregateRa, aggregateRb Commitment) (Commitment, error) {
+	var Ra, Rb edwards25519.ExtendedGroupElement
+	if err := decodeCommitment(&Ra, aggregateRa); err != nil {
+		return nil, err
+	}
+	if err := decodeCommitment(&Rb, aggregateRb); err != nil {
+		return nil, err
+	}
+	var R [32]byte
+	Ra.Add(&Ra, &Rb)
+	Ra.ToBytes(&R)
+	return R[:], nil
+}
+
+// AggregateSignatureMerged combines signatures sigA and sigB,
+// produced by AggregateSignature on groups a and b respectively
+// with each group's own aggregate commit,
+// into a collective signature for the group MergeGroups(a, b).
+// The commits and the masks are combined as in MergeGroups,
+// and the signature parts are summed.
+// The result is valid only if every cosigner
+// signed with the merged aggregate commit and aggregate public key.
+//
+// AggregateSignatureMerged returns ErrSignatureLength or ErrSignatureScalar
+// if either signature is malformed for its group,
+// and ErrInvalidCommit if either holds an invalid commit.
+func AggregateSignatureMerged(a, b *Cosigners, sigA, sigB []byte) ([]byte, error) {
+	if err := validateSignatureForm(sigA, a.MaskLen()); err != nil {
+		return nil, err
+	}
+	if err := validateSignatureForm(sigB, b.MaskLen()); err != nil {
+		return nil, err
+	}
+	R, err := AggregateCommitMerged(sigA[:32], sigB[:32])
+	if err != nil {
+		return nil, err
+	}
+
+	var Sa, Sb, S [32]byte
+	copy(Sa[:], sigA[32:64])
+	copy(Sb[:], sigB[32:64])
+	edwards25519.ScMulAdd(&S, &Sa, &scOne, &Sb)
+
+	mask := mergeMasks(len(a.keys), sigA[64:], len(b.keys), sigB[64:])
+	sig := make([]byte, 64, 64+len(mask))
+	copy(sig, R)
+	copy(sig[32:], S[:])
+	return append(sig, mask...), nil
+}
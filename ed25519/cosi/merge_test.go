@@ -0,0 +1,92 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMergeGroups(t *testing.T) {
+	na, nb := 3, 6
+	genKeys(na + nb)
+	a, _ := NewCosignersErr(pubKeys[:na], []byte{0x02})
+	b, _ := NewCosignersErr(pubKeys[na:na+nb], []byte{0x11})
+	merged, err := MergeGroups(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged.SetPolicy(ThresholdPolicy(6))
+
+	if merged.CountTotal() != na+nb || merged.MaskLen() != 2 {
+		t.Fatalf("merged group has %d cosigners, mask length %d",
+			merged.CountTotal(), merged.MaskLen())
+	}
+	for i := 0; i < na+nb; i++ {
+		var want MaskBit
+		if i < na {
+			want = a.MaskBit(i)
+		} else {
+			want = b.MaskBit(i - na)
+		}
+		if merged.MaskBit(i) != want {
+			t.Errorf("merged mask bit %d is %v", i, merged.MaskBit(i))
+		}
+	}
+	if !bytes.Equal(merged.PublicKeys()[na], pubKeys[na]) {
+		t.Errorf("b's keys not shifted after a's")
+	}
+
+	// Each group aggregates its own commits and signature parts.
+	commits := make([]Commitment, na+nb)
+	secrets := make([]*Secret, na+nb)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggRa := a.AggregateCommit(commits[:na])
+	aggRb := b.AggregateCommit(commits[na:])
+	aggR, err := AggregateCommitMerged(aggRa, aggRb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(aggR, merged.AggregateCommit(commits)) {
+		t.Errorf("AggregateCommitMerged differs from merged AggregateCommit")
+	}
+
+	aggK := merged.AggregatePublicKey()
+	parts := make([]SignaturePart, na+nb)
+	for i := range parts {
+		if merged.MaskBit(i) == Enabled {
+			parts[i] = Cosign(priKeys[i], secrets[i], rightMessage,
+				aggK, aggR)
+		}
+	}
+	sigA := a.AggregateSignature(aggRa, parts[:na])
+	sigB := b.AggregateSignature(aggRb, parts[na:])
+	sig, err := AggregateSignatureMerged(a, b, sigA, sigB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig) != 64+merged.MaskLen() {
+		t.Errorf("merged signature has length %d", len(sig))
+	}
+	if !merged.Verify(rightMessage, sig) {
+		t.Errorf("merged signature did not verify")
+	}
+	if merged.Verify(wrongMessage, sig) {
+		t.Errorf("merged signature verified on wrong message")
+	}
+	if want := merged.AggregateSignature(aggR, parts); !bytes.Equal(sig, want) {
+		t.Errorf("merged signature %x, want %x", sig, want)
+	}
+
+	if _, err := AggregateSignatureMerged(a, b, sigA, sigB[:64]); err != ErrSignatureLength {
+		t.Errorf("truncated signature: got %v", err)
+	}
+	b.SetMuSig(true)
+	if _, err := MergeGroups(a, b); err != ErrMergeMuSig {
+		t.Errorf("merging a MuSig group: got %v", err)
+	}
+}
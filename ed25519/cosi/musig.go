@@ -0,0 +1,177 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/sha512"
+
+	//"golang.org/x/crypto/ed25519"
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// muSigDomain separates MuSig coefficient hashes
+// from every other use of SHA-512 in this package.
+const muSigDomain = "CoSi Ed25519 MuSig coefficient\x00"
+
+// SetMuSig selects MuSig-style key aggregation when on is true,
+// or plain CoSi key aggregation, the default, when on is false.
+//
+// Plain CoSi aggregates the enabled cosigners' public keys K_i
+// by simply adding them,
+// which lets a participant who chooses its key after seeing the others
+// cancel them out unless every key comes with a proof of possession.
+// With MuSig aggregation, each key is first multiplied
+// by a coefficient a_i = SHA-512(tag || SHA-512(K_0 || ... || K_n-1) || K_i)
+// reduced modulo the group order,
+// as in the MuSig scheme of Maxwell, Poelstra, Seurin and Wuille,
+// so that no key can be chosen to cancel the others
+// without proofs of possession.
+//
+// The challenge, and hence the final collective signature,
+// has exactly the same form in both modes:
+// it is an ordinary Ed25519 signature on the message
+// under the aggregate public key,
+// followed by the participation mask.
+// But the aggregate public keys, and hence the signatures, differ,
+// so signers and verifiers must agree on the mode,
+// and signatures from one mode never verify in the other.
+// In MuSig mode, cosigners must produce their signature parts
+// with CosignCoefficient, passing their KeyCoefficient,
+// and VerifyPart checks parts against the weighted key a_i*K_i.
+// VerifyPartStandalone and the Cosigner type always use plain keys.
+//
+// Because every coefficient depends on the entire list of public keys,
+// AppendCosigner recomputes all of them in MuSig mode.
+// The mode is not recorded by MarshalBinary.
+func (cos *Cosigners) SetMuSig(on bool) {
+	if on == (cos.weighted != nil) {
+		return
+	}
+	if on {
+		cos.weighKeys()
+	} else {
+		cos.coefs = nil
+		cos.weighted = nil
+	}
+	cos.reaggregate()
+}
+
+// KeyCoefficient returns the MuSig coefficient a_i
+// of the cosigner at index i,
+// which that cosigner needs in order to produce its signature part
+// with CosignCoefficient.
+// It returns ErrSignerRange if i is not a valid cosigner index,
+// and ErrNotMuSig unless MuSig aggregation is enabled.
+func (cos *Cosigners) KeyCoefficient(i int) ([]byte, error) {
+	if i < 0 || i >= len(cos.keys) {
+		return nil, ErrSignerRange
+	}
+	if cos.coefs == nil {
+		return nil, ErrNotMuSig
+	}
+	a := cos.coefs[i]
+	return a[:], nil
+}
+
+// CosignCoefficient is used by a cosigner
+// of a Cosigners object in MuSig mode
+// to produce its part of a collective signature,
+// as CosignErr does in plain mode.
+// The coefficient is the cosigner's KeyCoefficient,
+// and the aggregateK must be the MuSig aggregate public key.
+// CosignCoefficient returns ErrInvalidScalar
+// if the coefficient is not a reduced 32-byte scalar,
+// and otherwise the same errors as CosignErr.
+// A deterministic secret is guarded by the challenge scaled by the coefficient,
+// since that is the scalar multiplied with the private key:
+// CosignCoefficient returns ErrSecretReused if an identical secret
+// was used with the same aggregate values but a different coefficient,
+// or by CosignErr.
+func CosignCoefficient(privateKey ed25519.PrivateKey, secret *Secret,
+	message []byte, aggregateK ed25519.PublicKey,
+	aggregateR Commitment, coefficient []byte) (SignaturePart, error) {
+
+	if len(coefficient) != 32 || !scMinimal(coefficient) {
+		return nil, ErrInvalidScalar
+	}
+	if err := checkCosign(privateKey, secret, aggregateR); err != nil {
+		return nil, err
+	}
+	if secret.deterministic {
+		digest := sha512.Sum512(message)
+		if err := secret.checkMessage(&digest); err != nil {
+			return nil, err
+		}
+	}
+
+	// s_i = r_i + c*(a_i*x_i) is the part for the weighted key a_i*K_i.
+	var a, zero [32]byte
+	copy(a[:], coefficient)
+	h := newHram(nil, nil, aggregateR, aggregateK)
+	h.Write(message)
+	c := reduceHram(h)
+	var ca [32]byte
+	edwards25519.ScMulAdd(&ca, &c, &a, &zero)
+	return cosignScalar(privateKey, secret, &ca)
+}
+
+// weighKeys computes the MuSig coefficients and weighted keys
+// for the current list of public keys.
+func (cos *Cosigners) weighKeys() {
+	n := len(cos.keys)
+	encoded := make([][32]byte, n)
+	list := sha512.New()
+	for i := range cos.keys {
+		cos.keys[i].ToBytes(&encoded[i])
+		list.Write(encoded[i][:])
+	}
+	var listHash [64]byte
+	list.Sum(listHash[:0])
+
+	var zero [32]byte
+	cos.coefs = make([][32]byte, n)
+	cos.weighted = make([]edwards25519.ExtendedGroupElement, n)
+	for i := range cos.keys {
+		h := sha512.New()
+		h.Write([]byte(muSigDomain))
+		h.Write(listHash[:])
+		h.Write(encoded[i][:])
+		var digest [64]byte
+		h.Sum(digest[:0])
+		edwards25519.ScReduce(&cos.coefs[i], &digest)
+
+		var P edwards25519.ProjectiveGroupElement
+		edwards25519.GeDoubleScalarMultVartime(&P, &cos.coefs[i],
+			&cos.keys[i], &zero)
+		P.ToExtended(&cos.weighted[i])
+	}
+}
+
+// aggKey returns the point that cosigner i contributes
+// to the aggregate public key:
+// its weighted key in MuSig mode, and otherwise its plain public key.
+func (cos *Cosigners) aggKey(i int) *edwards25519.ExtendedGroupElement {
+	if cos.weighted != nil {
+		return &cos.weighted[i]
+	}
+	return &cos.keys[i]
+}
+
+// reaggregate recomputes the aggregate public key from scratch
+// for the current mask, discarding any cached aggregates.
+func (cos *Cosigners) reaggregate() {
+	mask := append([]byte{}, cos.mask...)
+	for i := range cos.mask {
+		cos.mask[i] = 0xff // all disabled
+	}
+	cos.aggr.Zero()
+	cos.enabled = 0
+	if cos.cache != nil {
+		cos.cache = newMaskCache(cos.cache.size)
+	}
+	cos.SetMask(mask)
+}
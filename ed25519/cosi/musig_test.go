@@ -0,0 +1,177 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+func TestMuSig(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x04})
+	cos.SetPolicy(ThresholdPolicy(n - 1))
+	plainK := cos.AggregatePublicKey()
+	if _, err := cos.KeyCoefficient(0); err != ErrNotMuSig {
+		t.Errorf("KeyCoefficient in plain mode: got %v", err)
+	}
+
+	cos.SetMuSig(true)
+	aggK := cos.AggregatePublicKey()
+	if bytes.Equal(aggK, plainK) {
+		t.Fatalf("MuSig aggregate key equals plain aggregate key")
+	}
+	if _, err := cos.KeyCoefficient(n); err != ErrSignerRange {
+		t.Errorf("KeyCoefficient out of range: got %v", err)
+	}
+
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		if cos.MaskBit(i) == Disabled {
+			continue
+		}
+		a, err := cos.KeyCoefficient(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[i], err = CosignCoefficient(priKeys[i], secrets[i],
+			rightMessage, aggK, aggR, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cos.VerifyPart(rightMessage, aggR, i, commits[i], parts[i]) {
+			t.Errorf("VerifyPart rejected MuSig part %d", i)
+		}
+	}
+	sig := cos.AggregateSignature(aggR, parts)
+
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("MuSig signature did not verify")
+	}
+	if cos.Verify(wrongMessage, sig) {
+		t.Errorf("MuSig signature verified on wrong message")
+	}
+	if !ed25519.Verify(aggK, rightMessage, sig[:64]) {
+		t.Errorf("MuSig signature is not an Ed25519 signature")
+	}
+	plain := cos.Clone()
+	plain.SetMuSig(false)
+	if !bytes.Equal(plain.AggregatePublicKey(), plainK) {
+		t.Errorf("SetMuSig(false) did not restore the plain aggregate")
+	}
+	if plain.Verify(rightMessage, sig) {
+		t.Errorf("MuSig signature verified in plain mode")
+	}
+
+	// A MuSig part is not a valid plain part, and vice versa.
+	c, s, _ := Commit(testRand)
+	part := Cosign(priKeys[0], s, rightMessage, aggK, c)
+	if cos.VerifyPart(rightMessage, c, 0, c, part) {
+		t.Errorf("plain part accepted in MuSig mode")
+	}
+
+	// A deterministic secret used with one coefficient
+	// may not be used with another, nor in plain mode,
+	// even on identical aggregate values.
+	// Deterministic secrets are tracked across tests,
+	// so sign a message no other test signs deterministically.
+	message := []byte("deterministic MuSig part")
+	c, _ = cos.CommitDeterministic(priKeys[0], message)
+	a0, _ := cos.KeyCoefficient(0)
+	a1, _ := cos.KeyCoefficient(1)
+	_, s = cos.CommitDeterministic(priKeys[0], message)
+	if _, err := CosignCoefficient(priKeys[0], s, message, aggK, c,
+		a0); err != nil {
+		t.Fatal(err)
+	}
+	_, s = cos.CommitDeterministic(priKeys[0], message)
+	if _, err := CosignCoefficient(priKeys[0], s, message, aggK, c,
+		a1); err != ErrSecretReused {
+		t.Errorf("deterministic secret with another coefficient: got %v", err)
+	}
+	if _, err := CosignErr(priKeys[0], s, message, aggK,
+		c); err != ErrSecretReused {
+		t.Errorf("deterministic MuSig secret in plain mode: got %v", err)
+	}
+}
+
+// TestMuSigVector checks the MuSig coefficients and aggregate key
+// against values computed by an independent implementation.
+func TestMuSigVector(t *testing.T) {
+	genKeys(3)
+	cos, _ := NewCosignersErr(pubKeys[:3], nil)
+	cos.SetMuSig(true)
+
+	keys := []string{
+		"3b6a27bcceb6a42d62a3a8d02a6f0d73653215771de243a63ac048a18b59da29",
+		"8a88e3dd7409f195fd52db2d3cba5d72ca6709bf1d94121bf3748801b40f6f5c",
+		"8139770ea87d175f56a35466c34c7ecccb8d8a91b4ee37a25df60f5b8fc9b394",
+	}
+	for i, k := range keys {
+		if hex.EncodeToString(pubKeys[i]) != k {
+			t.Fatalf("test key %d changed: %x", i, pubKeys[i])
+		}
+	}
+	a, _ := cos.KeyCoefficient(0)
+	if got, want := hex.EncodeToString(a),
+		"a806c12d751aa09f67dcea1faad58a9c1ec615553bd06766a13ab68e9fe5cb0f"; got != want {
+		t.Errorf("coefficient 0 is %s, want %s", got, want)
+	}
+	if got, want := hex.EncodeToString(cos.AggregatePublicKey()),
+		"8a6a6725ed28851b3b6f6373d1b88f462ed82b5f299321b897c28dd333ee6c7b"; got != want {
+		t.Errorf("aggregate key is %s, want %s", got, want)
+	}
+}
+
+func TestMuSigAppend(t *testing.T) {
+	n := 9
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n-1], []byte{0x02})
+	cos.SetMuSig(true)
+	cos.SetMaskCache(4)
+	if err := cos.AppendCosigner(pubKeys[n-1]); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := NewCosignersErr(pubKeys[:n], []byte{0x02})
+	want.SetMuSig(true)
+	if !bytes.Equal(cos.AggregatePublicKey(), want.AggregatePublicKey()) ||
+		cos.CountEnabled() != want.CountEnabled() {
+		t.Errorf("AppendCosigner did not reweigh the keys")
+	}
+}
+
+// TestMuSigRogueKey checks that a key chosen to cancel
+// another cosigner's key controls the plain aggregate but not the MuSig one.
+func TestMuSigRogueKey(t *testing.T) {
+	genKeys(2)
+	minusOne := groupOrder
+	minusOne[0]--
+	m, _ := NewScalar(minusOne[:])
+	victim, _ := NewPoint(pubKeys[0])
+	target, _ := NewPoint(pubKeys[1])
+	rogue := AddPoint(target, ScalarMul(m, victim))
+
+	keys := []ed25519.PublicKey{pubKeys[0], rogue.Bytes()}
+	cos, _ := NewCosignersErr(keys, nil)
+	if !bytes.Equal(cos.AggregatePublicKey(), pubKeys[1]) {
+		t.Fatalf("rogue key did not control the plain aggregate")
+	}
+	cos.SetMuSig(true)
+	if bytes.Equal(cos.AggregatePublicKey(), pubKeys[1]) {
+		t.Errorf("rogue key controls the MuSig aggregate")
+	}
+}
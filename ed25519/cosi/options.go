@@ -0,0 +1,89 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"hash"
+	"io"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// An Option configures a Cosigners object as NewCosignersErr creates it.
+// Each option has the same effect as the corresponding setter
+// called immediately after construction.
+// Options are the preferred way to configure a new object;
+// the setters remain for objects not made by NewCosignersErr,
+// such as those restored by UnmarshalBinary,
+// whose encoding records neither the Policy nor the hash function.
+type Option func(*options)
+
+// options collects the settings requested by Options.
+type options struct {
+	policy      Policy
+	newHash     func() hash.Hash
+	rand        io.Reader
+	parallel    int
+	strict      bool
+	rejectEmpty bool
+}
+
+// WithPolicy installs policy in place of the default Policy,
+// as SetPolicy does.
+func WithPolicy(policy Policy) Option {
+	return func(o *options) { o.policy = policy }
+}
+
+// WithRejectEmpty rejects signatures with no participating cosigners
+// whatever the Policy, as SetRejectEmpty(true) does.
+func WithRejectEmpty() Option {
+	return func(o *options) { o.rejectEmpty = true }
+}
+
+// WithHash installs newHash as the challenge hash, as SetHash does.
+// NewCosignersErr returns ErrHashSize
+// if newHash does not produce 64-byte digests.
+func WithHash(newHash func() hash.Hash) Option {
+	return func(o *options) { o.newHash = newHash }
+}
+
+// WithRand makes the Cosigners object draw its randomness from rand,
+// as SetRand does.
+// A deterministic source makes higher-level signing flows reproducible
+// in tests, but must never be used to produce real signatures.
+func WithRand(rand io.Reader) Option {
+	return func(o *options) { o.rand = rand }
+}
+
+// WithParallelThreshold makes the Cosigners object sum points
+// in parallel for threshold or more cosigners,
+// as SetParallelThreshold does.
+func WithParallelThreshold(threshold int) Option {
+	return func(o *options) { o.parallel = threshold }
+}
+
+// WithStrictKeys makes NewCosignersErr reject public keys
+// that are not canonically encoded, are of small order,
+// or appear more than once, exactly as NewCosignersStrict does.
+func WithStrictKeys() Option {
+	return func(o *options) { o.strict = true }
+}
+
+// apply configures a newly-created cos according to o.
+func (o *options) apply(cos *Cosigners, publicKeys []ed25519.PublicKey) error {
+	if o.strict {
+		if err := cos.checkStrictKeys(publicKeys); err != nil {
+			return err
+		}
+	}
+	if o.policy != nil {
+		cos.SetPolicy(o.policy)
+	}
+	cos.SetRejectEmpty(o.rejectEmpty)
+	cos.SetRand(o.rand)
+	cos.SetParallelThreshold(o.parallel)
+	return cos.SetHash(o.newHash)
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"reflect"
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+func TestOptions(t *testing.T) {
+	n := 4
+	genKeys(n)
+	keys := pubKeys[:n]
+
+	// No options: default policy and hash, lenient keys.
+	cos, err := NewCosignersErr(keys, []byte{0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cos.policy != (fullPolicy{}) || cos.newHash != nil {
+		t.Errorf("zero-option constructor is not the default")
+	}
+	dup := []ed25519.PublicKey{keys[0], keys[1], keys[0]}
+	if _, err := NewCosignersErr(dup, nil); err != nil {
+		t.Errorf("zero-option constructor rejected duplicate keys: %v", err)
+	}
+
+	// WithPolicy
+	policy := ThresholdPolicy(3)
+	cos, err = NewCosignersErr(keys, []byte{0x01}, WithPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cos.policy != policy {
+		t.Errorf("WithPolicy did not install the policy")
+	}
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("signature rejected under WithPolicy")
+	}
+
+	// WithStrictKeys
+	var de *DuplicateKeyError
+	if _, err := NewCosignersErr(dup, nil, WithStrictKeys()); !errors.As(err, &de) ||
+		de.Index != 0 || de.Duplicate != 2 {
+		t.Errorf("WithStrictKeys on duplicate keys: got %v", err)
+	}
+	small := []ed25519.PublicKey{keys[0], identity[:]}
+	if _, err := NewCosignersErr(small, nil, WithStrictKeys()); !errors.Is(err, ErrSmallOrderKey) {
+		t.Errorf("WithStrictKeys on small-order key: got %v", err)
+	}
+	strict, err := NewCosignersErr(keys, nil, WithStrictKeys())
+	if err != nil {
+		t.Errorf("WithStrictKeys rejected good keys: %v", err)
+	}
+	lenient, _ := NewCosignersErr(keys, nil)
+	if !reflect.DeepEqual(strict, lenient) {
+		t.Errorf("WithStrictKeys changed the resulting object")
+	}
+
+	// WithHash
+	newHash := func() hash.Hash { return sha512.New() }
+	cos, err = NewCosignersErr(keys, nil, WithHash(newHash), WithPolicy(policy))
+	if err != nil || cos.newHash == nil || cos.policy != policy {
+		t.Errorf("WithHash with WithPolicy: %v", err)
+	}
+	if _, err := NewCosignersErr(keys, nil, WithHash(sha256.New)); err != ErrHashSize {
+		t.Errorf("WithHash with a 32-byte hash: got %v", err)
+	}
+
+	// WithRand
+	commit := func(cos *Cosigners) Commitment {
+		c, _, err := cos.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	seeded, _ := NewCosignersErr(keys, nil, WithRand(constReader{7}))
+	c1 := commit(seeded)
+	seeded, _ = NewCosignersErr(keys, nil, WithRand(constReader{7}))
+	c2 := commit(seeded)
+	c3, _, _ := Commit(constReader{7})
+	if !bytes.Equal(c1, c2) || !bytes.Equal(c1, c3) {
+		t.Errorf("WithRand commits not reproducible")
+	}
+	if cos, _ := NewCosignersErr(keys, nil); cos.rand != nil {
+		t.Errorf("source of randomness set without WithRand")
+	}
+}
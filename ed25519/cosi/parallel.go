@@ -0,0 +1,93 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"runtime"
+	"sync"
+
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// SetParallelThreshold sets the number of cosigners at or above which
+// SetMask and AggregateCommit split their point summations
+// across up to GOMAXPROCS goroutines.
+// Because point addition is associative and commutative,
+// the parallel result is the same point as the serial one,
+// and encodes to identical bytes.
+// The default threshold of zero disables parallel summation.
+// Like the other setters, SetParallelThreshold
+// must not be called concurrently with other uses of the object.
+func (cos *Cosigners) SetParallelThreshold(threshold int) {
+	cos.parallel = threshold
+}
+
+// parallelChunks returns the number of chunks
+// into which a summation over n cosigners should be split.
+func (cos *Cosigners) parallelChunks(n int) int {
+	if cos.parallel <= 0 || n < cos.parallel {
+		return 1
+	}
+	chunks := runtime.GOMAXPROCS(0)
+	if max := (n + 7) >> 3; chunks > max {
+		chunks = max // keep at least one mask byte per chunk
+	}
+	if chunks < 1 {
+		chunks = 1
+	}
+	return chunks
+}
+
+// parallelSum computes a point sum over cosigner indices [0,n).
+// It calls f once per chunk with a disjoint range [lo,hi) of indices
+// and a zeroed point into which f accumulates that chunk's contribution.
+// Chunk boundaries are multiples of 8, so that each chunk
+// touches a disjoint set of mask bytes.
+// The partial sums are combined in chunk order.
+// If any chunk fails, parallelSum returns the error
+// from the lowest-numbered failing chunk.
+func (cos *Cosigners) parallelSum(n int, f func(chunk, lo, hi int,
+	sum *edwards25519.ExtendedGroupElement) error) (
+	edwards25519.ExtendedGroupElement, error) {
+
+	var total edwards25519.ExtendedGroupElement
+	total.Zero()
+
+	chunks := cos.parallelChunks(n)
+	if chunks == 1 {
+		err := f(0, 0, n, &total)
+		return total, err
+	}
+
+	size := ((n+chunks-1)/chunks + 7) &^ 7
+	sums := make([]edwards25519.ExtendedGroupElement, chunks)
+	errs := make([]error, chunks)
+	var wg sync.WaitGroup
+	for c := 0; c < chunks; c++ {
+		lo, hi := c*size, (c+1)*size
+		if hi > n {
+			hi = n
+		}
+		sums[c].Zero()
+		if lo >= hi {
+			continue
+		}
+		wg.Add(1)
+		go func(c, lo, hi int) {
+			defer wg.Done()
+			errs[c] = f(c, lo, hi, &sums[c])
+		}(c, lo, hi)
+	}
+	wg.Wait()
+
+	for c := range sums {
+		if errs[c] != nil {
+			return total, errs[c]
+		}
+		total.Add(&total, &sums[c])
+	}
+	return total, nil
+}
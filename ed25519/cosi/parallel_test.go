@@ -0,0 +1,96 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"crypto/rand"
+	"runtime"
+	"testing"
+)
+
+func TestParallelSum(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	for _, n := range []int{1, 7, 9, 64, 333} {
+		genKeys(n)
+		commits := make([]Commitment, n)
+		for i := range commits {
+			commits[i], _, _ = Commit(testRand)
+		}
+		mask := make([]byte, (n+7)>>3)
+		rand.Read(mask)
+
+		var aggK, aggR [2][]byte
+		var enabled [2]int
+		for j, thres := range []int{0, 1} {
+			cos, err := NewCosignersErr(pubKeys[:n], nil,
+				WithParallelThreshold(thres))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cos.SetMask(mask)
+			R, err := cos.AggregateCommitErr(commits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			aggK[j], aggR[j] = cos.AggregatePublicKey(), R
+			enabled[j] = cos.CountEnabled()
+		}
+		if !bytes.Equal(aggK[0], aggK[1]) || !bytes.Equal(aggR[0], aggR[1]) ||
+			enabled[0] != enabled[1] {
+			t.Errorf("n=%d: parallel result differs from serial", n)
+		}
+	}
+
+	// The error reported must be the first bad commit, as when serial.
+	n := 64
+	genKeys(n)
+	commits := make([]Commitment, n)
+	for i := range commits {
+		commits[i], _, _ = Commit(testRand)
+	}
+	commits[40] = commits[40][:31]
+	commits[50] = invalidPoint[:]
+	cos, _ := NewCosignersErr(pubKeys[:n], nil, WithParallelThreshold(1))
+	_, err := cos.AggregateCommitErr(commits)
+	if ce, ok := err.(*CommitError); !ok || ce.Index != 40 {
+		t.Errorf("got error %v, want commit error at index 40", err)
+	}
+	if cos.Clone().parallel != 1 {
+		t.Errorf("Clone dropped the parallel threshold")
+	}
+}
+
+func benchAggregate(b *testing.B, thres int) {
+	n := 4096
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil, WithParallelThreshold(thres))
+	if err != nil {
+		b.Fatal(err)
+	}
+	commits := make([]Commitment, n)
+	for i := range commits {
+		commits[i], _, _ = Commit(testRand)
+	}
+	masks := [2][]byte{make([]byte, cos.MaskLen()), make([]byte, cos.MaskLen())}
+	for i := range masks[0] {
+		masks[0][i] = 0x55
+		masks[1][i] = 0xaa
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cos.SetMask(masks[i&1])
+		cos.AggregateCommit(commits)
+	}
+}
+
+func BenchmarkAggregate4096Serial(b *testing.B) {
+	benchAggregate(b, 0)
+}
+
+func BenchmarkAggregate4096Parallel(b *testing.B) {
+	benchAggregate(b, 1)
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// Point is an opaque, already-decoded point on the Ed25519 curve,
+// such as an aggregate commit,
+// which higher-level aggregation layers can reuse
+// without repeatedly decoding its 32-byte encoding.
+//
+// Point and Scalar, together with the functions operating on them,
+// are thin wrappers around the internal curve primitives
+// that this package itself uses.
+// They are meant for building CoSi variants,
+// such as schemes with per-key aggregation coefficients,
+// and offer only the handful of operations those need:
+// they are not a general-purpose elliptic curve library.
+type Point struct {
+	p edwards25519.ExtendedGroupElement
+}
+
+// NewPoint decodes a point from its standard 32-byte encoding.
+// It returns ErrInvalidPoint if b is not exactly 32 bytes long,
+// or is not the canonical encoding of a point on the curve.
+// Unlike a Commitment, a Point may be the identity.
+func NewPoint(b []byte) (*Point, error) {
+	if len(b) != 32 {
+		return nil, ErrInvalidPoint
+	}
+	var s [32]byte
+	copy(s[:], b)
+	if !canonicalPoint(&s) || (isIdentity(&s) && s[31] != 0) {
+		return nil, ErrInvalidPoint
+	}
+	P := new(Point)
+	if !P.p.FromBytes(&s) {
+		return nil, ErrInvalidPoint
+	}
+	return P, nil
+}
+
+// Bytes returns the standard 32-byte encoding of the point.
+func (P *Point) Bytes() []byte {
+	var b [32]byte
+	P.p.ToBytes(&b)
+	return b[:]
+}
+
+// AddPoint returns the sum of points a and b.
+func AddPoint(a, b *Point) *Point {
+	P := new(Point)
+	P.p.Add(&a.p, &b.p)
+	return P
+}
+
+// ScalarMulBase returns s times the Ed25519 base point.
+// It runs in constant time, and may be used with secret scalars.
+func ScalarMulBase(s *Scalar) *Point {
+	P := new(Point)
+	edwards25519.GeScalarMultBase(&P.p, &s.s)
+	return P
+}
+
+// ScalarMul returns s times the point A.
+// ScalarMul is not constant-time,
+// and must only be used with public scalars,
+// such as key aggregation coefficients.
+func ScalarMul(s *Scalar, A *Point) *Point {
+	var zero [32]byte
+	var r edwards25519.ProjectiveGroupElement
+	edwards25519.GeDoubleScalarMultVartime(&r, &s.s, &A.p, &zero)
+
+	P := new(Point)
+	r.ToExtended(&P.p)
+	return P
+}
+
+// Scalar is an opaque integer modulo the order of the Ed25519 base point.
+// See Point for the intended scope of these types.
+type Scalar struct {
+	s [32]byte
+}
+
+// NewScalar decodes a scalar from its 32-byte little-endian encoding.
+// It returns ErrInvalidScalar if b is not exactly 32 bytes long,
+// or is not reduced below the group order.
+func NewScalar(b []byte) (*Scalar, error) {
+	if len(b) != 32 || !scMinimal(b) {
+		return nil, ErrInvalidScalar
+	}
+	s := new(Scalar)
+	copy(s.s[:], b)
+	return s, nil
+}
+
+// ReduceScalar reduces a 64-byte little-endian integer,
+// such as a SHA-512 digest, modulo the group order.
+// This is how Ed25519 derives challenges and nonces from hashes.
+func ReduceScalar(wide *[64]byte) *Scalar {
+	s := new(Scalar)
+	edwards25519.ScReduce(&s.s, wide)
+	return s
+}
+
+// Bytes returns the 32-byte little-endian encoding of the scalar.
+func (s *Scalar) Bytes() []byte {
+	b := s.s
+	return b[:]
+}
+
+// MulAddScalar returns a*b + c modulo the group order.
+func MulAddScalar(a, b, c *Scalar) *Scalar {
+	s := new(Scalar)
+	edwards25519.ScMulAdd(&s.s, &a.s, &b.s, &c.s)
+	return s
+}
+
+// AggregateCommitPoint combines cosigners' individual commits
+// exactly as AggregateCommitErr does,
+// but returns the aggregate commit as a decoded Point.
+func (cos *Cosigners) AggregateCommitPoint(commits []Commitment) (*Point, error) {
+	aggR, err := cos.parallelSum(len(cos.keys), func(_, lo, hi int,
+		sum *edwards25519.ExtendedGroupElement) error {
+		var indivR edwards25519.ExtendedGroupElement
+		for i := lo; i < hi; i++ {
+			if cos.MaskBit(i) == Disabled {
+				continue
+			}
+
+			if i >= len(commits) {
+				return newCommitError(i, ErrCommitLength)
+			}
+			if err := decodeCommitmentReason(&indivR, commits[i]); err != nil {
+				return newCommitError(i, err)
+			}
+			sum.Add(sum, &indivR)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Point{aggR}, nil
+}
+
+// AggregatePublicKeyPoint returns the aggregate public key
+// of the cosigners currently enabled in the participation bitmask,
+// as AggregatePublicKey does, but as a decoded Point,
+// so that hierarchical schemes can combine aggregate keys
+// without re-parsing them.
+// The returned Point is a copy,
+// unaffected by later changes to the participation bitmask.
+func (cos *Cosigners) AggregatePublicKeyPoint() *Point {
+	return &Point{cos.aggr}
+}
@@ -0,0 +1,128 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"testing"
+)
+
+func TestAggregateCommitPoint(t *testing.T) {
+	n := 7
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x24})
+	commits := make([]Commitment, n)
+	for i := range commits {
+		commits[i], _, _ = Commit(testRand)
+	}
+
+	P, err := cos.AggregateCommitPoint(commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(P.Bytes(), cos.AggregateCommit(commits)) {
+		t.Errorf("AggregateCommitPoint encodes to %x, AggregateCommit %x",
+			P.Bytes(), cos.AggregateCommit(commits))
+	}
+
+	commits[3] = invalidPoint
+	if _, err := cos.AggregateCommitPoint(commits); err == nil {
+		t.Errorf("invalid commit accepted")
+	} else if ce, ok := err.(*CommitError); !ok || ce.Index != 3 {
+		t.Errorf("got error %v, want CommitError at 3", err)
+	}
+}
+
+func TestAggregatePublicKeyPoint(t *testing.T) {
+	n := 7
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x24})
+	P := cos.AggregatePublicKeyPoint()
+	if !bytes.Equal(P.Bytes(), cos.AggregatePublicKey()) {
+		t.Errorf("AggregatePublicKeyPoint encodes to %x, AggregatePublicKey %x",
+			P.Bytes(), cos.AggregatePublicKey())
+	}
+
+	// The aggregate keys of two disjoint halves add up to the whole.
+	cos.SetMask([]byte{0x0f})
+	hi := cos.AggregatePublicKeyPoint()
+	cos.SetMask([]byte{0xf0})
+	lo := cos.AggregatePublicKeyPoint()
+	cos.SetMask(nil)
+	if !bytes.Equal(AddPoint(lo, hi).Bytes(), cos.AggregatePublicKey()) {
+		t.Errorf("sum of partial aggregate keys differs from the whole")
+	}
+}
+
+func TestPointScalar(t *testing.T) {
+	var wa, wb [64]byte
+	wa[0], wa[40] = 7, 9
+	wb[0], wb[63] = 3, 0xff
+	a, b := ReduceScalar(&wa), ReduceScalar(&wb)
+	one, _ := NewScalar(scOne[:])
+	zero, _ := NewScalar(make([]byte, 32))
+
+	// Encoding round trips.
+	a2, err := NewScalar(a.Bytes())
+	if err != nil || *a2 != *a {
+		t.Errorf("scalar round trip: %v", err)
+	}
+	A := ScalarMulBase(a)
+	A2, err := NewPoint(A.Bytes())
+	if err != nil || !bytes.Equal(A2.Bytes(), A.Bytes()) {
+		t.Errorf("point round trip: %v", err)
+	}
+	id, err := NewPoint(identity[:])
+	if err != nil || !bytes.Equal(ScalarMulBase(zero).Bytes(), id.Bytes()) {
+		t.Errorf("identity round trip: %v", err)
+	}
+
+	// Arithmetic: aB + bB == (a+b)B, and a(bB) == (ab)B.
+	B := ScalarMulBase(b)
+	sum := ScalarMulBase(MulAddScalar(a, one, b))
+	if !bytes.Equal(AddPoint(A, B).Bytes(), sum.Bytes()) {
+		t.Errorf("aB + bB != (a+b)B")
+	}
+	prod := ScalarMulBase(MulAddScalar(a, b, zero))
+	if !bytes.Equal(ScalarMul(a, B).Bytes(), prod.Bytes()) {
+		t.Errorf("a(bB) != (ab)B")
+	}
+	if !bytes.Equal(AddPoint(A, id).Bytes(), A.Bytes()) {
+		t.Errorf("A + 0 != A")
+	}
+
+	// The key of a cosigner matches its private scalar.
+	genKeys(1)
+	h := sha512.Sum512(priKeys[0][:32])
+	h[0] &= 248
+	h[31] &= 127
+	h[31] |= 64
+	var wide [64]byte
+	copy(wide[:], h[:32])
+	if !bytes.Equal(ScalarMulBase(ReduceScalar(&wide)).Bytes(), pubKeys[0]) {
+		t.Errorf("ScalarMulBase does not reproduce a public key")
+	}
+
+	// Invalid encodings.
+	negZero := identity
+	negZero[31] |= 0x80
+	nonCanonical := [32]byte{0xee}
+	for i := 1; i < 31; i++ {
+		nonCanonical[i] = 0xff
+	}
+	nonCanonical[31] = 0x7f
+	for _, b := range [][]byte{nil, invalidPoint, negZero[:],
+		nonCanonical[:], make([]byte, 33)} {
+		if _, err := NewPoint(b); err != ErrInvalidPoint {
+			t.Errorf("NewPoint(%x): got %v", b, err)
+		}
+	}
+	for _, b := range [][]byte{nil, groupOrder[:], make([]byte, 31)} {
+		if _, err := NewScalar(b); err != ErrInvalidScalar {
+			t.Errorf("NewScalar(%x): got %v", b, err)
+		}
+	}
+}
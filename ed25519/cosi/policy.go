@@ -0,0 +1,375 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// Policy represents a fully customizable cosigning policy
+// deciding what cosigner sets are and aren't sufficient
+// for a collective signature to be considered acceptable to a verifier.
+// The Check method may inspect the set of participants that cosigned
+// by invoking cosigners.Mask and/or cosigners.MaskBit,
+// and may use any other relevant contextual information
+// (e.g., how security-critical
+// the operation relying on the collective signature is)
+// in determining whether the collective signature
+// was produced by an acceptable set of cosigners.
+type Policy interface {
+	Check(cosigners *Cosigners) bool
+}
+
+// ContextPolicy is a Policy that can also take into account
+// the message whose collective signature is being verified,
+// for example to demand more cosigners for more sensitive messages.
+// When the registered Policy, or the one passed to VerifyWithPolicy,
+// implements ContextPolicy,
+// the verification methods that are given the whole message
+// call CheckCtx instead of Check.
+// Check is still used where no message is available,
+// such as in VerifyStream and VerifyMulti
+// and in methods like PolicyNeeds and MinimalSatisfyingSet
+// that evaluate the Policy independently of any signature,
+// so it should accept only the sets acceptable for every message.
+// VerifyPrehashed passes CheckCtx the digest it is given,
+// and the domain-separated forms such as VerifyCtx
+// pass the message without its context or prefix.
+type ContextPolicy interface {
+	Policy
+	CheckCtx(cosigners *Cosigners, message []byte) bool
+}
+
+// PolicyNeeds reports whether the registered Policy
+// would reject the current participation set without the given signer,
+// i.e., whether disabling that signer in the participation bitmask
+// would cause the Policy's Check to fail.
+// A leader collecting signature parts can use it
+// to decide whether to keep waiting for a slow cosigner:
+// by disabling the cosigners known to have failed
+// and asking about each one still pending.
+// PolicyNeeds leaves the participation bitmask unchanged,
+// and returns false if signer is out of range.
+func (cos *Cosigners) PolicyNeeds(signer int) bool {
+	if signer < 0 || signer >= len(cos.keys) {
+		return false
+	}
+	old := cos.MaskBit(signer)
+	cos.SetMaskBit(signer, Disabled)
+	ok := cos.checkPolicy(cos.policy)
+	cos.SetMaskBit(signer, old)
+	return !ok
+}
+
+// MinimalSatisfyingSet returns a minimal set of the cosigners
+// currently enabled in the participation bitmask
+// that satisfies the registered Policy, in increasing order,
+// or false if even the whole enabled set does not satisfy it.
+// A leader can use it to decide which cosigners to contact first.
+//
+// The set is minimal in that removing any one cosigner from it
+// makes the Policy fail, provided the Policy is monotone,
+// i.e., never rejects a superset of a set it accepts,
+// as all the policies in this package are.
+// It is not necessarily the smallest such set,
+// which for arbitrary policies would require an exponential search.
+// Instead MinimalSatisfyingSet tries dropping each enabled cosigner in turn,
+// from the highest index to the lowest,
+// keeping it only if the Policy fails without it,
+// and so calls the Policy's Check at most CountEnabled()+1 times.
+// For ThresholdPolicy(t) the result is the t lowest-indexed enabled cosigners.
+// The participation bitmask is left unchanged.
+func (cos *Cosigners) MinimalSatisfyingSet() ([]int, bool) {
+	if !cos.checkPolicy(cos.policy) {
+		return nil, false
+	}
+	saved := cos.Mask()
+	enabled := cos.EnabledSigners()
+	for k := len(enabled) - 1; k >= 0; k-- {
+		i := enabled[k]
+		cos.SetMaskBit(i, Disabled)
+		if !cos.checkPolicy(cos.policy) {
+			cos.SetMaskBit(i, Enabled)
+		}
+	}
+	set := cos.EnabledSigners()
+	cos.SetMask(saved)
+	return set, true
+}
+
+// maxEnumerate is the largest number of cosigners
+// for which SatisfyingMasks enumerates participation masks.
+const maxEnumerate = 20
+
+// SatisfyingMasks returns every participation mask,
+// in the form Mask returns,
+// for which the registered Policy accepts a collective signature,
+// ordered by the set of enabled cosigners read as a binary number,
+// with cosigner 0 as the least significant bit.
+// It is meant for testing and analyzing policies on small groups,
+// since it calls the Policy's Check once for each of the 2^N subsets
+// of N cosigners,
+// and it returns ErrTooManySigners if there are more than 20 cosigners.
+// Masks enabling a cosigner revoked by DisablePermanently are omitted,
+// since such a cosigner can never count as participating.
+// The participation bitmask is left unchanged.
+func (cos *Cosigners) SatisfyingMasks() ([][]byte, error) {
+	n := len(cos.keys)
+	if n > maxEnumerate {
+		return nil, ErrTooManySigners
+	}
+	saved := cos.Mask()
+
+	// Visit the subsets in Gray-code order,
+	// so that successive subsets differ in a single cosigner.
+	var sets []uint32
+	var set, revoked uint32
+	for i := 0; i < n; i++ {
+		cos.SetMaskBit(i, Disabled)
+		if cos.Revoked(i) {
+			revoked |= 1 << uint(i)
+		}
+	}
+	for k := uint32(0); ; k++ {
+		if k > 0 {
+			i := bits.TrailingZeros32(k)
+			set ^= 1 << uint(i)
+			if set&(1<<uint(i)) != 0 {
+				cos.SetMaskBit(i, Enabled)
+			} else {
+				cos.SetMaskBit(i, Disabled)
+			}
+		}
+		if set&revoked == 0 && cos.checkPolicy(cos.policy) {
+			sets = append(sets, set)
+		}
+		if k == 1<<uint(n)-1 {
+			break
+		}
+	}
+	cos.SetMask(saved)
+
+	sort.Slice(sets, func(a, b int) bool { return sets[a] < sets[b] })
+	masks := make([][]byte, len(sets))
+	for m, set := range sets {
+		masks[m] = make([]byte, len(cos.mask))
+		for j := range masks[m] {
+			masks[m][j] = ^byte(set >> (8 * uint(j)))
+		}
+	}
+	return masks, nil
+}
+
+// The default, conservative policy
+// just requires all participants to have signed.
+type fullPolicy struct{}
+
+func (_ fullPolicy) Check(cosigners *Cosigners) bool {
+	return cosigners.CountEnabled() == cosigners.CountTotal()
+}
+
+type thresPolicy struct{ t int }
+
+func (p thresPolicy) Check(cosigners *Cosigners) bool {
+	return cosigners.CountEnabled() >= p.t
+}
+
+// ThresholdPolicy creates a Policy object representing a simple T-of-N policy,
+// which deems a collective signature acceptable provided
+// that at least the given threshold number of participants cosigned.
+func ThresholdPolicy(threshold int) Policy {
+	return &thresPolicy{threshold}
+}
+
+type quorumPolicy struct{ num, den int }
+
+func (p quorumPolicy) Check(cosigners *Cosigners) bool {
+	total := cosigners.CountTotal()
+	if total == 0 {
+		return false
+	}
+	// Compare enabled*den >= total*num in 128 bits,
+	// so that no product can overflow.
+	hi1, lo1 := bits.Mul64(uint64(cosigners.CountEnabled()), uint64(p.den))
+	hi2, lo2 := bits.Mul64(uint64(total), uint64(p.num))
+	return hi1 > hi2 || (hi1 == hi2 && lo1 >= lo2)
+}
+
+// QuorumPolicy creates a Policy object that deems a collective signature
+// acceptable provided that at least the fraction numerator/denominator
+// of all cosigners participated,
+// such as QuorumPolicy(2, 3) for "two-thirds of all cosigners".
+// The fraction is checked exactly in integer arithmetic,
+// so a signature by exactly two-thirds of the cosigners
+// satisfies QuorumPolicy(2, 3).
+// An empty cosigner list satisfies no quorum.
+// QuorumPolicy panics if denominator is not positive,
+// or numerator is negative or exceeds denominator.
+func QuorumPolicy(numerator, denominator int) Policy {
+	if denominator <= 0 || numerator < 0 || numerator > denominator {
+		panic("cosi: bad quorum fraction: " + strconv.Itoa(numerator) +
+			"/" + strconv.Itoa(denominator))
+	}
+	return &quorumPolicy{numerator, denominator}
+}
+
+type weightedPolicy struct {
+	weights   []int
+	threshold int
+}
+
+func (p *weightedPolicy) Check(cosigners *Cosigners) bool {
+	if len(p.weights) != cosigners.CountTotal() {
+		return false
+	}
+	// Weights are non-negative, so total never decreases;
+	// stop as soon as the threshold is reached
+	// rather than risk overflowing the sum.
+	total := 0
+	for i, w := range p.weights {
+		if cosigners.MaskBit(i) == Enabled {
+			if w >= p.threshold-total {
+				return true
+			}
+			total += w
+		}
+	}
+	return total >= p.threshold
+}
+
+// WeightedThresholdPolicy creates a Policy object
+// in which each cosigner i carries a voting weight of weights[i],
+// and which deems a collective signature acceptable provided
+// that the total weight of the participating cosigners
+// is at least the given threshold.
+// The weights slice must have one entry per cosigner;
+// otherwise the policy rejects every signature.
+// The total weight is computed without overflow,
+// so weights may be as large as the int type permits.
+// WeightedThresholdPolicy panics if any weight is negative,
+// since the policy would then no longer be monotone:
+// an additional cosigner could turn an acceptable signature
+// into an unacceptable one.
+func WeightedThresholdPolicy(weights []int, threshold int) Policy {
+	for i, w := range weights {
+		if w < 0 {
+			panic("cosi: bad weight for cosigner " + strconv.Itoa(i) +
+				": " + strconv.Itoa(w))
+		}
+	}
+	return &weightedPolicy{append([]int{}, weights...), threshold}
+}
+
+type subsetPolicy []int
+
+func (p subsetPolicy) Check(cosigners *Cosigners) bool {
+	for _, signer := range p {
+		if signer < 0 || signer >= cosigners.CountTotal() ||
+			cosigners.MaskBit(signer) == Disabled {
+			return false
+		}
+	}
+	return true
+}
+
+// SubsetPolicy creates a Policy object that deems a collective signature
+// acceptable only if every cosigner whose index is listed in required
+// participated.
+// An index outside the range of cosigners is never satisfied.
+// SubsetPolicy is typically combined with ThresholdPolicy using AndPolicy,
+// to express rules such as "any 5 of 9, but signer 0 must be present".
+func SubsetPolicy(required []int) Policy {
+	return subsetPolicy(append([]int{}, required...))
+}
+
+type andPolicy []Policy
+
+func (p andPolicy) Check(cosigners *Cosigners) bool {
+	for _, policy := range p {
+		if !policy.Check(cosigners) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckCtx passes message on to the children that are ContextPolicies.
+func (p andPolicy) CheckCtx(cosigners *Cosigners, message []byte) bool {
+	for _, policy := range p {
+		if !checkCtx(policy, cosigners, message) {
+			return false
+		}
+	}
+	return true
+}
+
+type orPolicy []Policy
+
+func (p orPolicy) Check(cosigners *Cosigners) bool {
+	for _, policy := range p {
+		if policy.Check(cosigners) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCtx passes message on to the children that are ContextPolicies.
+func (p orPolicy) CheckCtx(cosigners *Cosigners, message []byte) bool {
+	for _, policy := range p {
+		if checkCtx(policy, cosigners, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCtx checks policy with CheckCtx if it is a ContextPolicy,
+// and with Check otherwise.
+func checkCtx(policy Policy, cosigners *Cosigners, message []byte) bool {
+	if cp, ok := policy.(ContextPolicy); ok {
+		return cp.CheckCtx(cosigners, message)
+	}
+	return policy.Check(cosigners)
+}
+
+// AndPolicy creates a Policy object that deems a collective signature
+// acceptable only if every one of the given policies does.
+// The policies are checked in order,
+// and checking stops at the first policy that rejects.
+// An AndPolicy with no children accepts every signature.
+// As with SetPolicy, a nil child stands for the default policy
+// requiring all cosigners to participate.
+// Children that are ContextPolicies are given the message being verified,
+// wherever the verification method provides one.
+func AndPolicy(policies ...Policy) Policy {
+	return andPolicy(normalizePolicies(policies))
+}
+
+// OrPolicy creates a Policy object that deems a collective signature
+// acceptable if at least one of the given policies does.
+// The policies are checked in order,
+// and checking stops at the first policy that accepts.
+// An OrPolicy with no children rejects every signature.
+// As with SetPolicy, a nil child stands for the default policy
+// requiring all cosigners to participate.
+// Children that are ContextPolicies are given the message being verified,
+// wherever the verification method provides one.
+func OrPolicy(policies ...Policy) Policy {
+	return orPolicy(normalizePolicies(policies))
+}
+
+func normalizePolicies(policies []Policy) []Policy {
+	p := make([]Policy, len(policies))
+	for i, policy := range policies {
+		if policy == nil {
+			policy = fullPolicy{}
+		}
+		p[i] = policy
+	}
+	return p
+}
@@ -0,0 +1,577 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"hash"
+	"reflect"
+	"testing"
+)
+
+func TestWeightedThresholdPolicy(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := WeightedThresholdPolicy([]int{10, 1, 1, 1, 1}, 10)
+
+	// The single high-weight signer suffices on its own.
+	cos.SetMask([]byte{0x1e})
+	if !policy.Check(cos) {
+		t.Errorf("high-weight signer alone rejected")
+	}
+
+	// Without it, all four low-weight signers are not enough.
+	cos.SetMask([]byte{0x01})
+	if policy.Check(cos) {
+		t.Errorf("insufficient low-weight signers accepted")
+	}
+
+	policy = WeightedThresholdPolicy([]int{10, 1, 1, 1, 1}, 4)
+	if !policy.Check(cos) {
+		t.Errorf("sufficient low-weight signers rejected")
+	}
+	cos.SetMaskBit(4, Disabled)
+	if policy.Check(cos) {
+		t.Errorf("insufficient low-weight signers accepted")
+	}
+
+	policy = WeightedThresholdPolicy([]int{10, 1, 1}, 0)
+	if policy.Check(cos) {
+		t.Errorf("policy with wrong number of weights accepted")
+	}
+
+	// Huge weights must not wrap around when summed.
+	max := int(^uint(0) >> 1)
+	policy = WeightedThresholdPolicy([]int{max, max, max, 0, 0}, max)
+	cos.SetMask([]byte{0x18})
+	if !policy.Check(cos) {
+		t.Errorf("huge weights overflowed")
+	}
+	cos.SetMask([]byte{0x1f})
+	if policy.Check(cos) {
+		t.Errorf("zero-weight signers accepted")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("negative weight accepted")
+		}
+	}()
+	WeightedThresholdPolicy([]int{10, -1, 1, 1, 1}, 10)
+}
+
+func TestAndOrPolicy(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// At least 3 signers, and one of them must be the CA key (signer 0).
+	policy := AndPolicy(ThresholdPolicy(3), SubsetPolicy([]int{0}))
+
+	tests := []struct {
+		mask byte
+		ok   bool
+	}{
+		{0x00, true},  // everyone
+		{0x18, true},  // 0, 1, 2
+		{0x19, false}, // 1, 2 only
+		{0x1e, false}, // CA alone
+		{0x01, false}, // 4 signers but not the CA
+	}
+	for _, test := range tests {
+		cos.SetMask([]byte{test.mask})
+		if ok := policy.Check(cos); ok != test.ok {
+			t.Errorf("mask %02x: got %v, want %v", test.mask, ok, test.ok)
+		}
+	}
+
+	// Either the CA alone, or any 4 signers; nested arbitrarily.
+	policy = OrPolicy(AndPolicy(SubsetPolicy([]int{0})), OrPolicy(ThresholdPolicy(4)))
+	for _, test := range []struct {
+		mask byte
+		ok   bool
+	}{
+		{0x1e, true},
+		{0x01, true},
+		{0x03, false},
+	} {
+		cos.SetMask([]byte{test.mask})
+		if ok := policy.Check(cos); ok != test.ok {
+			t.Errorf("mask %02x: got %v, want %v", test.mask, ok, test.ok)
+		}
+	}
+
+	cos.SetMask([]byte{0x1f})
+	if !AndPolicy().Check(cos) {
+		t.Errorf("empty AndPolicy rejected")
+	}
+	if OrPolicy().Check(cos) {
+		t.Errorf("empty OrPolicy accepted")
+	}
+	cos.SetMask(nil)
+	if !AndPolicy(nil).Check(cos) {
+		t.Errorf("nil child policy rejected full participation")
+	}
+}
+
+func TestSubsetPolicy(t *testing.T) {
+	n := 9
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Any 5 of 9, but signer 0 must always be present.
+	policy := AndPolicy(ThresholdPolicy(5), SubsetPolicy([]int{0}))
+	tests := []struct {
+		mask []byte
+		ok   bool
+	}{
+		{[]byte{0x00, 0x00}, true},
+		{[]byte{0xf0, 0x00}, true},
+		{[]byte{0xf0, 0x01}, false},
+		{[]byte{0x01, 0x00}, false},
+	}
+	for _, test := range tests {
+		cos.SetMask(test.mask)
+		if ok := policy.Check(cos); ok != test.ok {
+			t.Errorf("mask %x: got %v, want %v", test.mask, ok, test.ok)
+		}
+	}
+
+	cos.SetMask([]byte{0x06, 0x00})
+	if !SubsetPolicy([]int{0, 3, 8}).Check(cos) {
+		t.Errorf("required signers present but rejected")
+	}
+	if SubsetPolicy([]int{0, 2}).Check(cos) {
+		t.Errorf("required signer absent but accepted")
+	}
+	if SubsetPolicy([]int{0, n}).Check(cos) ||
+		SubsetPolicy([]int{-1}).Check(cos) {
+		t.Errorf("out-of-range required signer accepted")
+	}
+
+	// The empty required set always passes.
+	cos.SetMask([]byte{0xff, 0xff})
+	if !SubsetPolicy(nil).Check(cos) {
+		t.Errorf("empty SubsetPolicy rejected")
+	}
+}
+
+func TestRejectEmpty(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil, WithPolicy(ThresholdPolicy(0)))
+
+	// Anyone can produce a signature with nobody participating:
+	// R and the aggregate key are the identity, and S is zero.
+	// The identity aggregate key makes verification fail even so.
+	empty := make([]byte, 65)
+	copy(empty, identity[:])
+	empty[64] = 0xff
+	if cos.Verify(rightMessage, empty) {
+		t.Errorf("empty signature accepted without the guard")
+	}
+	if ok, policyOK, _ := cos.VerifyDetailed(rightMessage, empty); ok || !policyOK {
+		t.Errorf("empty signature without the guard: crypto %v, policy %v",
+			ok, policyOK)
+	}
+
+	cos.SetRejectEmpty(true)
+	if cos.Verify(rightMessage, empty) {
+		t.Errorf("empty signature accepted by Verify with the guard")
+	}
+	if cos.VerifyConstantTime(rightMessage, empty) {
+		t.Errorf("empty signature accepted by VerifyConstantTime with the guard")
+	}
+	if ok, _ := cos.VerifyBatch([][]byte{rightMessage}, [][]byte{empty}); ok {
+		t.Errorf("empty signature accepted by VerifyBatch with the guard")
+	}
+	if cos.Clone().Verify(rightMessage, empty) {
+		t.Errorf("Clone lost the guard")
+	}
+	cos.SetMask(nil)
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("non-empty signature rejected with the guard")
+	}
+	if set, ok := cos.MinimalSatisfyingSet(); !ok || len(set) != 1 {
+		t.Errorf("MinimalSatisfyingSet with the guard = %v, %v", set, ok)
+	}
+
+	cos, _ = NewCosignersErr(pubKeys[:n], nil,
+		WithPolicy(ThresholdPolicy(0)), WithRejectEmpty())
+	if cos.Verify(rightMessage, empty) {
+		t.Errorf("empty signature accepted with WithRejectEmpty")
+	}
+}
+
+func TestVerifyWithPolicy(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetMaskBit(2, Disabled)
+	sig := cosignWith(t, cos, func(i int, secret *Secret,
+		aggK, aggR []byte) SignaturePart {
+		return Cosign(priKeys[i], secret, rightMessage, aggK, aggR)
+	})
+
+	registered := ThresholdPolicy(3)
+	cos.SetPolicy(registered)
+	if !cos.VerifyWithPolicy(rightMessage, sig, ThresholdPolicy(4)) {
+		t.Errorf("signature rejected under satisfied policy")
+	}
+	if cos.VerifyWithPolicy(rightMessage, sig, ThresholdPolicy(5)) {
+		t.Errorf("signature accepted under stricter policy")
+	}
+	if cos.VerifyWithPolicy(rightMessage, sig, nil) {
+		t.Errorf("signature accepted under default full policy")
+	}
+	if cos.VerifyWithPolicy(wrongMessage, sig, ThresholdPolicy(4)) {
+		t.Errorf("signature accepted on wrong message")
+	}
+
+	if cos.policy != registered {
+		t.Errorf("VerifyWithPolicy changed the registered policy")
+	}
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("signature rejected under registered policy")
+	}
+}
+
+// panicHash is a 64-byte hash whose Write panics once armed.
+type panicHash struct {
+	hash.Hash
+	armed *bool
+}
+
+func (h panicHash) Write(p []byte) (int, error) {
+	if *h.armed {
+		panic("hash failure")
+	}
+	return h.Hash.Write(p)
+}
+
+func TestVerifyThreshold(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x14})
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	registered := ThresholdPolicy(n)
+	cos.SetPolicy(registered)
+	if !cos.VerifyThreshold(rightMessage, sig, 3) {
+		t.Errorf("signature rejected at satisfied threshold")
+	}
+	if cos.VerifyThreshold(rightMessage, sig, 4) {
+		t.Errorf("signature accepted above its number of signers")
+	}
+	if cos.VerifyThreshold(wrongMessage, sig, 0) {
+		t.Errorf("signature accepted on wrong message")
+	}
+	if cos.policy != registered || cos.Verify(rightMessage, sig) {
+		t.Errorf("VerifyThreshold changed the registered policy")
+	}
+
+	// The registered policy survives a panic during verification.
+	armed := false
+	if err := cos.SetHash(func() hash.Hash {
+		return panicHash{sha512.New(), &armed}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	armed = true
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected hash panic")
+			}
+		}()
+		cos.VerifyThreshold(rightMessage, sig, 3)
+	}()
+	if cos.policy != registered {
+		t.Errorf("panic left a different policy registered")
+	}
+}
+
+// flagPolicy is a ContextPolicy requiring all cosigners
+// for messages containing the byte '!', and any two otherwise.
+type flagPolicy struct{}
+
+func (flagPolicy) Check(cos *Cosigners) bool {
+	return cos.CountEnabled() == len(cos.keys)
+}
+
+func (flagPolicy) CheckCtx(cos *Cosigners, message []byte) bool {
+	if bytes.IndexByte(message, '!') >= 0 {
+		return flagPolicy{}.Check(cos)
+	}
+	return cos.CountEnabled() >= 2
+}
+
+func TestContextPolicy(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x05})
+	plain := []byte("routine update")
+	urgent := []byte("emergency update!")
+	plainSig := testCosign(t, plain, priKeys[:n], cos)
+	urgentSig := testCosign(t, urgent, priKeys[:n], cos)
+
+	cos.SetPolicy(flagPolicy{})
+	if !cos.Verify(plain, plainSig) {
+		t.Errorf("CheckCtx not used for a plain message")
+	}
+	if cos.Verify(urgent, urgentSig) {
+		t.Errorf("flagged message accepted with two signers")
+	}
+	if cos.VerifyStream(bytes.NewReader(plain), plainSig) {
+		t.Errorf("VerifyStream did not fall back to Check")
+	}
+	if !cos.VerifyWithPolicy(plain, plainSig, flagPolicy{}) {
+		t.Errorf("VerifyWithPolicy did not use CheckCtx")
+	}
+	if ok, _ := cos.VerifyBatch([][]byte{plain, urgent},
+		[][]byte{plainSig, urgentSig}); ok {
+		t.Errorf("batch accepted a flagged message with two signers")
+	}
+
+	// With all cosigners the flagged message is accepted.
+	cos.SetMask([]byte{0})
+	urgentSig = testCosign(t, urgent, priKeys[:n], cos)
+	if !cos.Verify(urgent, urgentSig) {
+		t.Errorf("flagged message rejected with all signers")
+	}
+
+	// A Policy without CheckCtx works as before.
+	cos.SetPolicy(ThresholdPolicy(2))
+	if !cos.Verify(plain, plainSig) {
+		t.Errorf("plain Policy rejected a sufficient signature")
+	}
+
+	// Combined policies pass the message on to their children.
+	cos.SetMask([]byte{0x05})
+	urgentSig = testCosign(t, urgent, priKeys[:n], cos)
+	for _, policy := range []Policy{
+		AndPolicy(ThresholdPolicy(1), flagPolicy{}),
+		OrPolicy(ThresholdPolicy(3), flagPolicy{}),
+	} {
+		if !cos.VerifyWithPolicy(plain, plainSig, policy) {
+			t.Errorf("%T did not pass the message to its children", policy)
+		}
+		if cos.VerifyWithPolicy(urgent, urgentSig, policy) {
+			t.Errorf("%T accepted a flagged message with two signers",
+				policy)
+		}
+	}
+}
+
+func TestQuorumPolicy(t *testing.T) {
+	policy := QuorumPolicy(2, 3)
+	for _, n := range []int{1, 2, 3, 4, 6, 9, 10} {
+		genKeys(n)
+		cos, err := NewCosignersErr(pubKeys[:n], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		need := (2*n + 2) / 3 // smallest count with count*3 >= n*2
+		for i := n - 1; i >= 0; i-- {
+			ok := policy.Check(cos)
+			if ok != (cos.CountEnabled() >= need) {
+				t.Errorf("n=%d, %d enabled: got %v, want %v", n,
+					cos.CountEnabled(), ok, !ok)
+			}
+			cos.SetMaskBit(i, Disabled)
+		}
+	}
+
+	cos, _ := NewCosignersErr(nil, nil)
+	if policy.Check(cos) || QuorumPolicy(0, 1).Check(cos) {
+		t.Errorf("quorum satisfied with no cosigners")
+	}
+
+	expectPanic(t, "QuorumPolicy(1, 0)", func() { QuorumPolicy(1, 0) })
+	expectPanic(t, "QuorumPolicy(1, -3)", func() { QuorumPolicy(1, -3) })
+	expectPanic(t, "QuorumPolicy(-1, 3)", func() { QuorumPolicy(-1, 3) })
+	expectPanic(t, "QuorumPolicy(4, 3)", func() { QuorumPolicy(4, 3) })
+	expectPanic(t, "QuorumPolicy(1<<62, 1)", func() { QuorumPolicy(1<<62, 1) })
+
+	// Huge fractions are compared exactly, without overflow.
+	genKeys(3)
+	cos, _ = NewCosignersErr(pubKeys[:3], nil)
+	huge := int(^uint(0) >> 1)
+	for i := 0; i < 3; i++ {
+		cos.SetMaskBit(i, Disabled)
+	}
+	if QuorumPolicy(huge-1, huge).Check(cos) {
+		t.Errorf("huge quorum satisfied by 0 of 3 cosigners")
+	}
+	cos.SetMaskBit(0, Enabled)
+	cos.SetMaskBit(1, Enabled)
+	if !QuorumPolicy(1<<61, 1<<62).Check(cos) ||
+		QuorumPolicy(huge-1, huge).Check(cos) {
+		t.Errorf("huge quorum fraction miscompared")
+	}
+}
+
+func TestPolicyNeeds(t *testing.T) {
+	n := 5
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetPolicy(ThresholdPolicy(3))
+
+	// With all 5 enabled, no single signer is pivotal.
+	for i := 0; i < n; i++ {
+		if cos.PolicyNeeds(i) {
+			t.Errorf("signer %d pivotal among 5 of 3", i)
+		}
+	}
+
+	// With exactly 3 enabled, each of them is pivotal.
+	cos.SetMask([]byte{0x12}) // disable 1 and 4
+	mask := cos.Mask()
+	for i := 0; i < n; i++ {
+		want := i != 1 && i != 4
+		if cos.PolicyNeeds(i) != want {
+			t.Errorf("signer %d: PolicyNeeds = %v, want %v", i, !want, want)
+		}
+	}
+	if !bytes.Equal(cos.Mask(), mask) || cos.CountEnabled() != 3 {
+		t.Errorf("PolicyNeeds changed the mask to %x", cos.Mask())
+	}
+
+	cos.SetPolicy(SubsetPolicy([]int{4}))
+	cos.SetMask(nil)
+	if !cos.PolicyNeeds(4) || cos.PolicyNeeds(0) {
+		t.Errorf("required signer not reported as needed")
+	}
+	if cos.PolicyNeeds(-1) || cos.PolicyNeeds(n) {
+		t.Errorf("out-of-range signer reported as needed")
+	}
+}
+
+func TestMinimalSatisfyingSet(t *testing.T) {
+	n := 6
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x02}) // 1 disabled
+	mask := cos.Mask()
+
+	tests := []struct {
+		policy Policy
+		set    []int
+		ok     bool
+	}{
+		{ThresholdPolicy(3), []int{0, 2, 3}, true},
+		{ThresholdPolicy(5), []int{0, 2, 3, 4, 5}, true},
+		{ThresholdPolicy(6), nil, false},
+		{ThresholdPolicy(0), []int{}, true},
+		{SubsetPolicy([]int{4, 5}), []int{4, 5}, true},
+		{SubsetPolicy([]int{1}), nil, false},
+		{AndPolicy(ThresholdPolicy(3), SubsetPolicy([]int{5})),
+			[]int{0, 2, 5}, true},
+		{nil, nil, false}, // full policy, but 1 is disabled
+	}
+	for i, test := range tests {
+		cos.SetPolicy(test.policy)
+		set, ok := cos.MinimalSatisfyingSet()
+		if ok != test.ok || !reflect.DeepEqual(set, test.set) {
+			t.Errorf("test %d: got %v, %v; want %v, %v",
+				i, set, ok, test.set, test.ok)
+		}
+		if !bytes.Equal(cos.Mask(), mask) {
+			t.Errorf("test %d: mask changed to %x", i, cos.Mask())
+		}
+	}
+}
+
+func TestSatisfyingMasks(t *testing.T) {
+	n := 7
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x12})
+	mask := cos.Mask()
+
+	// binomial[k] is n choose k.
+	binomial := []int{1, 7, 21, 35, 35, 21, 7, 1}
+	for threshold := 0; threshold <= n+1; threshold++ {
+		cos.SetPolicy(ThresholdPolicy(threshold))
+		masks, err := cos.SatisfyingMasks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 0
+		for k := threshold; k <= n; k++ {
+			want += binomial[k]
+		}
+		if len(masks) != want {
+			t.Errorf("threshold %d: got %d masks, want %d",
+				threshold, len(masks), want)
+		}
+		seen := make(map[string]bool)
+		for _, m := range masks {
+			if seen[string(m)] {
+				t.Errorf("threshold %d: mask %x repeated", threshold, m)
+			}
+			seen[string(m)] = true
+			cos.SetMask(m)
+			if cos.CountEnabled() < threshold || !bytes.Equal(cos.Mask(), m) {
+				t.Errorf("threshold %d: bad mask %x", threshold, m)
+			}
+		}
+		cos.SetMask(mask)
+	}
+
+	cos.SetPolicy(SubsetPolicy([]int{1, 6}))
+	masks, _ := cos.SatisfyingMasks()
+	if len(masks) != 32 || !bytes.Equal(masks[0], []byte{0xbd}) ||
+		!bytes.Equal(masks[31], []byte{0x80}) {
+		t.Errorf("subset policy: got %d masks from %x", len(masks), masks[0])
+	}
+	if !bytes.Equal(cos.Mask(), mask) {
+		t.Errorf("mask changed to %x", cos.Mask())
+	}
+
+	cos.SetRejectEmpty(true)
+	cos.SetPolicy(ThresholdPolicy(0))
+	if masks, _ := cos.SatisfyingMasks(); len(masks) != 1<<uint(n)-1 {
+		t.Errorf("SetRejectEmpty: got %d masks", len(masks))
+	}
+
+	// Masks enabling a revoked cosigner are never listed,
+	// and every listed mask survives SetMask unchanged.
+	cos.SetRejectEmpty(false)
+	cos.SetPolicy(ThresholdPolicy(5))
+	cos.DisablePermanently(2)
+	masks, _ = cos.SatisfyingMasks()
+	if len(masks) != 6+1 { // 6 choose 5 plus 6 choose 6
+		t.Errorf("revoked cosigner: got %d masks, want 7", len(masks))
+	}
+	for _, m := range masks {
+		if m[0]&0x04 == 0 {
+			t.Errorf("revoked cosigner: mask %x enables cosigner 2", m)
+		}
+		cos.SetMask(m)
+		if !bytes.Equal(cos.Mask(), m) || !cos.checkPolicy(cos.policy) {
+			t.Errorf("revoked cosigner: bad mask %x", m)
+		}
+	}
+
+	genKeys(21)
+	big, _ := NewCosignersErr(pubKeys[:21], nil)
+	if _, err := big.SatisfyingMasks(); err != ErrTooManySigners {
+		t.Errorf("21 cosigners: got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/sha512"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// PreparedSigner holds a cosigner's private key in expanded form,
+// so that a cosigner signing frequently with the same key
+// need not rehash its private key for every signature part
+// as Cosign and CosignErr do.
+// A PreparedSigner is as sensitive as the private key itself.
+type PreparedSigner struct {
+	scalar [32]byte // clamped secret scalar
+	prefix [32]byte // nonce-derivation prefix for CommitDeterministic
+}
+
+// NewPreparedSigner expands privateKey into a PreparedSigner.
+// It returns ErrPrivateKeyLength
+// if the key is not ed25519.PrivateKeySize bytes long.
+func NewPreparedSigner(privateKey ed25519.PrivateKey) (*PreparedSigner, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, ErrPrivateKeyLength
+	}
+	p := new(PreparedSigner)
+	p.scalar, p.prefix = expandPrivateKey(privateKey)
+	return p, nil
+}
+
+// Cosign produces this cosigner's part of a collective signature
+// exactly as CosignErr does with the original private key,
+// and returns the same errors.
+func (p *PreparedSigner) Cosign(secret *Secret, message []byte,
+	aggregateK ed25519.PublicKey, aggregateR Commitment) (SignaturePart, error) {
+
+	if len(aggregateR) != ed25519.PublicKeySize {
+		return nil, ErrCommitLength
+	}
+	if !secret.valid {
+		return nil, ErrSecretReused
+	}
+	if secret.deterministic {
+		digest := sha512.Sum512(message)
+		if err := secret.checkMessage(&digest); err != nil {
+			return nil, err
+		}
+	}
+
+	h := newHram(nil, nil, aggregateR, aggregateK)
+	h.Write(message)
+	c := reduceHram(h)
+	return cosignExpanded(&p.scalar, secret, &c)
+}
+
+// CommitDeterministic produces the same commitment and secret
+// as Cosigners.CommitDeterministic does with the original private key
+// for the cosigners whose GroupID is group,
+// subject to the same restrictions on the secret's use.
+func (p *PreparedSigner) CommitDeterministic(group [32]byte,
+	message []byte) (Commitment, *Secret) {
+
+	return commitDeterministic(&p.prefix, &group, message)
+}
@@ -0,0 +1,94 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPreparedSigner(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+
+	for i := 0; i < n; i++ {
+		p, err := NewPreparedSigner(priKeys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Identical commitments from the same message.
+		c1, s1 := cos.CommitDeterministic(priKeys[i], rightMessage)
+		c2, s2 := p.CommitDeterministic(cos.GroupID(), rightMessage)
+		if !bytes.Equal(c1, c2) {
+			t.Errorf("signer %d: deterministic commitments differ", i)
+		}
+
+		// Identical parts from identical secrets.
+		aggR := cos.AggregateCommit([]Commitment{c1, c1, c1})
+		part1, err1 := CosignErr(priKeys[i], s1, rightMessage, aggK, aggR)
+		part2, err2 := p.Cosign(s2, rightMessage, aggK, aggR)
+		if err1 != nil || err2 != nil {
+			t.Fatalf("signer %d: errors %v, %v", i, err1, err2)
+		}
+		if !bytes.Equal(part1, part2) {
+			t.Errorf("signer %d: prepared part %x, want %x", i, part2, part1)
+		}
+		if _, err := p.Cosign(s2, rightMessage, aggK, aggR); err != ErrSecretReused {
+			t.Errorf("signer %d: reused secret: got %v", i, err)
+		}
+	}
+
+	sig := cosignWith(t, cos, func(i int, secret *Secret, aggK, aggR []byte) SignaturePart {
+		p, _ := NewPreparedSigner(priKeys[i])
+		part, err := p.Cosign(secret, rightMessage, aggK, aggR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return part
+	})
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("prepared signature rejected")
+	}
+
+	if _, err := NewPreparedSigner(priKeys[0][:63]); err != ErrPrivateKeyLength {
+		t.Errorf("short private key: got %v", err)
+	}
+}
+
+// benchCosignPrepared measures producing one signature part
+// with the private key expanded on each call, or once in advance.
+func benchCosignPrepared(b *testing.B, prepared bool) {
+	genKeys(1)
+	cos, _ := NewCosignersErr(pubKeys[:1], nil)
+	aggK := cos.AggregatePublicKey()
+	commit, secret, _ := Commit(testRand)
+	aggR := cos.AggregateCommit([]Commitment{commit})
+	p, _ := NewPreparedSigner(priKeys[0])
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := *secret
+		var err error
+		if prepared {
+			_, err = p.Cosign(&s, rightMessage, aggK, aggR)
+		} else {
+			_, err = CosignErr(priKeys[0], &s, rightMessage, aggK, aggR)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCosignPrivateKey(b *testing.B) {
+	benchCosignPrepared(b, false)
+}
+
+func BenchmarkCosignPrepared(b *testing.B) {
+	benchCosignPrepared(b, true)
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// keyProofDomain prefixes the public key in a proof of possession,
+// so that a proof cannot double as a signature on any message
+// a cosigner might otherwise sign with the same key.
+const keyProofDomain = "CoSi Ed25519 proof of possession\x00"
+
+// MakeKeyProof produces a proof that the holder of privateKey
+// knows the private key for its public key,
+// in the form of an Ed25519 self-signature
+// on the public key with a fixed domain-separation prefix.
+// Each member of a cosigning group should publish such a proof
+// along with its public key,
+// and the others should check it with VerifyKeyProof,
+// to rule out the related-key attacks described in the package documentation.
+// MakeKeyProof panics if privateKey has the wrong length.
+func MakeKeyProof(privateKey ed25519.PrivateKey) []byte {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		panic(ErrPrivateKeyLength)
+	}
+	publicKey := ed25519.PublicKey(privateKey[32:])
+	return ed25519.Sign(privateKey, keyProofMessage(publicKey))
+}
+
+// VerifyKeyProof reports whether proof, produced by MakeKeyProof,
+// shows knowledge of the private key for publicKey.
+func VerifyKeyProof(publicKey ed25519.PublicKey, proof []byte) bool {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(publicKey, keyProofMessage(publicKey), proof)
+}
+
+// keyProofMessage returns the message self-signed in a proof of possession.
+func keyProofMessage(publicKey ed25519.PublicKey) []byte {
+	return append([]byte(keyProofDomain), publicKey...)
+}
+
+// NewCosignersWithProofs is like NewCosignersStrict,
+// but also requires proofs[i] to be a valid proof of possession,
+// as produced by MakeKeyProof, for publicKeys[i].
+// If a proof is missing or invalid,
+// it returns a *KeyError wrapping ErrKeyProof
+// identifying the first offending key.
+func NewCosignersWithProofs(publicKeys []ed25519.PublicKey, proofs [][]byte,
+	mask []byte) (*Cosigners, error) {
+
+	for i := range publicKeys {
+		if i >= len(proofs) || !VerifyKeyProof(publicKeys[i], proofs[i]) {
+			if len(publicKeys[i]) != ed25519.PublicKeySize {
+				return nil, &KeyError{i, ErrKeyLength}
+			}
+			return nil, &KeyError{i, ErrKeyProof}
+		}
+	}
+	return NewCosignersStrict(publicKeys, mask)
+}
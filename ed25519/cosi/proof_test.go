@@ -0,0 +1,73 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+func TestKeyProof(t *testing.T) {
+	n := 4
+	genKeys(n)
+	proofs := make([][]byte, n)
+	for i := range proofs {
+		proofs[i] = MakeKeyProof(priKeys[i])
+		if !VerifyKeyProof(pubKeys[i], proofs[i]) {
+			t.Errorf("valid proof %d rejected", i)
+		}
+	}
+	if VerifyKeyProof(pubKeys[1], proofs[0]) {
+		t.Errorf("proof for one key accepted for another")
+	}
+	if ed25519.Verify(pubKeys[0], pubKeys[0], proofs[0]) {
+		t.Errorf("proof is an undomained signature on the public key")
+	}
+	if VerifyKeyProof(pubKeys[0][:31], proofs[0]) {
+		t.Errorf("proof accepted for a short key")
+	}
+
+	cos, err := NewCosignersWithProofs(pubKeys[:n], proofs, nil)
+	if err != nil || cos.CountTotal() != n {
+		t.Fatalf("NewCosignersWithProofs: %v", err)
+	}
+
+	// A rogue key K' = K_attacker - K_victim would let the attacker
+	// alone produce signatures for the aggregate K_victim + K',
+	// but the attacker cannot prove possession of K'.
+	var att, victim edwards25519.ExtendedGroupElement
+	var attBytes, victimBytes, rogueBytes [32]byte
+	copy(attBytes[:], pubKeys[3])
+	copy(victimBytes[:], pubKeys[0])
+	att.FromBytes(&attBytes)
+	victim.FromBytes(&victimBytes)
+	att.Sub(&att, &victim)
+	att.ToBytes(&rogueBytes)
+	rogue := ed25519.PublicKey(rogueBytes[:])
+	for i, proof := range [][]byte{proofs[3], proofs[0], MakeKeyProof(priKeys[3])} {
+		if VerifyKeyProof(rogue, proof) {
+			t.Errorf("rogue key accepted with proof %d", i)
+		}
+	}
+	keys := []ed25519.PublicKey{pubKeys[0], rogue}
+	_, err = NewCosignersWithProofs(keys, [][]byte{proofs[0], proofs[3]}, nil)
+	if ke, ok := err.(*KeyError); !ok || ke.Index != 1 || ke.Err != ErrKeyProof {
+		t.Errorf("rogue key: got %v, want KeyError at 1", err)
+	}
+
+	_, err = NewCosignersWithProofs(pubKeys[:n], proofs[:n-1], nil)
+	if ke, ok := err.(*KeyError); !ok || ke.Index != n-1 || ke.Err != ErrKeyProof {
+		t.Errorf("missing proof: got %v, want KeyError at %d", err, n-1)
+	}
+	proofs[2], proofs[1] = proofs[1], proofs[2]
+	_, err = NewCosignersWithProofs(pubKeys[:n], proofs, nil)
+	if ke, ok := err.(*KeyError); !ok || ke.Index != 1 || ke.Err != ErrKeyProof {
+		t.Errorf("swapped proofs: got %v, want KeyError at 1", err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/sha512"
+	"io"
+	"sync"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// A ScalarProvider performs the steps of cosigning
+// that require a cosigner's private key or its one-time secrets,
+// so that keys held in a hardware security module or key management service
+// can cosign without exposing either.
+// A signature part s = c*x + r reveals the private scalar x
+// to anyone who also knows the one-time secret r,
+// so the provider generates every one-time secret itself,
+// and the host sees only commitments and signature parts.
+type ScalarProvider interface {
+	// Commit generates and retains a fresh one-time secret r,
+	// and returns only its commitment R = r*B.
+	Commit() (Commitment, error)
+
+	// MulAddScalar returns c*x + r modulo the group order,
+	// where x is the expanded Ed25519 private scalar,
+	// c is a 32-byte little-endian scalar reduced modulo the group order,
+	// and r is the unused one-time secret whose commitment Commit returned
+	// as commit.
+	// MulAddScalar must erase r, so that it is never used twice,
+	// and must fail if it holds no unused secret for commit.
+	MulAddScalar(commit Commitment, c *[32]byte) ([32]byte, error)
+}
+
+// maxProviderSecrets is the number of unused one-time secrets
+// a PrivateKeyProvider retains.
+const maxProviderSecrets = 1 << 16
+
+// PrivateKeyProvider is the ScalarProvider for an in-memory private key.
+// It is safe for concurrent use,
+// and retains the secret of each commitment it returns until it is used,
+// until Forget is called on the commitment,
+// or until maxProviderSecrets (65536) newer commitments have been made,
+// so that abandoned signing rounds cannot grow it without bound.
+// Once its secret is dropped, a commitment can no longer be used to sign,
+// and MulAddScalar returns ErrSecretReused.
+type PrivateKeyProvider struct {
+	scalar  [32]byte // clamped secret scalar
+	rand    io.Reader
+	mu      sync.Mutex
+	secrets map[[32]byte]*Secret
+	order   [][32]byte // ring of retained commitments, oldest at next
+	next    int
+}
+
+// NewPrivateKeyProvider returns a PrivateKeyProvider for privateKey,
+// which draws its one-time secrets from rand as Commit does,
+// or from a default source if rand is nil.
+// It returns ErrPrivateKeyLength
+// if the key is not ed25519.PrivateKeySize bytes long.
+func NewPrivateKeyProvider(privateKey ed25519.PrivateKey,
+	rand io.Reader) (*PrivateKeyProvider, error) {
+
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, ErrPrivateKeyLength
+	}
+	p := &PrivateKeyProvider{rand: rand}
+	p.scalar, _ = expandPrivateKey(privateKey)
+	return p, nil
+}
+
+// Commit implements ScalarProvider,
+// returning any error from the random source as Commit does.
+func (p *PrivateKeyProvider) Commit() (Commitment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	commit, secret, err := Commit(p.rand)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	copy(key[:], commit)
+	p.retain(key, secret)
+	return commit, nil
+}
+
+// retain records secret under the commitment key,
+// evicting the oldest commitment once maxProviderSecrets are recorded.
+// The caller must hold p.mu.
+func (p *PrivateKeyProvider) retain(key [32]byte, secret *Secret) {
+	if p.secrets == nil {
+		p.secrets = make(map[[32]byte]*Secret)
+	}
+	if len(p.order) < maxProviderSecrets {
+		p.order = append(p.order, key)
+	} else {
+		delete(p.secrets, p.order[p.next])
+		p.order[p.next] = key
+		p.next = (p.next + 1) % maxProviderSecrets
+	}
+	p.secrets[key] = secret
+}
+
+// Forget discards the one-time secret of commit,
+// for a signing round that was abandoned before its secret was used.
+// Forget does nothing if the provider holds no secret for commit.
+func (p *PrivateKeyProvider) Forget(commit Commitment) {
+	if len(commit) != ed25519.PublicKeySize {
+		return
+	}
+	var key [32]byte
+	copy(key[:], commit)
+	p.mu.Lock()
+	delete(p.secrets, key)
+	p.mu.Unlock()
+}
+
+// MulAddScalar implements ScalarProvider.
+// It returns ErrCommitLength if commit has the wrong length,
+// and ErrSecretReused if commit is not a commitment
+// whose secret this provider still holds.
+func (p *PrivateKeyProvider) MulAddScalar(commit Commitment,
+	c *[32]byte) ([32]byte, error) {
+
+	var s [32]byte
+	if len(commit) != ed25519.PublicKeySize {
+		return s, ErrCommitLength
+	}
+	var key [32]byte
+	copy(key[:], commit)
+	p.mu.Lock()
+	secret := p.secrets[key]
+	delete(p.secrets, key)
+	p.mu.Unlock()
+	if secret == nil {
+		return s, ErrSecretReused
+	}
+	part, err := cosignExpanded(&p.scalar, secret, c)
+	if err != nil {
+		return s, err
+	}
+	copy(s[:], part)
+	return s, nil
+}
+
+// expandPrivateKey hashes the seed half of an Ed25519 private key
+// into the clamped secret scalar and the nonce-derivation prefix,
+// as in RFC 8032.
+// The caller must check the private key's length.
+func expandPrivateKey(privateKey ed25519.PrivateKey) (scalar, prefix [32]byte) {
+	digest1 := sha512.Sum512(privateKey[:32])
+	copy(scalar[:], digest1[:32])
+	scalar[0] &= 248
+	scalar[31] &= 63
+	scalar[31] |= 64
+	copy(prefix[:], digest1[32:])
+	return
+}
+
+// CosignProvider is like CosignErr,
+// but delegates the use of the private key and the one-time secret
+// to provider.
+// The commit is the provider's own commitment,
+// previously returned by its Commit method
+// and included in the aggregate commit aggregateR.
+// The signature part is identical to the one CosignErr would produce
+// with the same private key and secret.
+//
+// CosignProvider returns ErrCommitLength if aggregateR has the wrong length,
+// and otherwise any error from the provider.
+func CosignProvider(provider ScalarProvider, commit Commitment, message []byte,
+	aggregateK ed25519.PublicKey, aggregateR Commitment) (SignaturePart, error) {
+
+	if len(aggregateR) != ed25519.PublicKeySize {
+		return nil, ErrCommitLength
+	}
+
+	h := newHram(nil, nil, aggregateR, aggregateK)
+	h.Write(message)
+	c := reduceHram(h)
+	s, err := provider.MulAddScalar(commit, &c)
+	if err != nil {
+		return nil, err
+	}
+	return s[:], nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+	"testing"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// mockHSM is a ScalarProvider that, like a hardware security module,
+// holds only the expanded private scalar and never the private key,
+// and keeps its one-time secrets to itself.
+type mockHSM struct {
+	x       *Scalar
+	secrets map[string]*Scalar
+	calls   int
+	err     error
+}
+
+func newMockHSM(privateKey ed25519.PrivateKey) *mockHSM {
+	h := sha512.Sum512(privateKey[:32])
+	h[0] &= 248
+	h[31] &= 63
+	h[31] |= 64
+	var wide [64]byte
+	copy(wide[:], h[:32])
+	return &mockHSM{x: ReduceScalar(&wide),
+		secrets: make(map[string]*Scalar)}
+}
+
+func (m *mockHSM) Commit() (Commitment, error) {
+	var wide [64]byte
+	constReader{byte(len(m.secrets) + 3)}.Read(wide[:])
+	r := ReduceScalar(&wide)
+	commit := Commitment(ScalarMulBase(r).Bytes())
+	m.secrets[string(commit)] = r
+	return commit, nil
+}
+
+func (m *mockHSM) MulAddScalar(commit Commitment, c *[32]byte) ([32]byte, error) {
+	var s [32]byte
+	m.calls++
+	if m.err != nil {
+		return s, m.err
+	}
+	r := m.secrets[string(commit)]
+	if r == nil {
+		return s, ErrSecretReused
+	}
+	delete(m.secrets, string(commit))
+	cs, _ := NewScalar(c[:])
+	copy(s[:], MulAddScalar(cs, m.x, r).Bytes())
+	return s, nil
+}
+
+func TestCosignProvider(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+	hsm := newMockHSM(priKeys[0])
+
+	// Cosigner 0 signs through the mock HSM, the rest in process.
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	var err error
+	if commits[0], err = hsm.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < n; i++ {
+		commits[i], secrets[i], _ = Commit(testRand)
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	parts[0], err = CosignProvider(hsm, commits[0], rightMessage, aggK, aggR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < n; i++ {
+		parts[i] = Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+	}
+	sig := cos.AggregateSignature(aggR, parts)
+	if hsm.calls != 1 || !cos.Verify(rightMessage, sig) {
+		t.Errorf("signature with an HSM-backed cosigner rejected")
+	}
+	if !cos.VerifyPart(rightMessage, aggR, 0, commits[0], parts[0]) {
+		t.Errorf("HSM-backed signature part rejected")
+	}
+
+	// The default provider's part matches the in-process one
+	// for the same random source.
+	c, secret, _ := Commit(constReader{3})
+	part := Cosign(priKeys[0], secret, rightMessage, aggK, c)
+	provider, err := NewPrivateKeyProvider(priKeys[0], constReader{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := provider.Commit()
+	if err != nil || !bytes.Equal(pc, c) {
+		t.Fatalf("provider commit %x, %v, want %x", pc, err, c)
+	}
+	p, err := CosignProvider(provider, pc, rightMessage, aggK, c)
+	if err != nil || !bytes.Equal(p, part) {
+		t.Errorf("provider part %x, %v, want %x", p, err, part)
+	}
+
+	// Each provider secret is used at most once,
+	// and only for a commitment the provider made.
+	for _, provider := range []ScalarProvider{hsm, provider} {
+		if _, err := CosignProvider(provider, pc, rightMessage, aggK,
+			c); err != ErrSecretReused {
+			t.Errorf("%T: reused or unknown commitment: got %v",
+				provider, err)
+		}
+	}
+
+	// Provider errors are returned.
+	hsm.err = errors.New("HSM unavailable")
+	hc, _ := hsm.Commit()
+	if _, err := CosignProvider(hsm, hc, rightMessage, aggK, c); err != hsm.err {
+		t.Errorf("failing provider: got %v", err)
+	}
+	if _, err := CosignProvider(provider, pc[:31], rightMessage, aggK,
+		c); err != ErrCommitLength {
+		t.Errorf("short commitment: got %v", err)
+	}
+	if _, err := NewPrivateKeyProvider(priKeys[0][:10], nil); err != ErrPrivateKeyLength {
+		t.Errorf("short private key: got %v", err)
+	}
+}
+
+func TestPrivateKeyProviderForget(t *testing.T) {
+	genKeys(1)
+	provider, err := NewPrivateKeyProvider(priKeys[0], testRand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _, _ := Commit(constReader{5})
+	commit, err := provider.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.Forget(commit)
+	provider.Forget(commit[:31])
+	if _, err := CosignProvider(provider, commit, rightMessage, pubKeys[0],
+		c); err != ErrSecretReused {
+		t.Errorf("forgotten commitment: got %v", err)
+	}
+
+	// Unused secrets are evicted oldest first once the bound is reached.
+	commit, _ = provider.Commit()
+	var key [32]byte
+	provider.mu.Lock()
+	for i := 0; i < maxProviderSecrets; i++ {
+		key[0], key[1], key[2] = byte(i), byte(i>>8), 0xff
+		provider.retain(key, &Secret{})
+	}
+	size := len(provider.secrets)
+	provider.mu.Unlock()
+	if size > maxProviderSecrets {
+		t.Errorf("provider retains %d secrets, want at most %d",
+			size, maxProviderSecrets)
+	}
+	if _, err := CosignProvider(provider, commit, rightMessage, pubKeys[0],
+		c); err != ErrSecretReused {
+		t.Errorf("evicted commitment: got %v", err)
+	}
+}
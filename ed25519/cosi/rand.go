@@ -0,0 +1,23 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !cosi_norand
+// +build !cosi_norand
+
+package cosi
+
+import (
+	cryptorand "crypto/rand"
+	"io"
+)
+
+// defaultRand returns the source of randomness used
+// when the caller supplies none.
+// Building with the cosi_norand tag removes this package's
+// dependency on crypto/rand,
+// for constrained targets whose callers always supply their own source.
+// The ed25519 package itself still imports crypto/rand for GenerateKey.
+func defaultRand() (io.Reader, error) {
+	return cryptorand.Reader, nil
+}
@@ -0,0 +1,24 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build cosi_norand
+// +build cosi_norand
+
+package cosi
+
+import (
+	"io"
+)
+
+// defaultRand reports that no default source of randomness
+// is available in builds with the cosi_norand tag.
+//
+// The tag removes only this package's own import of crypto/rand.
+// This package imports the ed25519 package,
+// which still imports crypto/rand for GenerateKey,
+// so crypto/rand remains linked into any program using this package
+// until ed25519 offers a similar tag.
+func defaultRand() (io.Reader, error) {
+	return nil, ErrNoRand
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build cosi_norand
+// +build cosi_norand
+
+package cosi
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+// The other tests pass testRand to Commit,
+// so with no default source they use crypto/rand explicitly.
+func init() {
+	testRand = rand.Reader
+}
+
+func TestNoRand(t *testing.T) {
+	if _, _, err := Commit(nil); err != ErrNoRand {
+		t.Errorf("Commit(nil): got %v, want ErrNoRand", err)
+	}
+	if _, _, err := CommitBatch(nil, 3); err != ErrNoRand {
+		t.Errorf("CommitBatch(nil): got %v, want ErrNoRand", err)
+	}
+
+	// An explicit source still works.
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	aggK := cos.AggregatePublicKey()
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	for i := range commits {
+		var err error
+		rand := bytes.NewReader(bytes.Repeat([]byte{byte(i + 1)}, 64))
+		commits[i], secrets[i], err = Commit(rand)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	aggR := cos.AggregateCommit(commits)
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		parts[i] = Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+	}
+	sig := cos.AggregateSignature(aggR, parts)
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("signature with explicit randomness rejected")
+	}
+
+	// VerifyBatch checks each signature individually.
+	ok, valid := cos.VerifyBatch([][]byte{rightMessage}, [][]byte{sig})
+	if !ok || valid != nil {
+		t.Errorf("VerifyBatch = %v, %v; want true, nil", ok, valid)
+	}
+	ok, valid = cos.VerifyBatch([][]byte{rightMessage, wrongMessage},
+		[][]byte{sig, sig})
+	if ok || len(valid) != 2 || !valid[0] || valid[1] {
+		t.Errorf("VerifyBatch = %v, %v; want false, [true false]",
+			ok, valid)
+	}
+
+	// VerifyParts checks each part individually without a random source.
+	partMap := make(map[int][]byte)
+	for i, part := range parts {
+		partMap[i] = part
+	}
+	if ok, bad := cos.VerifyParts(rightMessage, aggR, commits,
+		partMap); !ok || bad != nil {
+		t.Errorf("VerifyParts = %v, %v; want true, nil", ok, bad)
+	}
+	partMap[1] = addOrder(partMap[1])
+	if ok, bad := cos.VerifyParts(rightMessage, aggR, commits,
+		partMap); ok || len(bad) != 1 || bad[0] != 1 {
+		t.Errorf("VerifyParts = %v, %v; want false, [1]", ok, bad)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// policyRegistry maps policy names to the factories
+// registered for them with RegisterPolicy.
+var policyRegistry = struct {
+	sync.RWMutex
+	m map[string]func(args string) (Policy, error)
+}{m: map[string]func(args string) (Policy, error){
+	"all":       allFactory,
+	"threshold": thresholdFactory,
+	"quorum":    quorumFactory,
+}}
+
+// RegisterPolicy makes a Policy available to PolicyByName under name,
+// with factory creating the Policy from the arguments in a specification.
+// The factory should return an error wrapping ErrPolicySpec
+// if it cannot parse its arguments.
+// The names "all", "threshold", and "quorum" are built in.
+// RegisterPolicy is intended to be called from init functions,
+// and panics if name is empty, contains '-',
+// is already registered, or if factory is nil.
+func RegisterPolicy(name string, factory func(args string) (Policy, error)) {
+	if name == "" || strings.Contains(name, "-") || factory == nil {
+		panic("cosi: bad policy registration: " + strconv.Quote(name))
+	}
+	policyRegistry.Lock()
+	defer policyRegistry.Unlock()
+	if _, dup := policyRegistry.m[name]; dup {
+		panic("cosi: policy registered twice: " + strconv.Quote(name))
+	}
+	policyRegistry.m[name] = factory
+}
+
+// PolicyByName returns the Policy described by spec,
+// for services that select their policy in configuration.
+// A specification consists of a registered name,
+// optionally followed by '-' and arguments for the named policy:
+//
+//	all            every cosigner, as for SetPolicy(nil)
+//	threshold-T    ThresholdPolicy(T)
+//	quorum-N/D     QuorumPolicy(N, D)
+//	TofN           at least T signers in a group of exactly N cosigners
+//
+// such as "threshold-2" or "quorum-2/3".
+// Thresholds T and numerators N must be positive,
+// so that no specification yields a policy accepting every signature,
+// and a quorum's numerator may not exceed its denominator,
+// nor T exceed N in TofN.
+// Every count is at most math.MaxInt32.
+// The shorthand TofN, such as "2of3", is recognized
+// if no policy is registered under the whole specification.
+// It rejects every signature checked against a group
+// that does not have exactly N cosigners,
+// since the configuration was evidently meant for a different group.
+//
+// PolicyByName returns ErrUnknownPolicy if the name is not registered,
+// and the error from the policy's factory if the arguments are not valid,
+// which is ErrPolicySpec for the built-in policies
+// and for a TofN shorthand with counts out of range.
+func PolicyByName(spec string) (Policy, error) {
+	name, args := spec, ""
+	if i := strings.IndexByte(spec, '-'); i >= 0 {
+		name, args = spec[:i], spec[i+1:]
+	}
+	policyRegistry.RLock()
+	factory := policyRegistry.m[name]
+	policyRegistry.RUnlock()
+	if factory != nil {
+		return factory(args)
+	}
+	if t, n, ok := parseOf(spec); ok {
+		if t == 0 || t > n {
+			return nil, ErrPolicySpec
+		}
+		return ofPolicy{t, n}, nil
+	}
+	return nil, ErrUnknownPolicy
+}
+
+func allFactory(args string) (Policy, error) {
+	if args != "" {
+		return nil, ErrPolicySpec
+	}
+	return fullPolicy{}, nil
+}
+
+func thresholdFactory(args string) (Policy, error) {
+	t, ok := parseCount(args)
+	if !ok || t == 0 {
+		return nil, ErrPolicySpec
+	}
+	return ThresholdPolicy(t), nil
+}
+
+func quorumFactory(args string) (Policy, error) {
+	i := strings.IndexByte(args, '/')
+	if i < 0 {
+		return nil, ErrPolicySpec
+	}
+	num, ok1 := parseCount(args[:i])
+	den, ok2 := parseCount(args[i+1:])
+	if !ok1 || !ok2 || num == 0 || num > den {
+		return nil, ErrPolicySpec
+	}
+	return QuorumPolicy(num, den), nil
+}
+
+// parseOf parses the shorthand TofN.
+func parseOf(spec string) (t, n int, ok bool) {
+	i := strings.Index(spec, "of")
+	if i < 0 {
+		return 0, 0, false
+	}
+	t, ok1 := parseCount(spec[:i])
+	n, ok2 := parseCount(spec[i+2:])
+	if !ok1 || !ok2 {
+		return 0, 0, false
+	}
+	return t, n, true
+}
+
+// parseCount parses a non-negative decimal count
+// consisting of digits only and no greater than math.MaxInt32.
+func parseCount(s string) (int, bool) {
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s, 10, 31)
+	return int(n), err == nil
+}
+
+type ofPolicy struct{ t, n int }
+
+func (p ofPolicy) Check(cosigners *Cosigners) bool {
+	return cosigners.CountTotal() == p.n && cosigners.CountEnabled() >= p.t
+}
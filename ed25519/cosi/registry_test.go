@@ -0,0 +1,105 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"testing"
+)
+
+// evenPolicy accepts signatures by an even number of cosigners,
+// or by at least min of them if min is positive.
+type evenPolicy struct{ min int }
+
+func (p evenPolicy) Check(cosigners *Cosigners) bool {
+	return cosigners.CountEnabled()%2 == 0 ||
+		(p.min > 0 && cosigners.CountEnabled() >= p.min)
+}
+
+func TestPolicyByName(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetMaskBit(0, Disabled)
+
+	// Each built-in policy, with whether it accepts 2 of the 3 cosigners.
+	tests := []struct {
+		spec string
+		ok   bool
+	}{
+		{"all", false},
+		{"threshold-2", true},
+		{"threshold-3", false},
+		{"quorum-2/3", true},
+		{"quorum-3/4", false},
+		{"2of3", true},
+		{"3of3", false},
+		{"2of4", false}, // meant for a different group
+	}
+	for _, test := range tests {
+		policy, err := PolicyByName(test.spec)
+		if err != nil {
+			t.Errorf("%s: %v", test.spec, err)
+			continue
+		}
+		if policy.Check(cos) != test.ok {
+			t.Errorf("%s: Check = %v, want %v", test.spec, !test.ok, test.ok)
+		}
+	}
+
+	for _, spec := range []string{"", "bogus", "of3", "-2", "2of"} {
+		if _, err := PolicyByName(spec); err != ErrUnknownPolicy {
+			t.Errorf("%q: got error %v, want ErrUnknownPolicy", spec, err)
+		}
+	}
+	for _, spec := range []string{"all-1", "threshold", "threshold-x",
+		"threshold--1", "quorum-2", "quorum-2/0", "quorum-/3",
+		// specifications that would accept too much or overflow
+		"threshold-0", "quorum-0/3", "quorum-4/3",
+		"quorum-4611686018427387904/1", "threshold-2147483648",
+		"threshold-99999999999999999999", "3of2", "0of3", "0of0"} {
+		if _, err := PolicyByName(spec); err != ErrPolicySpec {
+			t.Errorf("%q: got error %v, want ErrPolicySpec", spec, err)
+		}
+	}
+
+	// A custom policy, registered only once even if the test is repeated.
+	if _, err := PolicyByName("even"); err == ErrUnknownPolicy {
+		RegisterPolicy("even", func(args string) (Policy, error) {
+			if args == "" {
+				return evenPolicy{}, nil
+			}
+			min, ok := parseCount(args)
+			if !ok {
+				return nil, ErrPolicySpec
+			}
+			return evenPolicy{min}, nil
+		})
+	}
+	even, err := PolicyByName("even")
+	if err != nil || !even.Check(cos) {
+		t.Errorf("custom policy: %v", err)
+	}
+	cos.SetMaskBit(0, Enabled)
+	if even.Check(cos) {
+		t.Errorf("custom policy accepted an odd number of signers")
+	}
+	if atLeast3, _ := PolicyByName("even-3"); !atLeast3.Check(cos) {
+		t.Errorf("custom policy ignored its arguments")
+	}
+	if _, err := PolicyByName("even-x"); err != ErrPolicySpec {
+		t.Errorf("custom policy with bad arguments: got %v", err)
+	}
+
+	for _, name := range []string{"threshold", "", "a-b"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %q did not panic", name)
+				}
+			}()
+			RegisterPolicy(name, thresholdFactory)
+		}()
+	}
+}
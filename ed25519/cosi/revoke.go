@@ -0,0 +1,49 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+// DisablePermanently revokes the cosigner at index i:
+// it disables the cosigner in the participation bitmask,
+// and keeps it disabled from then on,
+// whatever masks are later passed to SetMask or carried in signatures,
+// and despite any SetMaskBit call enabling it.
+// A signature in which a revoked cosigner participated
+// therefore no longer verifies,
+// since its aggregate public key includes the revoked cosigner's key.
+// The other cosigners keep their indices,
+// and masks keep their length.
+//
+// Revocation cannot be undone,
+// except by replacing the whole key list with UnmarshalBinary,
+// and is not recorded by MarshalBinary.
+// DisablePermanently returns ErrSignerRange if i is not a valid cosigner index.
+func (cos *Cosigners) DisablePermanently(i int) error {
+	if i < 0 || i >= len(cos.keys) {
+		return ErrSignerRange
+	}
+	cos.SetMaskBit(i, Disabled)
+	if cos.revoked == nil {
+		cos.revoked = make([]byte, len(cos.mask))
+	}
+	cos.revoked[i>>3] |= byte(1) << uint(i&7)
+	return nil
+}
+
+// Revoked reports whether the cosigner at index i
+// was revoked by DisablePermanently.
+func (cos *Cosigners) Revoked(i int) bool {
+	if i < 0 || i >= len(cos.keys) {
+		return false
+	}
+	return cos.revokedByte(i>>3)&(byte(1)<<uint(i&7)) != 0
+}
+
+// revokedByte returns byte byt of the revoked-cosigner bit-vector.
+func (cos *Cosigners) revokedByte(byt int) byte {
+	if cos.revoked == nil {
+		return 0
+	}
+	return cos.revoked[byt]
+}
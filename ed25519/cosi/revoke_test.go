@@ -0,0 +1,81 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDisablePermanently(t *testing.T) {
+	n := 10
+	genKeys(n + 1)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+	cos.SetPolicy(ThresholdPolicy(1))
+	full := testCosign(t, rightMessage, priKeys[:n], cos)
+	cos.SetMaskBit(3, Disabled)
+	without := testCosign(t, rightMessage, priKeys[:n], cos)
+	cos.SetMask(nil)
+
+	if err := cos.DisablePermanently(3); err != nil {
+		t.Fatal(err)
+	}
+	if !cos.Revoked(3) || cos.Revoked(2) || cos.MaskBit(3) != Disabled {
+		t.Fatalf("cosigner 3 not revoked")
+	}
+
+	// Neither SetMaskBit nor SetMask can re-enable it.
+	cos.SetMaskBit(3, Enabled)
+	cos.SetMask([]byte{0x00, 0x00})
+	if cos.MaskBit(3) != Disabled || cos.CountEnabled() != n-1 {
+		t.Errorf("revoked cosigner re-enabled: mask %x", cos.Mask())
+	}
+	if !bytes.Equal(cos.AggregatePublicKey(), func() []byte {
+		c, _ := NewCosignersErr(pubKeys[:n], []byte{0x08})
+		return c.AggregatePublicKey()
+	}()) {
+		t.Errorf("aggregate includes revoked cosigner")
+	}
+
+	// A crafted mask claiming its participation fails to verify,
+	// by every verification path,
+	// while signatures without it still verify.
+	if cos.Verify(rightMessage, full) || cos.VerifyConstantTime(rightMessage, full) {
+		t.Errorf("signature by revoked cosigner accepted")
+	}
+	if !cos.Verify(rightMessage, without) ||
+		!cos.VerifyConstantTime(rightMessage, without) {
+		t.Errorf("signature without revoked cosigner rejected")
+	}
+	cos.SetMaskCache(4)
+	for i := 0; i < 2; i++ {
+		if cos.Verify(rightMessage, full) {
+			t.Errorf("cached: signature by revoked cosigner accepted")
+		}
+		if !cos.Verify(rightMessage, without) {
+			t.Errorf("cached: signature without revoked cosigner rejected")
+		}
+	}
+
+	// Revocation survives Clone and AppendCosigner, but not reloading.
+	clone := cos.Clone()
+	clone.AppendCosigner(pubKeys[n])
+	clone.SetMask(nil)
+	if clone.MaskBit(3) != Disabled || clone.MaskBit(n) != Enabled {
+		t.Errorf("clone after append: mask %x", clone.Mask())
+	}
+	if cos.Revoked(n) || clone.Revoked(n) {
+		t.Errorf("appended cosigner revoked")
+	}
+	data, _ := cos.MarshalBinary()
+	cos.UnmarshalBinary(data)
+	if cos.Revoked(3) {
+		t.Errorf("revocation survived UnmarshalBinary")
+	}
+
+	if err := cos.DisablePermanently(n); err != ErrSignerRange {
+		t.Errorf("out-of-range revocation: got %v", err)
+	}
+}
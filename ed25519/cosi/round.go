@@ -0,0 +1,205 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"io"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// Round encapsulates a leader's state in one collective signing round,
+// enforcing the order of the steps described in the package documentation.
+// The leader first collects cosigners' commitments via SetCommit,
+// then calls Challenge to fix the participation mask
+// and obtain the aggregate public key and commit to send to the cosigners,
+// then collects their signature parts via SetPart,
+// and finally obtains the collective signature from Finalize.
+//
+// Cosigners that do not commit before Challenge
+// are disabled in the participation mask for the round.
+// If a cosigner that committed fails to return a valid signature part,
+// the round cannot complete:
+// Finalize returns ErrIncomplete,
+// and the leader must start a new Round with that cosigner disabled,
+// since the aggregate commit already includes its commitment.
+//
+// A Round uses the Cosigners object it was created with,
+// whose participation mask must not be changed
+// between Challenge and Finalize.
+type Round struct {
+	cos     *Cosigners
+	message []byte
+	commits []Commitment
+	aggK    []byte
+	aggR    []byte
+	mask    []byte // participation mask fixed by Challenge
+	acc     *Accumulator
+}
+
+// NewRound starts a collective signing round on message
+// among the cosigners currently enabled in cos.
+func NewRound(cos *Cosigners, message []byte) *Round {
+	return &Round{
+		cos:     cos,
+		message: append([]byte{}, message...),
+		commits: make([]Commitment, len(cos.keys)),
+	}
+}
+
+// SetCommit records the commitment from cosigner i.
+// It returns ErrSignerRange or ErrSignerDisabled
+// if i is out of range or disabled in the participation mask,
+// ErrDuplicateCommit if cosigner i already committed,
+// ErrRoundState if Challenge was already called,
+// or an error from ValidateCommitment if c is malformed.
+func (r *Round) SetCommit(i int, c Commitment) error {
+	if r.mask != nil {
+		return ErrRoundState
+	}
+	if i < 0 || i >= len(r.commits) {
+		return ErrSignerRange
+	}
+	if r.cos.MaskBit(i) == Disabled {
+		return ErrSignerDisabled
+	}
+	if r.commits[i] != nil {
+		return ErrDuplicateCommit
+	}
+	if err := ValidateCommitment(c); err != nil {
+		return err
+	}
+	r.commits[i] = append(Commitment{}, c...)
+	return nil
+}
+
+// Challenge ends the commitment phase of the round.
+// It disables every cosigner that has not committed,
+// fixes the resulting participation mask for the rest of the round,
+// and returns the aggregate public key and aggregate commit
+// that the leader sends to the committed cosigners for use in Cosign.
+// Calling Challenge again returns the same values.
+func (r *Round) Challenge() (aggK, aggR []byte) {
+	if r.mask == nil {
+		for i, c := range r.commits {
+			if c == nil {
+				r.cos.SetMaskBit(i, Disabled)
+			}
+		}
+		r.aggK = r.cos.AggregatePublicKey()
+		r.aggR = r.cos.AggregateCommit(r.commits)
+		r.mask = r.cos.Mask()
+		r.acc = r.cos.NewAccumulator()
+	}
+	return append([]byte{}, r.aggK...), append([]byte{}, r.aggR...)
+}
+
+// SetPart verifies and records the signature part from cosigner i.
+// It returns ErrRoundState if Challenge has not been called,
+// ErrMaskChanged if the participation mask has changed since,
+// ErrInvalidPart if the part does not verify,
+// or any error that Accumulator.AddPart returns,
+// notably ErrSignerDisabled if cosigner i did not commit.
+func (r *Round) SetPart(i int, p SignaturePart) error {
+	if err := r.checkMask(); err != nil {
+		return err
+	}
+	if i < 0 || i >= len(r.commits) {
+		return ErrSignerRange
+	}
+	if r.commits[i] == nil {
+		return ErrSignerDisabled
+	}
+	if len(p) != 32 {
+		return ErrPartLength
+	}
+	if !r.cos.VerifyPart(r.message, r.aggR, i, r.commits[i], p) {
+		return ErrInvalidPart
+	}
+	return r.acc.AddPart(i, p)
+}
+
+// Missing returns the number of committed cosigners
+// whose signature parts have not yet been recorded,
+// or -1 if Challenge has not been called.
+func (r *Round) Missing() int {
+	if r.acc == nil {
+		return -1
+	}
+	return r.acc.Missing()
+}
+
+// Finalize produces the collective signature once every committed cosigner's
+// signature part has been recorded.
+// It returns ErrRoundState if Challenge has not been called,
+// ErrMaskChanged if the participation mask has changed since,
+// or ErrIncomplete if any signature part is missing.
+func (r *Round) Finalize() ([]byte, error) {
+	if err := r.checkMask(); err != nil {
+		return nil, err
+	}
+	return r.acc.Finalize(r.aggR)
+}
+
+// checkMask checks that Challenge has fixed the participation mask
+// and that it has not changed since.
+func (r *Round) checkMask() error {
+	if r.mask == nil {
+		return ErrRoundState
+	}
+	if !bytes.Equal(r.cos.mask, r.mask) {
+		return ErrMaskChanged
+	}
+	return nil
+}
+
+// Cosigner encapsulates a cosigner's state across signing rounds,
+// holding its private key and the Secret for its pending commitment,
+// so that it cannot cosign without first committing
+// or use the same Secret twice.
+type Cosigner struct {
+	privateKey ed25519.PrivateKey
+	secret     *Secret
+}
+
+// NewCosigner creates a Cosigner using privateKey.
+// It returns ErrPrivateKeyLength if privateKey has the wrong length.
+func NewCosigner(privateKey ed25519.PrivateKey) (*Cosigner, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, ErrPrivateKeyLength
+	}
+	return &Cosigner{privateKey: append(ed25519.PrivateKey{}, privateKey...)}, nil
+}
+
+// Commit produces a fresh commitment for a new signing round,
+// using randomness from rand, or a default source if rand is nil,
+// as the package-level Commit does.
+// Any previous commitment not yet used by Cosign is discarded.
+func (c *Cosigner) Commit(rand io.Reader) (Commitment, error) {
+	commit, secret, err := Commit(rand)
+	if err != nil {
+		return nil, err
+	}
+	c.secret = secret
+	return commit, nil
+}
+
+// Cosign produces this cosigner's signature part for message
+// using its pending commitment, which it then discards.
+// Unlike the package-level Cosign, it returns an error instead of panicking:
+// ErrNoCommit if there is no pending commitment,
+// because Commit was not called or its commitment was already used,
+// or ErrCommitLength if aggregateR has the wrong length,
+// in which case the commitment remains pending.
+func (c *Cosigner) Cosign(message []byte, aggregateK ed25519.PublicKey,
+	aggregateR Commitment) (SignaturePart, error) {
+
+	if c.secret == nil {
+		return nil, ErrNoCommit
+	}
+	return CosignOnce(c.privateKey, &c.secret, message, aggregateK, aggregateR)
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	n := 6
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetPolicy(ThresholdPolicy(n - 1))
+
+	round := NewRound(cos, rightMessage)
+	if err := round.SetPart(0, make([]byte, 32)); err != ErrRoundState {
+		t.Errorf("SetPart before Challenge: got %v", err)
+	}
+	if _, err := round.Finalize(); err != ErrRoundState {
+		t.Errorf("Finalize before Challenge: got %v", err)
+	}
+
+	// Cosigner 2 is offline and never commits.
+	secrets := make([]*Secret, n)
+	for i := range secrets {
+		if i == 2 {
+			continue
+		}
+		var c Commitment
+		c, secrets[i], _ = Commit(testRand)
+		if err := round.SetCommit(i, c); err != nil {
+			t.Fatal(err)
+		}
+		if err := round.SetCommit(i, c); err != ErrDuplicateCommit {
+			t.Errorf("duplicate commit: got %v", err)
+		}
+	}
+	if err := round.SetCommit(n, invalidPoint); err != ErrSignerRange {
+		t.Errorf("commit out of range: got %v", err)
+	}
+	if err := round.SetCommit(2, invalidPoint); err != ErrInvalidCommit {
+		t.Errorf("invalid commit: got %v", err)
+	}
+
+	aggK, aggR := round.Challenge()
+	if cos.MaskBit(2) != Disabled || cos.CountEnabled() != n-1 {
+		t.Errorf("Challenge did not disable the non-committed cosigner")
+	}
+	if err := round.SetCommit(2, aggR); err != ErrRoundState {
+		t.Errorf("SetCommit after Challenge: got %v", err)
+	}
+	if round.Missing() != n-1 {
+		t.Errorf("Missing() = %d, want %d", round.Missing(), n-1)
+	}
+
+	parts := make([]SignaturePart, n)
+	for i := range parts {
+		if secrets[i] != nil {
+			parts[i] = Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		}
+	}
+	if err := round.SetPart(2, parts[0]); err != ErrSignerDisabled {
+		t.Errorf("part from non-committed cosigner: got %v", err)
+	}
+	if err := round.SetPart(0, parts[1]); err != ErrInvalidPart {
+		t.Errorf("wrong cosigner's part: got %v", err)
+	}
+
+	// The mask may not change while parts are being collected.
+	cos.SetMaskBit(4, Disabled)
+	if err := round.SetPart(0, parts[0]); err != ErrMaskChanged {
+		t.Errorf("SetPart after mask change: got %v", err)
+	}
+	cos.SetMaskBit(4, Enabled)
+
+	for i, part := range parts {
+		if part == nil {
+			continue
+		}
+		if err := round.SetPart(i, part); err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+	}
+	sig, err := round.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("signature from round rejected")
+	}
+}
+
+func TestRoundDropout(t *testing.T) {
+	n := 4
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+
+	round := NewRound(cos, rightMessage)
+	secrets := make([]*Secret, n)
+	for i := range secrets {
+		var c Commitment
+		c, secrets[i], _ = Commit(testRand)
+		round.SetCommit(i, c)
+	}
+	aggK, aggR := round.Challenge()
+
+	// Cosigner 3 committed but drops out before sending its part.
+	for i := 0; i < n-1; i++ {
+		part := Cosign(priKeys[i], secrets[i], rightMessage, aggK, aggR)
+		if err := round.SetPart(i, part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if round.Missing() != 1 {
+		t.Errorf("Missing() = %d, want 1", round.Missing())
+	}
+	if _, err := round.Finalize(); err != ErrIncomplete {
+		t.Errorf("Finalize with dropout: got %v, want ErrIncomplete", err)
+	}
+}
+
+func TestCosigner(t *testing.T) {
+	n := 3
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], nil)
+
+	if _, err := NewCosigner(priKeys[0][:32]); err != ErrPrivateKeyLength {
+		t.Errorf("short private key: got %v", err)
+	}
+	cosigners := make([]*Cosigner, n)
+	for i := range cosigners {
+		cosigners[i], _ = NewCosigner(priKeys[i])
+	}
+	aggK := cos.AggregatePublicKey()
+
+	if _, err := cosigners[0].Cosign(rightMessage, aggK, nil); err != ErrNoCommit {
+		t.Errorf("cosign without commit: got %v, want ErrNoCommit", err)
+	}
+
+	round := NewRound(cos, rightMessage)
+	for i, c := range cosigners {
+		commit, err := c.Commit(testRand)
+		if err != nil {
+			t.Fatal(err)
+		}
+		round.SetCommit(i, commit)
+	}
+	aggK, aggR := round.Challenge()
+	if _, err := cosigners[0].Cosign(rightMessage, aggK, aggR[:31]); err != ErrCommitLength {
+		t.Errorf("short aggregate commit: got %v", err)
+	}
+	for i, c := range cosigners {
+		part, err := c.Cosign(rightMessage, aggK, aggR)
+		if err != nil {
+			t.Fatalf("cosigner %d: %v", i, err)
+		}
+		if err := round.SetPart(i, part); err != nil {
+			t.Fatalf("cosigner %d: %v", i, err)
+		}
+	}
+	if _, err := cosigners[0].Cosign(rightMessage, aggK, aggR); err != ErrNoCommit {
+		t.Errorf("cosign twice: got %v, want ErrNoCommit", err)
+	}
+	sig, err := round.Finalize()
+	if err != nil || !cos.Verify(rightMessage, sig) {
+		t.Errorf("signature from Cosigner parts rejected: %v", err)
+	}
+}
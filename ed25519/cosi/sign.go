@@ -5,10 +5,10 @@
 package cosi
 
 import (
-	cryptorand "crypto/rand"
+	"bytes"
 	"crypto/sha512"
+	"hash"
 	"io"
-	"strconv"
 
 	//"golang.org/x/crypto/ed25519"
 	//"golang.org/x/crypto/ed25519/internal/edwards25519"
@@ -31,6 +31,11 @@ type SignaturePart []byte
 type Secret struct {
 	reduced [32]byte
 	valid   bool
+
+	// Set only for secrets produced by CommitDeterministic
+	deterministic bool
+	commit        [32]byte // the corresponding commitment R
+	message       [64]byte // SHA-512 digest of the message to be signed
 }
 
 // Commit is invoked by cosigners to produce a one-time commit
@@ -42,20 +47,105 @@ type Secret struct {
 // to be sent to the leader for aggregation via AggregateCommit,
 // and a Secret object representing a cryptographic secret
 // to be used later in the corresponding call to Cosign.
-// Commit fails and returns an error only if rand yields an error.
+// Commit fails and returns an error only if rand yields an error,
+// or if rand is nil in a build with the cosi_norand tag,
+// which has no default source, in which case the error is ErrNoRand.
 func Commit(rand io.Reader) (Commitment, *Secret, error) {
 
 	var secretFull [64]byte
 	if rand == nil {
-		rand = cryptorand.Reader
+		var err error
+		if rand, err = defaultRand(); err != nil {
+			return nil, nil, err
+		}
 	}
 	_, err := io.ReadFull(rand, secretFull[:])
 	if err != nil {
 		return nil, nil, err
 	}
 
+	commit, secret := commitSecret(&secretFull)
+	return commit, secret, nil
+}
+
+// SetRand sets the source of randomness that the Cosigners object uses
+// in Commit and in the random linear combinations
+// of VerifyBatch and VerifyParts.
+// Passing nil restores the default source.
+// The WithRand option sets the source when the object is created.
+// A deterministic source makes higher-level signing flows reproducible
+// in tests, but must never be used to produce real signatures,
+// since reusing a commitment's randomness reveals the private key.
+func (cos *Cosigners) SetRand(rand io.Reader) {
+	cos.rand = rand
+}
+
+// randReader returns the configured source of randomness,
+// or the default source.
+func (cos *Cosigners) randReader() (io.Reader, error) {
+	if cos.rand != nil {
+		return cos.rand, nil
+	}
+	return defaultRand()
+}
+
+// Commit is like the package-level Commit,
+// but draws randomness from the source set by SetRand.
+func (cos *Cosigners) Commit() (Commitment, *Secret, error) {
+	rand, err := cos.randReader()
+	if err != nil {
+		return nil, nil, err
+	}
+	return Commit(rand)
+}
+
+// CommitBatch is like Commit but produces n commitments at once,
+// for a cosigner that expects to sign many messages.
+// It draws the randomness for all n secrets in a single read from rand,
+// amortizing the cost of a syscall-backed random source.
+// The secrets are independent, and each may be used
+// in only one call to Cosign, exactly as with Commit.
+func CommitBatch(rand io.Reader, n int) ([]Commitment, []*Secret, error) {
+
+	if n <= 0 {
+		return nil, nil, nil
+	}
+	if rand == nil {
+		var err error
+		if rand, err = defaultRand(); err != nil {
+			return nil, nil, err
+		}
+	}
+	buf := make([]byte, 64*n)
+	_, err := io.ReadFull(rand, buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commits := make([]Commitment, n)
+	secrets := make([]*Secret, n)
+	var secretFull [64]byte
+	for i := range commits {
+		copy(secretFull[:], buf[64*i:])
+		commits[i], secrets[i] = commitSecret(&secretFull)
+	}
+
+	// Don't leave the raw secrets lying around in memory.
+	for i := range buf {
+		buf[i] = 0
+	}
+	for i := range secretFull {
+		secretFull[i] = 0
+	}
+	return commits, secrets, nil
+}
+
+// commitSecret derives a one-time secret from 64 bytes of randomness
+// and returns it together with its commitment.
+func commitSecret(secretFull *[64]byte) (Commitment, *Secret) {
+
 	var secret Secret
-	edwards25519.ScReduce(&secret.reduced, &secretFull)
+	edwards25519.ScReduce(&secret.reduced, secretFull)
 	secret.valid = true
 
 	// compute R, the individual Schnorr commit to our one-time secret
@@ -64,7 +154,7 @@ func Commit(rand io.Reader) (Commitment, *Secret, error) {
 
 	var encodedR [32]byte
 	R.ToBytes(&encodedR)
-	return encodedR[:], &secret, nil
+	return encodedR[:], &secret
 }
 
 // Cosign signs the message with privateKey and returns a partial signature. It will
@@ -81,43 +171,216 @@ func Commit(rand io.Reader) (Commitment, *Secret, error) {
 // Since it is security-critical that a particular Secret be used only once,
 // Cosign invalidates the secret when it is called,
 // and panics if called with a previously-used secret.
+// Cosign also panics if privateKey or aggregateR has the wrong length;
+// CosignErr returns an error in all these cases instead.
 func Cosign(privateKey ed25519.PrivateKey, secret *Secret, message []byte,
 	aggregateK ed25519.PublicKey, aggregateR Commitment) SignaturePart {
 
-	if l := len(privateKey); l != ed25519.PrivateKeySize {
-		panic("ed25519: bad private key length: " + strconv.Itoa(l))
+	part, err := CosignErr(privateKey, secret, message, aggregateK, aggregateR)
+	if err != nil {
+		panic(err)
+	}
+	return part
+}
+
+// CosignErr is like Cosign, but returns an error instead of panicking:
+// ErrPrivateKeyLength or ErrCommitLength
+// if privateKey or aggregateR has the wrong length,
+// ErrSecretReused if the secret was already used,
+// or ErrSecretMessage if a deterministic secret
+// is used on a message other than the one it was derived from.
+func CosignErr(privateKey ed25519.PrivateKey, secret *Secret, message []byte,
+	aggregateK ed25519.PublicKey, aggregateR Commitment) (SignaturePart, error) {
+
+	return cosignDom(nil, privateKey, secret, nil, message,
+		aggregateK, aggregateR)
+}
+
+// CosignOnce is like CosignErr,
+// but takes the address of the caller's *Secret variable
+// and sets that variable to nil once the secret has been used,
+// so that the caller cannot accidentally hold on to a spent secret
+// and pass it to another signing round.
+// CosignOnce returns ErrSecretReused if *secret is nil or already used.
+// If it fails for any other reason, *secret is left unchanged.
+func CosignOnce(privateKey ed25519.PrivateKey, secret **Secret, message []byte,
+	aggregateK ed25519.PublicKey, aggregateR Commitment) (SignaturePart, error) {
+
+	if *secret == nil {
+		return nil, ErrSecretReused
 	}
-	if l := len(aggregateR); l != ed25519.PublicKeySize {
-		panic("ed25519: bad aggregateR length: " + strconv.Itoa(l))
+	part, err := CosignErr(privateKey, *secret, message, aggregateK, aggregateR)
+	if err == nil || (*secret).Used() {
+		*secret = nil
 	}
-	if !secret.valid {
-		panic("ed25519: you must use a cosigning Secret only once")
+	return part, err
+}
+
+// Used reports whether the secret has already been used
+// to produce a signature part, and hence may not be used again.
+// A Secret not obtained from Commit or a related function
+// also reports itself as used.
+func (secret *Secret) Used() bool {
+	return !secret.valid
+}
+
+// CosignHash is like CosignErr,
+// but computes the Schnorr challenge using newHash instead of SHA-512,
+// for use with a Cosigners object configured by SetHash
+// with the same hash function.
+// It returns ErrHashSize if newHash does not produce 64-byte digests.
+func CosignHash(newHash func() hash.Hash, privateKey ed25519.PrivateKey,
+	secret *Secret, message []byte, aggregateK ed25519.PublicKey,
+	aggregateR Commitment) (SignaturePart, error) {
+
+	if newHash != nil && newHash().Size() != sha512.Size {
+		return nil, ErrHashSize
 	}
+	return cosignDom(newHash, privateKey, secret, nil, message,
+		aggregateK, aggregateR)
+}
 
-	h := sha512.New()
-	h.Write(privateKey[:32])
+// cosignDom is CosignErr with an optional domain-separation prefix dom,
+// computing the challenge with newHash, or SHA-512 if newHash is nil.
+func cosignDom(newHash func() hash.Hash, privateKey ed25519.PrivateKey,
+	secret *Secret, dom, message []byte,
+	aggregateK ed25519.PublicKey, aggregateR Commitment) (SignaturePart, error) {
 
-	var digest1 [64]byte
-	var expandedSecretKey [32]byte
-	h.Sum(digest1[:0])
-	copy(expandedSecretKey[:], digest1[:])
-	expandedSecretKey[0] &= 248
-	expandedSecretKey[31] &= 63
-	expandedSecretKey[31] |= 64
+	if err := checkCosign(privateKey, secret, aggregateR); err != nil {
+		return nil, err
+	}
+	if secret.deterministic {
+		digest := sha512.Sum512(message)
+		if err := secret.checkMessage(&digest); err != nil {
+			return nil, err
+		}
+	}
 
-	var hramDigest [64]byte
-	h.Reset()
-	h.Write(aggregateR)
-	h.Write(aggregateK)
+	h := newHram(newHash, dom, aggregateR, aggregateK)
 	h.Write(message)
-	h.Sum(hramDigest[:0])
+	return cosign(privateKey, secret, h)
+}
+
+// CosignStream is like CosignErr,
+// but reads the message to be signed from r,
+// feeding it incrementally into the hash
+// rather than requiring the whole message to be held in memory.
+// The resulting signature part is identical to the one Cosign would produce
+// on the same message.
+//
+// Besides the errors CosignErr returns,
+// CosignStream returns any error encountered reading from r,
+// in which case the secret remains unused and valid.
+func CosignStream(privateKey ed25519.PrivateKey, secret *Secret, r io.Reader,
+	aggregateK ed25519.PublicKey, aggregateR Commitment) (SignaturePart, error) {
+
+	if err := checkCosign(privateKey, secret, aggregateR); err != nil {
+		return nil, err
+	}
+
+	h := newHram(nil, nil, aggregateR, aggregateK)
+	if !secret.deterministic {
+		if _, err := io.Copy(h, r); err != nil {
+			return nil, err
+		}
+		return cosign(privateKey, secret, h)
+	}
+
+	m := sha512.New()
+	if _, err := io.Copy(io.MultiWriter(h, m), r); err != nil {
+		return nil, err
+	}
+	var digest [64]byte
+	m.Sum(digest[:0])
+	if err := secret.checkMessage(&digest); err != nil {
+		return nil, err
+	}
+	return cosign(privateKey, secret, h)
+}
+
+// CosignMulti is like CosignErr,
+// but takes the message to be signed as a list of fragments,
+// which it feeds into the hash in order,
+// without concatenating them.
+// The resulting signature part is identical to the one Cosign would produce
+// on the concatenation of the fragments.
+func CosignMulti(privateKey ed25519.PrivateKey, secret *Secret, parts [][]byte,
+	aggregateK ed25519.PublicKey, aggregateR Commitment) (SignaturePart, error) {
+
+	if err := checkCosign(privateKey, secret, aggregateR); err != nil {
+		return nil, err
+	}
+
+	h := newHram(nil, nil, aggregateR, aggregateK)
+	for _, part := range parts {
+		h.Write(part)
+	}
+	if secret.deterministic {
+		m := sha512.New()
+		for _, part := range parts {
+			m.Write(part)
+		}
+		var digest [64]byte
+		m.Sum(digest[:0])
+		if err := secret.checkMessage(&digest); err != nil {
+			return nil, err
+		}
+	}
+	return cosign(privateKey, secret, h)
+}
+
+func checkCosign(privateKey ed25519.PrivateKey, secret *Secret,
+	aggregateR Commitment) error {
+
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return ErrPrivateKeyLength
+	}
+	if len(aggregateR) != ed25519.PublicKeySize {
+		return ErrCommitLength
+	}
+	if !secret.valid {
+		return ErrSecretReused
+	}
+	return nil
+}
+
+// cosign produces a signature part given a hash
+// into which the aggregate commit, aggregate public key,
+// and message have already been written, in that order.
+func cosign(privateKey ed25519.PrivateKey, secret *Secret,
+	hram hash.Hash) (SignaturePart, error) {
+
+	var hramDigest [64]byte
+	hram.Sum(hramDigest[:0])
 
 	var hramDigestReduced [32]byte
 	edwards25519.ScReduce(&hramDigestReduced, &hramDigest)
+	return cosignScalar(privateKey, secret, &hramDigestReduced)
+}
+
+// cosignScalar produces a signature part for an already-reduced challenge,
+// consuming the one-time secret.
+// It returns ErrSecretReused, leaving the secret unused,
+// if the secret is deterministic and was used with another challenge.
+func cosignScalar(privateKey ed25519.PrivateKey, secret *Secret,
+	hramDigestReduced *[32]byte) (SignaturePart, error) {
+
+	// The caller has already checked the private key's length.
+	expandedSecretKey, _ := expandPrivateKey(privateKey)
+	return cosignExpanded(&expandedSecretKey, secret, hramDigestReduced)
+}
 
-	// Produce our individual contribution to the collective signature
+// cosignExpanded is cosignScalar given the expanded private scalar.
+func cosignExpanded(expandedSecretKey *[32]byte, secret *Secret,
+	hramDigestReduced *[32]byte) (SignaturePart, error) {
+
+	if err := secret.recordChallenge(hramDigestReduced); err != nil {
+		return nil, err
+	}
+
+	// Produce our individual contribution to the collective signature.
 	var s [32]byte
-	edwards25519.ScMulAdd(&s, &hramDigestReduced, &expandedSecretKey,
+	edwards25519.ScMulAdd(&s, hramDigestReduced, expandedSecretKey,
 		&secret.reduced)
 
 	// Erase the one-time secret and make darn sure it gets used only once,
@@ -125,7 +388,7 @@ func Cosign(privateKey ed25519.PrivateKey, secret *Secret, message []byte,
 	secret.reduced = [32]byte{}
 	secret.valid = false
 
-	return s[:] // individual partial signature
+	return s[:], nil // individual partial signature
 }
 
 // AggregatePublicKey computes and returns an aggregate public key
@@ -146,30 +409,189 @@ func (cos *Cosigners) AggregatePublicKey() ed25519.PublicKey {
 // The commits slice must have length equal to the total number of cosigners,
 // but AggregateCommit uses only the entries corresponding to cosigners
 // that are enabled in the participation mask.
+//
+// The result depends only on which commit is stored at which index,
+// never on the order in which the leader filled in the slice,
+// so commits arriving concurrently may be stored as they come.
+//
+// AggregateCommit returns nil if any enabled cosigner's commit is malformed;
+// use AggregateCommitErr to find out which one.
 func (cos *Cosigners) AggregateCommit(commits []Commitment) []byte {
+	aggR, err := cos.AggregateCommitErr(commits)
+	if err != nil {
+		return nil
+	}
+	return aggR
+}
 
-	var aggR, indivR edwards25519.ExtendedGroupElement
-	var commitBytes [32]byte
+// AggregateCommitErr combines cosigners' individual commits
+// into an aggregate commit exactly as AggregateCommit does,
+// but if any enabled cosigner's commit is malformed,
+// returns a *CommitError identifying the first such cosigner
+// and the reason its commit was rejected.
+// A commit missing because commits is too short
+// is reported as a CommitError wrapping ErrCommitLength.
+// The leader can then exclude that cosigner and restart the signing round.
+func (cos *Cosigners) AggregateCommitErr(commits []Commitment) ([]byte, error) {
+
+	aggR, err := cos.AggregateCommitPoint(commits)
+	if err != nil {
+		return nil, err
+	}
+	return aggR.Bytes(), nil
+}
 
+// AggregateCommitFunc combines cosigners' individual commits
+// exactly as AggregateCommitErr does,
+// but obtains each enabled cosigner's commit on demand
+// by calling get with its index,
+// so that a leader of a very large group can stream the commits
+// from disk or the network rather than holding them all in memory.
+// AggregateCommitFunc calls get once for each enabled cosigner,
+// in increasing order of index, from the calling goroutine only.
+// If get returns false, or a malformed commit,
+// AggregateCommitFunc stops and returns a *CommitError for that cosigner,
+// wrapping ErrCommitLength for a missing commit.
+func (cos *Cosigners) AggregateCommitFunc(get func(i int) (Commitment, bool)) ([]byte, error) {
+	var aggR, indivR edwards25519.ExtendedGroupElement
 	aggR.Zero()
 	for i := range cos.keys {
 		if cos.MaskBit(i) == Disabled {
 			continue
 		}
-
-		if l := len(commits[i]); l != ed25519.PublicKeySize {
-			return nil
+		commit, ok := get(i)
+		if !ok {
+			return nil, &CommitError{Index: i, Err: ErrCommitLength}
 		}
-		copy(commitBytes[:], commits[i])
-		if !indivR.FromBytes(&commitBytes) {
-			return nil
+		if err := decodeCommitmentReason(&indivR, commit); err != nil {
+			return nil, newCommitError(i, err)
 		}
 		aggR.Add(&aggR, &indivR)
 	}
 
 	var aggRBytes [32]byte
 	aggR.ToBytes(&aggRBytes)
-	return aggRBytes[:]
+	return aggRBytes[:], nil
+}
+
+// CheckAggregateCommit reports whether aggregateR is the correct
+// aggregate of the enabled cosigners' individual commits
+// under the current participation mask.
+// A cosigner that has seen all the individual commits
+// can use it to catch a buggy or malicious leader
+// before spending a one-time secret on a bad signing round.
+// CheckAggregateCommit returns false if any enabled cosigner's commit
+// is malformed, or if len(commits) is not the number of cosigners.
+func (cos *Cosigners) CheckAggregateCommit(commits []Commitment, aggregateR []byte) bool {
+	if len(commits) != len(cos.keys) {
+		return false
+	}
+	aggR, err := cos.AggregateCommitErr(commits)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aggR, aggregateR)
+}
+
+// VerifyAggregateContext is run by a cosigner before Cosign
+// to confirm that the aggregate commit and aggregate public key
+// the leader sent it were both built over the participation mask
+// the leader claims, which the cosigner must first install in cos,
+// and over the individual commits the leader showed it.
+// Otherwise a leader could, for example, compute the challenge
+// over a different set of participants than it announced.
+// VerifyAggregateContext returns false if aggregateK is not
+// the aggregate public key of the cosigners enabled in cos,
+// or if CheckAggregateCommit fails.
+func VerifyAggregateContext(cos *Cosigners, commits []Commitment,
+	aggregateR, aggregateK []byte) bool {
+
+	return bytes.Equal(cos.AggregatePublicKey(), aggregateK) &&
+		cos.CheckAggregateCommit(commits, aggregateR)
+}
+
+// ValidateCommitment checks that c is a well-formed commitment,
+// as Commit produces,
+// allowing a leader to reject a malformed commitment when it arrives
+// rather than later in AggregateCommitErr.
+// It returns ErrCommitLength if c is not 32 bytes long,
+// and ErrInvalidCommit if c is not the canonical encoding
+// of a point on the curve, or is the identity point,
+// which a malicious cosigner could submit
+// to avoid contributing any randomness to the aggregate commit.
+func ValidateCommitment(c Commitment) error {
+	var R edwards25519.ExtendedGroupElement
+	return decodeCommitment(&R, c)
+}
+
+// decodeCommitment validates commitment c as ValidateCommitment does,
+// and decodes it into R.
+func decodeCommitment(R *edwards25519.ExtendedGroupElement, c Commitment) error {
+	err := decodeCommitmentReason(R, c)
+	if err != nil && err != ErrCommitLength {
+		return ErrInvalidCommit
+	}
+	return err
+}
+
+// decodeCommitmentReason is decodeCommitment,
+// but returns the specific reason an invalid commitment was rejected:
+// ErrCommitNonCanonical, ErrCommitIdentity, or ErrCommitNotOnCurve.
+func decodeCommitmentReason(R *edwards25519.ExtendedGroupElement, c Commitment) error {
+	if len(c) != ed25519.PublicKeySize {
+		return ErrCommitLength
+	}
+	var commitBytes [32]byte
+	copy(commitBytes[:], c)
+	switch {
+	case !canonicalPoint(&commitBytes):
+		return ErrCommitNonCanonical
+	case isIdentity(&commitBytes):
+		return ErrCommitIdentity
+	case !R.FromBytes(&commitBytes):
+		return ErrCommitNotOnCurve
+	}
+	return nil
+}
+
+// newCommitError returns the *CommitError reporting
+// that cosigner i's commitment was rejected by decodeCommitmentReason
+// for the given reason.
+func newCommitError(i int, reason error) *CommitError {
+	if reason == ErrCommitLength {
+		return &CommitError{Index: i, Err: reason}
+	}
+	return &CommitError{Index: i, Err: ErrInvalidCommit, Reason: reason}
+}
+
+// isIdentity reports whether an encoded point is the identity,
+// ignoring the sign bit, which FromBytes also ignores when x is zero.
+func isIdentity(s *[32]byte) bool {
+	if s[0] != 1 || s[31]&0x7f != 0 {
+		return false
+	}
+	for i := 1; i < 31; i++ {
+		if s[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalPoint reports whether the y-coordinate in an encoded point
+// is fully reduced modulo the field prime 2^255-19.
+// FromBytes silently accepts unreduced encodings,
+// which would give the same point several distinct representations.
+func canonicalPoint(s *[32]byte) bool {
+	if s[31]&0x7f != 0x7f {
+		return true
+	}
+	for i := 30; i > 0; i-- {
+		if s[i] != 0xff {
+			return true
+		}
+	}
+	return s[0] < 0xed
 }
 
 var scOne = [32]byte{1}
@@ -182,33 +604,105 @@ var scOne = [32]byte{1}
 // that are enabled in the participation mask,
 // which must be identical to the one
 // the leader previously used during AggregateCommit.
+//
+// As with AggregateCommit, the result depends only on
+// which part is stored at which index, not on the order of arrival.
+//
+// AggregateSignature panics if aggregateR has the wrong length,
+// and returns nil if any enabled cosigner's signature part is malformed;
+// use AggregateSignatureErr to find out which one.
 func (cos *Cosigners) AggregateSignature(aggregateR Commitment, sigParts []SignaturePart) []byte {
 
-	if l := len(aggregateR); l != ed25519.PublicKeySize {
-		panic("ed25519: bad aggregateR length: " + strconv.Itoa(l))
+	sig, err := cos.AggregateSignatureErr(aggregateR, sigParts)
+	if err == ErrCommitLength {
+		panic(err)
+	} else if err != nil {
+		return nil
+	}
+	return sig
+}
+
+// AggregateSignatureErr combines cosigners' signature parts
+// into a final collective signature exactly as AggregateSignature does,
+// but returns ErrCommitLength if aggregateR has the wrong length,
+// or a *PartError identifying the first enabled cosigner
+// whose signature part is missing or malformed.
+func (cos *Cosigners) AggregateSignatureErr(aggregateR Commitment, sigParts []SignaturePart) ([]byte, error) {
+
+	aggS, err := cos.aggregateS(aggregateR, sigParts)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, cos.SignatureSize())
+	copy(signature[:], aggregateR)
+	copy(signature[32:64], aggS[:])
+	copy(signature[64:], cos.mask)
+
+	return signature, nil
+}
+
+// WriteSignature combines cosigners' signature parts
+// into a final collective signature exactly as AggregateSignatureErr does,
+// but writes the signature to w instead of returning it,
+// avoiding an intermediate copy of a large participation mask.
+// It returns the number of bytes written and any error from w,
+// or the errors AggregateSignatureErr returns,
+// in which case nothing is written.
+func (cos *Cosigners) WriteSignature(w io.Writer, aggregateR Commitment,
+	sigParts []SignaturePart) (int, error) {
+
+	aggS, err := cos.aggregateS(aggregateR, sigParts)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, b := range [][]byte{aggregateR, aggS[:], cos.mask} {
+		n, err := w.Write(b)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// aggregateS sums the signature parts of the enabled cosigners,
+// checking them and aggregateR as AggregateSignatureErr describes.
+func (cos *Cosigners) aggregateS(aggregateR Commitment,
+	sigParts []SignaturePart) (aggS [32]byte, err error) {
+
+	if len(aggregateR) != ed25519.PublicKeySize {
+		return aggS, ErrCommitLength
 	}
+	return cos.AggregateScalars(sigParts)
+}
 
-	var aggS, indivS [32]byte
+// AggregateScalars returns the sum modulo the group order
+// of the signature parts of the cosigners enabled in the participation bitmask,
+// where sigParts is indexed as for AggregateSignature.
+// It is the S component of the collective signature
+// that AggregateSignature would produce,
+// without the aggregate commit or the mask,
+// for callers building their own aggregation layers.
+// AggregateScalars returns a PartError wrapping ErrPartLength
+// if an enabled cosigner's part is missing or not 32 bytes long.
+func (cos *Cosigners) AggregateScalars(sigParts []SignaturePart) (aggS [32]byte, err error) {
+
+	var indivS [32]byte
 	for i := range cos.keys {
 		if cos.MaskBit(i) == Disabled {
 			continue
 		}
 
-		if l := len(sigParts[i]); l != 32 {
-			return nil
+		if i >= len(sigParts) || len(sigParts[i]) != 32 {
+			return [32]byte{}, &PartError{i, ErrPartLength}
 		}
 		copy(indivS[:], sigParts[i])
 		edwards25519.ScMulAdd(&aggS, &aggS, &scOne, &indivS)
 	}
-
-	mask := cos.Mask()
-	cosigSize := ed25519.SignatureSize + len(mask)
-	signature := make([]byte, cosigSize)
-	copy(signature[:], aggregateR)
-	copy(signature[32:64], aggS[:])
-	copy(signature[64:], mask)
-
-	return signature
+	return aggS, nil
 }
 
 // VerifyPart allows the leader to verify an individual cosigner's
@@ -219,8 +713,38 @@ func (cos *Cosigners) AggregateSignature(aggregateR Commitment, sigParts []Signa
 // In such a situation, the leader cannot complete this signing round,
 // but can restart the collective signing process (with new commits)
 // after excluding the buggy or malicious cosigner.
+// VerifyPart returns false if signer is not a valid cosigner index,
+// so the leader may pass it indices taken from untrusted messages.
 func (cos *Cosigners) VerifyPart(message, aggR Commitment,
 	signer int, indR, indS []byte) bool {
 
-	return cos.verify(message, aggR, indR, indS, cos.keys[signer])
+	if signer < 0 || signer >= len(cos.keys) {
+		return false
+	}
+	return cos.verify(nil, message, aggR, indR, indS, *cos.aggKey(signer))
+}
+
+// VerifyPartStandalone checks an individual cosigner's signature part
+// as VerifyPart does, but without a Cosigners object,
+// given only that cosigner's public key
+// and the aggregate public key and aggregate commit of the signing round.
+// This lets a service that holds only one cosigner's key
+// check that cosigner's parts.
+// The challenge is computed with SHA-512.
+func VerifyPartStandalone(publicKey ed25519.PublicKey, message,
+	aggregateK, aggregateR, indR, indS []byte) bool {
+
+	if len(publicKey) != ed25519.PublicKeySize ||
+		len(aggregateK) != ed25519.PublicKeySize {
+		return false
+	}
+	var A edwards25519.ExtendedGroupElement
+	var keyBytes [32]byte
+	copy(keyBytes[:], publicKey)
+	if !A.FromBytes(&keyBytes) {
+		return false
+	}
+	h := newHram(nil, nil, aggregateR, aggregateK)
+	h.Write(message)
+	return checkHram(h, indR, indS, A)
 }
@@ -0,0 +1,136 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+
+	//"golang.org/x/crypto/ed25519"
+	"github.com/bford/golang-x-crypto/ed25519"
+)
+
+// Signature represents a collective signature parsed into its components.
+// The byte-slice form of a collective signature,
+// as produced by AggregateSignature,
+// is the concatenation of R, S, and Mask.
+type Signature struct {
+	R    [32]byte // aggregate Schnorr commit
+	S    [32]byte // aggregate Schnorr response
+	Mask []byte   // participation mask, as defined in Cosigners.SetMask
+}
+
+// ParseSignature splits the collective signature sig into its components,
+// checking that its length is correct for the cosigners in cos.
+// It returns ErrSignatureLength if not.
+// ParseSignature does not check the signature's validity;
+// use Cosigners.VerifySignature for that.
+func ParseSignature(cos *Cosigners, sig []byte) (*Signature, error) {
+	if len(sig) != ed25519.SignatureSize+cos.MaskLen() {
+		return nil, ErrSignatureLength
+	}
+	s := &Signature{}
+	copy(s.R[:], sig[:32])
+	copy(s.S[:], sig[32:64])
+	s.Mask = append([]byte{}, sig[64:]...)
+	return s, nil
+}
+
+// Bytes returns the byte-slice form of the collective signature.
+func (s *Signature) Bytes() []byte {
+	sig := make([]byte, ed25519.SignatureSize+len(s.Mask))
+	copy(sig[:32], s.R[:])
+	copy(sig[32:64], s.S[:])
+	copy(sig[64:], s.Mask)
+	return sig
+}
+
+// SignatureEqual reports whether a and b are the same collective signature,
+// with identical R, S, and participation mask,
+// for example to deduplicate stored signatures.
+// It returns false if either is shorter than ed25519.SignatureSize,
+// or if their masks differ in length.
+// Otherwise it runs in time independent of their contents.
+//
+// Since Verify rejects signatures whose S is not fully reduced,
+// each valid signature has a single R and S,
+// but Verify ignores the unused high bits of the mask,
+// so two signatures differing only in those bits
+// both verify yet compare unequal.
+// Callers needing a one-to-one correspondence between valid signatures
+// and their encodings should verify them with VerifyStrict.
+func SignatureEqual(a, b []byte) bool {
+	if len(a) < ed25519.SignatureSize || len(a) != len(b) {
+		return false
+	}
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// VerifySignature is like Verify,
+// but takes a parsed collective signature.
+func (cos *Cosigners) VerifySignature(message []byte, sig *Signature) bool {
+	return cos.Verify(message, sig.Bytes())
+}
+
+// jsonSignature is the JSON schema for collective signatures.
+type jsonSignature struct {
+	R       []byte `json:"r"`
+	S       []byte `json:"s"`
+	Signers []int  `json:"signers"`
+}
+
+// MarshalSignatureJSON encodes the collective signature sig as a JSON object
+// of the form {"r":"...","s":"...","signers":[0,2,5]},
+// in which r and s are base64-encoded
+// and signers lists, in increasing order,
+// the indices of the cosigners in cos that participated in the signature.
+// It returns ErrSignatureLength if sig has the wrong length for cos.
+func MarshalSignatureJSON(cos *Cosigners, sig []byte) ([]byte, error) {
+	s, err := ParseSignature(cos, sig)
+	if err != nil {
+		return nil, err
+	}
+	js := jsonSignature{R: s.R[:], S: s.S[:], Signers: []int{}}
+	for i := range cos.keys {
+		if s.Mask[i>>3]&(1<<uint(i&7)) == 0 {
+			js.Signers = append(js.Signers, i)
+		}
+	}
+	return json.Marshal(&js)
+}
+
+// UnmarshalSignatureJSON decodes a collective signature
+// encoded by MarshalSignatureJSON,
+// reconstructing its participation mask from the list of signers.
+// It returns ErrSignatureLength if r or s is not 32 bytes long,
+// ErrSignerRange if any signer index is not a valid index into cos,
+// and ErrDuplicateSigner if any signer index is listed twice.
+func UnmarshalSignatureJSON(cos *Cosigners, data []byte) ([]byte, error) {
+	var js jsonSignature
+	if err := json.Unmarshal(data, &js); err != nil {
+		return nil, err
+	}
+	if len(js.R) != 32 || len(js.S) != 32 {
+		return nil, ErrSignatureLength
+	}
+
+	s := &Signature{Mask: make([]byte, cos.MaskLen())}
+	copy(s.R[:], js.R)
+	copy(s.S[:], js.S)
+	for i := range s.Mask {
+		s.Mask[i] = 0xff // all disabled
+	}
+	for _, i := range js.Signers {
+		if i < 0 || i >= len(cos.keys) {
+			return nil, ErrSignerRange
+		}
+		bit := byte(1) << uint(i&7)
+		if s.Mask[i>>3]&bit == 0 {
+			return nil, ErrDuplicateSigner
+		}
+		s.Mask[i>>3] &^= bit
+	}
+	return s.Bytes(), nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestParseSignature(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetPolicy(ThresholdPolicy(n - 1))
+	cos.SetMaskBit(8, Disabled)
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	s, err := ParseSignature(cos, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.R[:], sig[:32]) || !bytes.Equal(s.S[:], sig[32:64]) ||
+		!bytes.Equal(s.Mask, sig[64:]) {
+		t.Errorf("signature components parsed incorrectly")
+	}
+	if !bytes.Equal(s.Bytes(), sig) {
+		t.Errorf("signature does not round-trip")
+	}
+	if !cos.VerifySignature(rightMessage, s) {
+		t.Errorf("valid parsed signature rejected")
+	}
+	if cos.VerifySignature(wrongMessage, s) {
+		t.Errorf("parsed signature on wrong message accepted")
+	}
+
+	// The parsed signature does not alias the original.
+	s.Mask[0] = 0xff
+	if sig[64] == 0xff {
+		t.Errorf("parsed mask aliases signature")
+	}
+
+	for _, l := range []int{0, 32, 64, len(sig) - 1, len(sig) + 1} {
+		bad := make([]byte, l)
+		if _, err := ParseSignature(cos, bad); err != ErrSignatureLength {
+			t.Errorf("length %d: got %v, want %v", l, err, ErrSignatureLength)
+		}
+	}
+}
+
+func TestSignatureJSON(t *testing.T) {
+	n := 10
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cos.SetPolicy(ThresholdPolicy(n - 3))
+	cos.SetMask([]byte{0x12, 0x02}) // cosigners 1, 4, and 9 absent
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	data, err := MarshalSignatureJSON(cos, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"r":"` + base64.StdEncoding.EncodeToString(sig[:32]) +
+		`","s":"` + base64.StdEncoding.EncodeToString(sig[32:64]) +
+		`","signers":[0,2,3,5,6,7,8]}`
+	if string(data) != want {
+		t.Errorf("got JSON %s, want %s", data, want)
+	}
+
+	decoded, err := UnmarshalSignatureJSON(cos, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, sig) {
+		t.Errorf("signature does not round-trip through JSON")
+	}
+	if !cos.Verify(rightMessage, decoded) {
+		t.Errorf("decoded signature rejected")
+	}
+
+	r := base64.StdEncoding.EncodeToString(sig[:32])
+	bad := []struct {
+		json string
+		err  error
+	}{
+		{`{"r":"` + r + `","s":"` + r + `","signers":[0,10]}`, ErrSignerRange},
+		{`{"r":"` + r + `","s":"` + r + `","signers":[-1]}`, ErrSignerRange},
+		{`{"r":"` + r + `","s":"` + r + `","signers":[3,3]}`, ErrDuplicateSigner},
+		{`{"r":"","s":"` + r + `","signers":[]}`, ErrSignatureLength},
+	}
+	for _, test := range bad {
+		if _, err := UnmarshalSignatureJSON(cos, []byte(test.json)); err != test.err {
+			t.Errorf("%s: got %v, want %v", test.json, err, test.err)
+		}
+	}
+	if _, err := UnmarshalSignatureJSON(cos, []byte("{")); err == nil {
+		t.Errorf("malformed JSON accepted")
+	}
+}
+
+func TestSignatureEqual(t *testing.T) {
+	n := 11
+	genKeys(n)
+	cos, _ := NewCosignersErr(pubKeys[:n], []byte{0x00, 0x04})
+	cos.SetPolicy(ThresholdPolicy(n - 1))
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+
+	if !SignatureEqual(sig, append([]byte{}, sig...)) {
+		t.Errorf("identical signatures compare unequal")
+	}
+
+	mask := append([]byte{}, sig...)
+	mask[64] ^= 0x01
+	s := append([]byte{}, sig...)
+	s[40] ^= 0x01
+	r := append([]byte{}, sig...)
+	r[0] ^= 0x01
+	padding := append([]byte{}, sig...)
+	padding[65] &^= 0x80
+	if !cos.Verify(rightMessage, padding) {
+		t.Fatalf("signature with cleared padding bit rejected")
+	}
+	for _, other := range [][]byte{mask, s, r, padding, sig[:len(sig)-1],
+		append(sig, 0xff)} {
+		if SignatureEqual(sig, other) || SignatureEqual(other, sig) {
+			t.Errorf("%x compares equal to %x", other, sig)
+		}
+	}
+	if SignatureEqual(sig[:63], sig[:63]) {
+		t.Errorf("truncated signatures compare equal")
+	}
+}
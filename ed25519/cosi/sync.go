@@ -0,0 +1,52 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"sync"
+)
+
+// SyncCosigners wraps a Cosigners object for use by many goroutines at once,
+// exposing a reduced, verification-only subset of its methods.
+// Each method holds an internal lock for its duration,
+// serializing access to the participation bitmask that Verify updates.
+//
+// Since successive calls may come from different goroutines,
+// the mask observed by CountEnabled or Mask
+// reflects whichever signature was most recently verified by any caller.
+// Callers needing the mask of a particular signature
+// should instead use a Clone of the underlying Cosigners object.
+type SyncCosigners struct {
+	mu  sync.Mutex
+	cos *Cosigners
+}
+
+// NewSyncCosigners creates a SyncCosigners object wrapping cos.
+// The caller must not use cos directly after passing it to NewSyncCosigners.
+func NewSyncCosigners(cos *Cosigners) *SyncCosigners {
+	return &SyncCosigners{cos: cos}
+}
+
+// Verify checks a collective signature as Cosigners.Verify does.
+func (s *SyncCosigners) Verify(message, sig []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cos.Verify(message, sig)
+}
+
+// CountEnabled returns the number of participants currently marked Enabled
+// in the participation bitmask.
+func (s *SyncCosigners) CountEnabled() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cos.CountEnabled()
+}
+
+// Mask returns a copy of the current cosigner disable-mask.
+func (s *SyncCosigners) Mask() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cos.Mask()
+}
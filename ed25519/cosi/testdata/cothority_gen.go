@@ -0,0 +1,154 @@
+//go:build ignore
+// +build ignore
+
+// This program generates cothority.input using the CoSi implementation
+// in go.dedis.ch/kyber/v3/sign/cosi, with SHA-512 as the challenge hash
+// for compatibility with Ed25519, as in that package's own tests.
+// Each line holds a message, a comma-separated list of public keys,
+// and a collective signature in kyber's format, all hex-encoded
+// and separated by colons.
+// Run it in a module that requires go.dedis.ch/kyber/v3:
+//
+//	go run cothority_gen.go > cothority.input
+//
+// Given a file of vectors in the same format, it instead checks
+// that kyber verifies each of them:
+//
+//	go run cothority_gen.go -verify vectors.input
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"hash"
+	"log"
+	"os"
+	"strings"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/group/edwards25519"
+	"go.dedis.ch/kyber/v3/sign/cosi"
+	"go.dedis.ch/kyber/v3/xof/blake2xb"
+)
+
+type suite struct {
+	cosi.Suite
+	r cipher.Stream
+}
+
+func (s *suite) Hash() hash.Hash             { return sha512.New() }
+func (s *suite) RandomStream() cipher.Stream { return s.r }
+
+type vector struct {
+	Message    string
+	PublicKeys []string
+	Signature  string
+}
+
+func main() {
+	verify := flag.String("verify", "", "file of vectors to verify")
+	flag.Parse()
+	if *verify != "" {
+		verifyFile(*verify)
+		return
+	}
+
+	s := &suite{edwards25519.NewBlakeSHA256Ed25519(),
+		blake2xb.New([]byte("CoSi cothority test vectors"))}
+	for _, test := range []struct {
+		n        int
+		disabled []int
+	}{
+		{1, nil}, {2, nil}, {5, []int{1, 3}}, {9, []int{0, 8}},
+		{16, []int{2, 5, 7, 11}},
+	} {
+		v := generate(s, test.n, test.disabled)
+		fmt.Printf("%s:%s:%s\n", v.Message,
+			strings.Join(v.PublicKeys, ","), v.Signature)
+	}
+}
+
+func generate(s *suite, n int, disabled []int) vector {
+	message := []byte(fmt.Sprintf("cothority test vector with %d cosigners", n))
+	privates := make([]kyber.Scalar, n)
+	publics := make([]kyber.Point, n)
+	var v vector
+	v.Message = hex.EncodeToString(message)
+	for i := range publics {
+		privates[i] = s.Scalar().Pick(s.RandomStream())
+		publics[i] = s.Point().Mul(privates[i], nil)
+		b, _ := publics[i].MarshalBinary()
+		v.PublicKeys = append(v.PublicKeys, hex.EncodeToString(b))
+	}
+
+	off := make(map[int]bool)
+	for _, i := range disabled {
+		off[i] = true
+	}
+	mask, _ := cosi.NewMask(s, publics, nil)
+	var secrets []kyber.Scalar
+	var signers []int
+	var commits []kyber.Point
+	var masks [][]byte
+	for i := 0; i < n; i++ {
+		if off[i] {
+			continue
+		}
+		mask.SetBit(i, true)
+		x, X := cosi.Commit(s)
+		m, _ := cosi.NewMask(s, publics, publics[i])
+		secrets = append(secrets, x)
+		signers = append(signers, i)
+		commits = append(commits, X)
+		masks = append(masks, m.Mask())
+	}
+	V, _, err := cosi.AggregateCommitments(s, commits, masks)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c, _ := cosi.Challenge(s, V, mask.AggregatePublic, message)
+	var responses []kyber.Scalar
+	for k, i := range signers {
+		r, _ := cosi.Response(s, privates[i], secrets[k], c)
+		responses = append(responses, r)
+	}
+	r, _ := cosi.AggregateResponses(s, responses)
+	sig, _ := cosi.Sign(s, V, r, mask)
+	if err := cosi.Verify(s, publics, message, sig,
+		cosi.NewThresholdPolicy(len(signers))); err != nil {
+		log.Fatal(err)
+	}
+	v.Signature = hex.EncodeToString(sig)
+	return v
+}
+
+func verifyFile(name string) {
+	s := &suite{Suite: edwards25519.NewBlakeSHA256Ed25519()}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for k, line := range lines {
+		parts := strings.Split(line, ":")
+		if len(parts) != 3 {
+			log.Fatalf("bad line %d", k+1)
+		}
+		message, _ := hex.DecodeString(parts[0])
+		sig, _ := hex.DecodeString(parts[2])
+		var publics []kyber.Point
+		for _, p := range strings.Split(parts[1], ",") {
+			b, _ := hex.DecodeString(p)
+			P := s.Point()
+			if err := P.UnmarshalBinary(b); err != nil {
+				log.Fatal(err)
+			}
+			publics = append(publics, P)
+		}
+		err := cosi.Verify(s, publics, message, sig, cosi.NewThresholdPolicy(1))
+		fmt.Printf("vector %d: %v\n", k, err)
+	}
+}
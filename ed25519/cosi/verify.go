@@ -7,6 +7,8 @@ package cosi
 import (
 	"crypto/sha512"
 	"crypto/subtle"
+	"hash"
+	"io"
 
 	//"golang.org/x/crypto/ed25519"
 	//"golang.org/x/crypto/ed25519/internal/edwards25519"
@@ -14,41 +16,6 @@ import (
 	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
 )
 
-// Policy represents a fully customizable cosigning policy
-// deciding what cosigner sets are and aren't sufficient
-// for a collective signature to be considered acceptable to a verifier.
-// The Check method may inspect the set of participants that cosigned
-// by invoking cosigners.Mask and/or cosigners.MaskBit,
-// and may use any other relevant contextual information
-// (e.g., how security-critical
-// the operation relying on the collective signature is)
-// in determining whether the collective signature
-// was produced by an acceptable set of cosigners.
-type Policy interface {
-	Check(cosigners *Cosigners) bool
-}
-
-// The default, conservative policy
-// just requires all participants to have signed.
-type fullPolicy struct{}
-
-func (_ fullPolicy) Check(cosigners *Cosigners) bool {
-	return cosigners.CountEnabled() == cosigners.CountTotal()
-}
-
-type thresPolicy struct{ t int }
-
-func (p thresPolicy) Check(cosigners *Cosigners) bool {
-	return cosigners.CountEnabled() >= p.t
-}
-
-// ThresholdPolicy creates a Policy object representing a simple T-of-N policy,
-// which deems a collective signature acceptable provided
-// that at least the given threshold number of participants cosigned.
-func ThresholdPolicy(threshold int) Policy {
-	return &thresPolicy{threshold}
-}
-
 // Verify determines whether collective signature represented by sig
 // is a valid collective signature on the indicated message,
 // collectively signed by an acceptable set of cosigners.
@@ -73,56 +40,560 @@ func ThresholdPolicy(threshold int) Policy {
 // to determine which specific cosigners did and did not sign.
 //
 func (cos *Cosigners) Verify(message, sig []byte) bool {
+	return cos.verifyDom(nil, message, sig)
+}
+
+// verifyDom is Verify with an optional domain-separation prefix dom.
+func (cos *Cosigners) verifyDom(dom, message, sig []byte) bool {
 
-	cosigSize := ed25519.SignatureSize + cos.MaskLen()
-	if len(sig) != cosigSize {
+	if !cos.checkSigMessage(sig, cos.policy, message) {
 		return false
 	}
+	return cos.verify(dom, message, sig[:32], sig[:32], sig[32:64], cos.aggr)
+}
+
+// VerifyWithPolicy is like Verify,
+// but checks the set of cosigners that signed against policy
+// instead of the Policy registered with SetPolicy,
+// which it leaves unchanged.
+// This allows one Cosigners object to evaluate a signature
+// under several candidate policies,
+// such as a stricter policy planned for the future.
+// A nil policy requires all cosigners to participate, as in SetPolicy.
+func (cos *Cosigners) VerifyWithPolicy(message, sig []byte, policy Policy) bool {
+
+	if policy == nil {
+		policy = fullPolicy{}
+	}
+	if !cos.checkSigMessage(sig, policy, message) {
+		return false
+	}
+	return cos.verify(nil, message, sig[:32], sig[:32], sig[32:64], cos.aggr)
+}
+
+// VerifyThreshold is like Verify,
+// but accepts the signature provided at least threshold cosigners signed,
+// as if ThresholdPolicy(threshold) were registered,
+// whatever Policy is actually registered.
+// The registered Policy is never changed,
+// so it stays in place even if verification panics.
+// SetRejectEmpty still applies.
+func (cos *Cosigners) VerifyThreshold(message, sig []byte, threshold int) bool {
+	return cos.VerifyWithPolicy(message, sig, ThresholdPolicy(threshold))
+}
+
+// VerifyStream is like Verify,
+// but reads the signed message from r,
+// feeding it incrementally into the hash
+// rather than requiring the whole message to be held in memory.
+// VerifyStream returns false if reading from r fails.
+// Since the message is not available when the Policy is checked,
+// a ContextPolicy is consulted only through its Check method.
+func (cos *Cosigners) VerifyStream(r io.Reader, sig []byte) bool {
 
-	// Update our mask to reflect which cosigners actually signed
+	if !cos.checkSig(sig) {
+		return false
+	}
+	h := cos.hram(nil, sig[:32])
+	if _, err := io.Copy(h, r); err != nil {
+		return false
+	}
+	return checkHram(h, sig[:32], sig[32:64], cos.aggr)
+}
+
+// VerifyMulti is like Verify,
+// but takes the signed message as a list of fragments,
+// such as a header and a body,
+// which it feeds into the hash in order.
+// The signed message is the concatenation of the fragments,
+// which VerifyMulti never needs to materialize.
+// As with VerifyStream,
+// a ContextPolicy is consulted only through its Check method.
+func (cos *Cosigners) VerifyMulti(parts [][]byte, sig []byte) bool {
+
+	if !cos.checkSig(sig) {
+		return false
+	}
+	h := cos.hram(nil, sig[:32])
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return checkHram(h, sig[:32], sig[32:64], cos.aggr)
+}
+
+// VerifySplit is like Verify,
+// but takes the 64-byte Ed25519 signature R||S
+// and the participation mask as separate slices,
+// for wire formats that carry them in different fields.
+// It is equivalent to calling Verify on their concatenation.
+func (cos *Cosigners) VerifySplit(message, coreSig, mask []byte) bool {
+	return cos.verifySplit(nil, message, coreSig, mask)
+}
+
+// verifySplit is VerifySplit with an optional domain-separation prefix dom.
+func (cos *Cosigners) verifySplit(dom, message, coreSig, mask []byte) bool {
+
+	if len(coreSig) != ed25519.SignatureSize || len(mask) != cos.MaskLen() ||
+		!scMinimal(coreSig[32:]) {
+		return false
+	}
+	cos.SetMask(mask)
+	if !cos.checkPolicyMessage(cos.policy, message) {
+		return false
+	}
+	return cos.verify(dom, message, coreSig[:32], coreSig[:32], coreSig[32:],
+		cos.aggr)
+}
+
+// VerifyLenient is like Verify,
+// but also accepts a signature whose participation mask
+// is shorter than MaskLen,
+// as produced before AppendCosigner grew the cosigner list.
+// The missing trailing mask bytes are taken to mark every cosigner
+// they would cover as disabled,
+// since those cosigners did not exist when the signature was produced.
+//
+// This laxness has security implications callers must weigh.
+// A signature no longer records the size of the group that produced it,
+// so a verifier cannot tell an old signature from a new one
+// in which the later cosigners simply did not take part,
+// and the registered Policy judges it against the current group:
+// a QuorumPolicy, for example, may reject an old signature
+// that satisfied the quorum of the smaller group.
+// Each signature also gains several valid encodings,
+// one for each way of trimming trailing all-disabled mask bytes,
+// so VerifyLenient must not be used where signatures need to be unique,
+// such as when they are used as identifiers.
+func (cos *Cosigners) VerifyLenient(message, sig []byte) bool {
+	maskLen := cos.MaskLen()
+	if len(sig) < ed25519.SignatureSize ||
+		len(sig) > ed25519.SignatureSize+maskLen {
+		return false
+	}
+	mask := make([]byte, maskLen)
+	n := copy(mask, sig[ed25519.SignatureSize:])
+	for i := n; i < maskLen; i++ {
+		mask[i] = 0xff // all disabled
+	}
+	return cos.verifySplit(nil, message, sig[:ed25519.SignatureSize], mask)
+}
+
+// VerifyScratch holds reusable state for VerifyReuse,
+// so that repeated verifications need not allocate.
+// The zero value is ready to use.
+// A VerifyScratch must not be used by more than one goroutine at a time.
+type VerifyScratch struct {
+	sha    hash.Hash // reusable SHA-512 challenge hash
+	aggK   [32]byte
+	digest [64]byte
+}
+
+// VerifyReuse checks a collective signature exactly as Verify does,
+// but keeps its hash state and buffers in scratch,
+// so that verifying many signatures with the same scratch
+// performs no per-call heap allocation.
+// This holds only with the default SHA-512 challenge hash
+// and without a mask cache:
+// after SetHash, VerifyReuse creates a new hash on every call.
+func (cos *Cosigners) VerifyReuse(message, sig []byte, scratch *VerifyScratch) bool {
+
+	if !cos.checkSigMessage(sig, cos.policy, message) {
+		return false
+	}
+
+	var h hash.Hash
+	if cos.newHash != nil {
+		h = cos.newHash()
+	} else if scratch.sha != nil {
+		h = scratch.sha
+		h.Reset()
+	} else {
+		h = sha512.New()
+		scratch.sha = h
+	}
+	cos.aggr.ToBytes(&scratch.aggK)
+	h.Write(sig[:32])
+	h.Write(scratch.aggK[:])
+	h.Write(message)
+	h.Sum(scratch.digest[:0])
+
+	var hReduced [32]byte
+	edwards25519.ScReduce(&hReduced, &scratch.digest)
+	return checkChallenge(&hReduced, sig[:32], sig[32:64], cos.aggr)
+}
+
+// VerifyDetailed is like Verify,
+// but reports separately whether the signature is cryptographically valid
+// and whether the set of cosigners that signed satisfies the Policy,
+// so that callers can tell a tampered or corrupted signature
+// apart from one that simply lacks enough cosigners.
+// VerifyDetailed checks the signature cryptographically
+// even if the Policy is not satisfied.
+// As with Verify, the participation bitmask is left set
+// to the mask carried in sig, which callers can inspect
+// using EnabledSigners or DisabledSigners.
+// If sig does not have the correct length for this cosigner list,
+// or its S component is not reduced below the group order,
+// VerifyDetailed returns ErrSignatureLength or ErrSignatureScalar
+// respectively, and leaves the participation bitmask unchanged.
+// The signature is acceptable only if both cryptoOK and policyOK are true.
+func (cos *Cosigners) VerifyDetailed(message, sig []byte) (cryptoOK, policyOK bool, err error) {
+
+	if err := validateSignatureForm(sig, cos.MaskLen()); err != nil {
+		return false, false, err
+	}
 	cos.SetMask(sig[64:])
+	policyOK = cos.checkPolicyMessage(cos.policy, message)
+	cryptoOK = cos.verify(nil, message, sig[:32], sig[:32], sig[32:64], cos.aggr)
+	return cryptoOK, policyOK, nil
+}
+
+// VerifyDebug is like Verify,
+// but also returns the commit expectedR
+// that the signature's S component and the aggregate public key
+// of the cosigners named in its mask call for,
+// together with the commit gotR the signature actually carries,
+// so that operators can compare the two when a signature is rejected.
+// They are equal exactly when the signature is cryptographically valid,
+// so a signature rejected with equal commits failed the Policy,
+// or has an identity aggregate public key.
+// If sig is malformed, expectedR is all zeros,
+// as is gotR if sig is shorter than 32 bytes.
+// VerifyDebug is meant for diagnosis only:
+// it does extra work and its results should not drive any decision.
+func (cos *Cosigners) VerifyDebug(message, sig []byte) (ok bool, expectedR, gotR [32]byte) {
+
+	if len(sig) >= 32 {
+		copy(gotR[:], sig[:32])
+	}
+	if !cos.checkSigMask(sig) {
+		return false, expectedR, gotR
+	}
+	policyOK := cos.checkPolicyMessage(cos.policy, message)
+	h := cos.hram(nil, sig[:32])
+	h.Write(message)
+	hReduced := reduceHram(h)
+	expectedR = recomputeR(&hReduced, sig[32:64], cos.aggr)
+	ok = policyOK && checkChallenge(&hReduced, sig[:32], sig[32:64], cos.aggr)
+	return ok, expectedR, gotR
+}
+
+// VerifyStrict is like Verify,
+// but additionally rejects a collective signature
+// whose participation mask is not in canonical form, as SetMaskStrict requires.
+// Verify ignores any unused high bits in the mask,
+// so flipping those bits yields a different byte string
+// that Verify still accepts as a valid signature;
+// VerifyStrict ensures that each signature has exactly one valid encoding.
+func (cos *Cosigners) VerifyStrict(message, sig []byte) bool {
+
+	if len(sig) < ed25519.SignatureSize ||
+		cos.SetMaskStrict(sig[64:]) != nil {
+		return false
+	}
+	return cos.Verify(message, sig)
+}
+
+// VerifyConstantTime is like Verify,
+// but avoids revealing through its timing
+// which cosigners participated in the signature.
+// Verify updates the aggregate public key incrementally,
+// doing work proportional to the number of mask bits that changed,
+// and returns early without any cryptographic check
+// if the participation set fails the Policy.
+// VerifyConstantTime instead recomputes the aggregate public key
+// with one point addition and one constant-time conditional move per cosigner,
+// always performs the cryptographic check,
+// and merges its result with the Policy's without branching.
+//
+// VerifyConstantTime does not hide the length of sig,
+// and thus the number of cosigners,
+// nor the running time of the Policy itself,
+// which is up to the Policy's implementation;
+// the built-in threshold policies, for example,
+// take time independent of the mask.
+// The final variable-time scalar multiplication
+// depends on the signature and on the aggregate public key,
+// which verifiers with the signature can compute anyway.
+// VerifyConstantTime bypasses any cache enabled by SetMaskCache,
+// and is substantially slower than Verify for large cosigner sets
+// when successive signatures have similar masks.
+// Like Verify, it leaves the participation bitmask
+// set to the mask carried in sig.
+func (cos *Cosigners) VerifyConstantTime(message, sig []byte) bool {
 
-	// Check that this represents a sufficient set of signers
-	if !cos.policy.Check(cos) {
+	if validateSignatureForm(sig, cos.MaskLen()) != nil {
 		return false
 	}
+	mask := sig[64:]
 
-	return cos.verify(message, sig[:32], sig[:32], sig[32:64], cos.aggr)
+	// Recompute the aggregate public key and participation count
+	// doing the same work for every cosigner.
+	var aggr, sum edwards25519.ExtendedGroupElement
+	aggr.Zero()
+	enabled := 0
+	for i := range cos.keys {
+		disabled := int32((mask[i>>3]|cos.revokedByte(i>>3))>>uint(i&7)) & 1
+		sum.Add(&aggr, cos.aggKey(i))
+		aggr.CMove(&sum, 1-disabled)
+		enabled += int(1 - disabled)
+	}
+	copy(cos.mask, mask)
+	for i := range cos.mask {
+		cos.mask[i] |= cos.revokedByte(i)
+	}
+	if pad := len(cos.keys) & 7; pad != 0 {
+		cos.mask[len(cos.mask)-1] |= byte(0xff) << uint(pad)
+	}
+	cos.aggr = aggr
+	cos.enabled = enabled
+
+	policyOK := boolInt(cos.checkPolicyMessage(cos.policy, message))
+	h := cos.hram(nil, sig[:32])
+	h.Write(message)
+	sigOK := boolInt(checkHram(h, sig[:32], sig[32:64], cos.aggr))
+	return subtle.ConstantTimeEq(policyOK&sigOK, 1) == 1
 }
 
-func (cos *Cosigners) verify(message, aggR, sigR, sigS []byte,
-	sigA edwards25519.ExtendedGroupElement) bool {
+// boolInt converts b to 1 or 0.
+func boolInt(b bool) int32 {
+	var i int32
+	if b {
+		i = 1
+	}
+	return i
+}
 
-	if len(sigR) != 32 || len(sigS) != 32 || sigS[31]&224 != 0 {
+// checkSig checks the length of a collective signature,
+// sets our mask to reflect which cosigners actually signed,
+// and checks that this represents a sufficient set of signers.
+// It is for callers that do not have the signed message in hand.
+func (cos *Cosigners) checkSig(sig []byte) bool {
+	return cos.checkSigMask(sig) && cos.checkPolicy(cos.policy)
+}
+
+// checkSigMessage is checkSig using the given policy
+// instead of the registered one,
+// which it checks against the signed message.
+func (cos *Cosigners) checkSigMessage(sig []byte, policy Policy, message []byte) bool {
+	return cos.checkSigMask(sig) && cos.checkPolicyMessage(policy, message)
+}
+
+// checkSigMask checks the form of a collective signature
+// and sets our mask to reflect which cosigners actually signed.
+func (cos *Cosigners) checkSigMask(sig []byte) bool {
+	if validateSignatureForm(sig, cos.MaskLen()) != nil {
 		return false
 	}
+	cos.SetMask(sig[64:])
+	return true
+}
+
+// checkPolicy reports whether the current participation set
+// satisfies policy, and is non-empty if SetRejectEmpty is in effect.
+func (cos *Cosigners) checkPolicy(policy Policy) bool {
+	if cos.rejectEmpty && cos.enabled == 0 {
+		return false
+	}
+	return policy.Check(cos)
+}
+
+// checkPolicyMessage is checkPolicy for a signature on message,
+// which it passes to policy's CheckCtx method if it has one.
+func (cos *Cosigners) checkPolicyMessage(policy Policy, message []byte) bool {
+	cp, ok := policy.(ContextPolicy)
+	if !ok {
+		return cos.checkPolicy(policy)
+	}
+	if cos.rejectEmpty && cos.enabled == 0 {
+		return false
+	}
+	return cp.CheckCtx(cos, message)
+}
 
-	// Compute the digest against aggregate public key and commit
+// validateSignatureForm checks that sig has the form of
+// a collective signature with a maskLen-byte participation mask:
+// that it has the right length,
+// and that its S component is reduced below the group order.
+// It returns ErrSignatureLength or ErrSignatureScalar otherwise.
+func validateSignatureForm(sig []byte, maskLen int) error {
+	if len(sig) != ed25519.SignatureSize+maskLen {
+		return ErrSignatureLength
+	}
+	if !scMinimal(sig[32:64]) {
+		return ErrSignatureScalar
+	}
+	return nil
+}
+
+// scMinimal reports whether the 32-byte little-endian scalar s
+// is less than the group order.
+func scMinimal(s []byte) bool {
+	var b [32]byte
+	copy(b[:], s)
+	return edwards25519.ScMinimal(&b)
+}
+
+// verify checks a signature on message,
+// hashed with the domain-separation prefix dom if it is non-nil.
+func (cos *Cosigners) verify(dom, message, aggR, sigR, sigS []byte,
+	sigA edwards25519.ExtendedGroupElement) bool {
+
+	h := cos.hram(dom, aggR)
+	h.Write(message)
+	return checkHram(h, sigR, sigS, sigA)
+}
+
+// hram starts the digest against aggregate public key and commit,
+// to which the caller must then write the message.
+func (cos *Cosigners) hram(dom, aggR []byte) hash.Hash {
 	var aggK [32]byte
 	cos.aggr.ToBytes(&aggK)
+	return newHram(cos.newHash, dom, aggR, aggK[:])
+}
 
-	h := sha512.New()
+// newHram starts the Schnorr challenge digest
+// over the optional domain-separation prefix dom,
+// the aggregate commit, and the aggregate public key,
+// to which the caller must then write the message.
+// The digest uses newHash, or SHA-512 if newHash is nil.
+func newHram(newHash func() hash.Hash, dom, aggR, aggK []byte) hash.Hash {
+	if newHash == nil {
+		newHash = sha512.New
+	}
+	h := newHash()
+	h.Write(dom)
 	h.Write(aggR)
-	h.Write(aggK[:])
+	h.Write(aggK)
+	return h
+}
+
+// Challenge returns the Schnorr challenge scalar,
+// reduced modulo the group order,
+// that Verify computes for a collective signature on message
+// with aggregate commit aggregateR,
+// under the aggregate public key of the cosigners
+// currently enabled in the participation bitmask.
+// The challenge hashes aggregateR, the aggregate public key,
+// and the message, in that order, using SHA-512 unless changed by SetHash.
+// Systems that aggregate collective signatures further,
+// such as trees of cosigner groups,
+// can use it to recompute and combine verification equations.
+func (cos *Cosigners) Challenge(message, aggregateR []byte) [32]byte {
+	h := cos.hram(nil, aggregateR)
 	h.Write(message)
+	return reduceHram(h)
+}
+
+// reduceHram finishes the challenge digest h
+// and reduces it modulo the group order.
+func reduceHram(h hash.Hash) [32]byte {
 	var digest [64]byte
 	h.Sum(digest[:0])
 
 	var hReduced [32]byte
 	edwards25519.ScReduce(&hReduced, &digest)
+	return hReduced
+}
+
+// checkHram checks the signature (sigR, sigS) against public key sigA,
+// given the digest h of the aggregate commit, aggregate key, and message.
+func checkHram(h hash.Hash, sigR, sigS []byte,
+	sigA edwards25519.ExtendedGroupElement) bool {
+
+	if len(sigR) != 32 || len(sigS) != 32 || !scMinimal(sigS) {
+		return false
+	}
+
+	hReduced := reduceHram(h)
+	return checkChallenge(&hReduced, sigR, sigS, sigA)
+}
+
+// checkChallenge checks the signature (sigR, sigS) against public key sigA,
+// given the reduced challenge hReduced.
+// It rejects every signature if sigA is the identity,
+// against which R = [S]B would verify for any S.
+func checkChallenge(hReduced *[32]byte, sigR, sigS []byte,
+	sigA edwards25519.ExtendedGroupElement) bool {
+
+	if isIdentityPoint(&sigA) {
+		return false
+	}
+	checkR := recomputeR(hReduced, sigS, sigA)
+	return subtle.ConstantTimeCompare(sigR, checkR[:]) == 1
+}
+
+// recomputeR returns the encoding of [S]B - [hReduced]sigA,
+// the commit R with which sigS is a valid signature against sigA.
+func recomputeR(hReduced *[32]byte, sigS []byte,
+	sigA edwards25519.ExtendedGroupElement) (checkR [32]byte) {
 
 	// The public key used for checking is whichever part was signed
 	edwards25519.FeNeg(&sigA.X, &sigA.X)
 	edwards25519.FeNeg(&sigA.T, &sigA.T)
 
+	var projR edwards25519.ProjectiveGroupElement
+	var b [32]byte
+	copy(b[:], sigS)
+	edwards25519.GeDoubleScalarMultVartime(&projR, hReduced, &sigA, &b)
+	projR.ToBytes(&checkR)
+	return checkR
+}
+
+// VerifyCofactored is like Verify,
+// but uses the cofactored verification equation [8][S]B = [8]R + [8][k]A
+// adopted by ZIP-215,
+// rather than the strict equation [S]B = R + [k]A that Verify checks.
+// The two agree on every signature produced honestly,
+// but may disagree on signatures whose R or public keys
+// contain a small-order component.
+// Systems that require all implementations to accept exactly
+// the same set of signatures, such as consensus protocols,
+// may need cofactored verification to avoid disagreements.
+// Unlike Verify, VerifyCofactored also accepts
+// non-canonical encodings of R, as ZIP-215 does.
+func (cos *Cosigners) VerifyCofactored(message, sig []byte) bool {
+
+	if !cos.checkSigMessage(sig, cos.policy, message) {
+		return false
+	}
+	h := cos.hram(nil, sig[:32])
+	h.Write(message)
+	return checkHramCofactored(h, sig[:32], sig[32:64], cos.aggr)
+}
+
+// checkHramCofactored is like checkHram,
+// but checks the cofactored verification equation.
+func checkHramCofactored(h hash.Hash, sigR, sigS []byte,
+	sigA edwards25519.ExtendedGroupElement) bool {
+
+	if len(sigR) != 32 || len(sigS) != 32 || !scMinimal(sigS) ||
+		isIdentityPoint(&sigA) {
+		return false
+	}
+
+	var R edwards25519.ExtendedGroupElement
+	var RBytes [32]byte
+	copy(RBytes[:], sigR)
+	if !R.FromBytes(&RBytes) {
+		return false
+	}
+
+	hReduced := reduceHram(h)
+
+	edwards25519.FeNeg(&sigA.X, &sigA.X)
+	edwards25519.FeNeg(&sigA.T, &sigA.T)
+
 	var projR edwards25519.ProjectiveGroupElement
 	var b [32]byte
 	copy(b[:], sigS)
 	edwards25519.GeDoubleScalarMultVartime(&projR, &hReduced, &sigA, &b)
 
-	var checkR [32]byte
-	projR.ToBytes(&checkR)
-	return subtle.ConstantTimeCompare(sigR, checkR[:]) == 1
+	// Check that [8]([S]B - [k]A - R) is the identity
+	var check edwards25519.ExtendedGroupElement
+	projR.ToExtended(&check)
+	check.Sub(&check, &R)
+	return isSmallOrder(&check)
 }
 
 // SetPolicy changes the current Policy object registered
@@ -142,6 +613,45 @@ func (cos *Cosigners) SetPolicy(policy Policy) {
 	cos.policy = policy
 }
 
+// SetRejectEmpty sets whether signatures in which no cosigner participated
+// are rejected regardless of the Policy.
+// Anyone could produce such a signature without any private key,
+// yet a permissive Policy such as ThresholdPolicy(0) accepts it.
+// Verification fails for it anyway,
+// since its aggregate public key is the identity,
+// but SetRejectEmpty makes the Policy check itself reject it.
+// Rejecting empty signatures is off by default for compatibility,
+// but recommended as a safety net against a misconfigured Policy.
+// It applies wherever this package checks the Policy,
+// including PolicyNeeds and MinimalSatisfyingSet.
+func (cos *Cosigners) SetRejectEmpty(reject bool) {
+	cos.rejectEmpty = reject
+}
+
+// SetHash changes the hash function used to compute
+// the Schnorr challenge from the aggregate commit,
+// the aggregate public key, and the message.
+// The default, restored by passing nil, is SHA-512 as in RFC 8032;
+// collective signatures made with any other hash
+// are incompatible with standard Ed25519 verifiers.
+// Cosigners must produce their signature parts using CosignHash
+// with the same hash function.
+// SetHash returns ErrHashSize and leaves the hash function unchanged
+// if newHash does not produce 64-byte digests.
+// The Ed25519 private key expansion always uses SHA-512 regardless.
+//
+// New objects should be given their hash with the WithHash option.
+// SetHash is for objects that NewCosignersErr did not create,
+// notably those restored by UnmarshalBinary,
+// since the binary encoding does not record the hash function.
+func (cos *Cosigners) SetHash(newHash func() hash.Hash) error {
+	if newHash != nil && newHash().Size() != sha512.Size {
+		return ErrHashSize
+	}
+	cos.newHash = newHash
+	return nil
+}
+
 // Verify checks a collective signature on a given message,
 // using a given list of public keys and acceptance policy.
 //
@@ -165,7 +675,70 @@ func Verify(publicKeys []ed25519.PublicKey, policy Policy,
 	if len(sig) < ed25519.SignatureSize {
 		return false
 	}
-	cos := NewCosigners(publicKeys, sig[64:])
+	cos, err := NewCosignersErr(publicKeys, sig[64:])
+	if err != nil {
+		return false
+	}
 	cos.SetPolicy(policy)
 	return cos.Verify(message, sig)
 }
+
+// VerifyWithOrderings is like the standalone Verify function,
+// but tries each of several candidate orderings of the cosigners' public keys
+// in turn, for interoperating with producers
+// that may have canonicalized the key list differently.
+// It returns true and the index in orderings
+// of the first ordering under which the signature verifies,
+// or false and -1 if there is none.
+// Each ordering is verified by a Cosigners object configured by opts,
+// so a Policy is supplied with WithPolicy;
+// without one, all cosigners must have participated.
+// Since the participation mask names cosigners by position,
+// the same mask may select different cosigners under different orderings,
+// and the policy is checked against the cosigners each ordering selects.
+// All the orderings should come from a trusted source,
+// and each rejected ordering costs a full verification.
+func VerifyWithOrderings(message, sig []byte,
+	orderings [][]ed25519.PublicKey, opts ...Option) (bool, int) {
+
+	if len(sig) < ed25519.SignatureSize {
+		return false, -1
+	}
+	for i, publicKeys := range orderings {
+		cos, err := NewCosignersErr(publicKeys, sig[64:], opts...)
+		if err == nil && cos.Verify(message, sig) {
+			return true, i
+		}
+	}
+	return false, -1
+}
+
+// VerifyAggregate checks a collective signature on message
+// directly against a precomputed aggregate public key,
+// such as the group key of a subtree in hierarchical CoSi,
+// without needing the individual cosigners' public keys.
+// Only the first 64 bytes of sig, R and S, are used;
+// any participation mask following them is not checked,
+// and is merely informational to the caller.
+// VerifyAggregate thus cannot enforce any Policy:
+// the caller must obtain aggregateKey for the intended set of cosigners
+// from a trusted source,
+// for example as Cosigners.AggregatePublicKey under the signature's mask.
+// The challenge is computed with SHA-512.
+func VerifyAggregate(message, sig, aggregateKey []byte) bool {
+
+	if len(sig) < ed25519.SignatureSize ||
+		validateSignatureForm(sig, len(sig)-ed25519.SignatureSize) != nil ||
+		len(aggregateKey) != ed25519.PublicKeySize {
+		return false
+	}
+	var A edwards25519.ExtendedGroupElement
+	var keyBytes [32]byte
+	copy(keyBytes[:], aggregateKey)
+	if !A.FromBytes(&keyBytes) {
+		return false
+	}
+	h := newHram(nil, nil, sig[:32], aggregateKey)
+	h.Write(message)
+	return checkHram(h, sig[:32], sig[32:64], A)
+}
@@ -0,0 +1,34 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	//"golang.org/x/crypto/ed25519/internal/edwards25519"
+	"github.com/bford/golang-x-crypto/ed25519/internal/edwards25519"
+)
+
+// Warmup exercises the precomputed base-point tables
+// used by Commit, Cosign and every verification operation,
+// so that a process creating many short-lived Cosigners objects
+// can pay their first-use cost at startup rather than on its first signature.
+//
+// The tables are compile-time constants shared by all Cosigners objects
+// and all goroutines, so there is nothing to compute or allocate:
+// the only first-use cost is that of faulting them into memory
+// and the processor's caches,
+// which Warmup incurs by performing one multiplication of each kind.
+// Calling Warmup is never required, and calling it more than once is harmless.
+func Warmup() {
+	var one [32]byte
+	one[0] = 1
+
+	// GeScalarMultBase reads every entry of the base table
+	// to remain constant-time, so one call touches all of it.
+	var A edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&A, &one)
+
+	var R edwards25519.ProjectiveGroupElement
+	edwards25519.GeDoubleScalarMultVartime(&R, &one, &A, &one)
+}
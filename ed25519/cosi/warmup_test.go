@@ -0,0 +1,64 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWarmup(t *testing.T) {
+	Warmup()
+	Warmup()
+
+	genKeys(3)
+	cos, err := NewCosignersErr(pubKeys[:3], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := testCosign(t, rightMessage, priKeys[:3], cos)
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("signature produced after Warmup failed to verify")
+	}
+}
+
+// evictBuf is larger than the last-level cache of most processors.
+var evictBuf = make([]byte, 16<<20)
+
+// evictCaches overwrites evictBuf,
+// so that the next curve operation finds the base tables cold.
+func evictCaches() {
+	for i := 0; i < len(evictBuf); i += 64 {
+		evictBuf[i]++
+	}
+}
+
+// benchFirstCommit measures the latency of a single Commit
+// issued with the curve tables either evicted from the caches or warmed up.
+// The eviction dominates the running time of each iteration,
+// so the Commit alone is timed and reported as ns/commit.
+func benchFirstCommit(b *testing.B, warm bool) {
+	var total time.Duration
+	for i := 0; i < b.N; i++ {
+		evictCaches()
+		if warm {
+			Warmup()
+		}
+		start := time.Now()
+		if _, _, err := Commit(testRand); err != nil {
+			b.Fatal(err)
+		}
+		total += time.Since(start)
+	}
+	b.ReportMetric(float64(total.Nanoseconds())/float64(b.N), "ns/commit")
+}
+
+func BenchmarkFirstCommitCold(b *testing.B) {
+	benchFirstCommit(b, false)
+}
+
+func BenchmarkFirstCommitWarm(b *testing.B) {
+	benchFirstCommit(b, true)
+}
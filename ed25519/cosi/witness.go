@@ -0,0 +1,74 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+// SetWitnesses records which cosigners act as mere witnesses,
+// replacing any previously recorded witness set.
+// All other cosigners are validators.
+//
+// As the package documentation notes,
+// a cosigner may attest only that it has seen and logged a message,
+// without checking that the message is valid in any deeper sense.
+// Witnesses and validators sign in exactly the same way,
+// so the roles are agreed upon outside the signature,
+// and SetWitnesses records them in the Cosigners object
+// for policies such as RolePolicy to consult.
+//
+// The witness set does not affect the aggregate public key
+// or the cryptographic validity of a signature,
+// and is not changed by SetMask or Verify.
+// SetWitnesses returns ErrSignerRange, leaving the set unchanged,
+// if any index is not a valid cosigner index.
+func (cos *Cosigners) SetWitnesses(signers []int) error {
+	var witness map[int]bool
+	for _, i := range signers {
+		if i < 0 || i >= len(cos.keys) {
+			return ErrSignerRange
+		}
+		if witness == nil {
+			witness = make(map[int]bool)
+		}
+		witness[i] = true
+	}
+	cos.witness = witness
+	return nil
+}
+
+// Witness reports whether the cosigner at index i
+// is in the witness set recorded by SetWitnesses,
+// regardless of its participation bit.
+func (cos *Cosigners) Witness(i int) bool {
+	return cos.witness[i]
+}
+
+type rolePolicy struct {
+	validators, witnesses int
+}
+
+func (p rolePolicy) Check(cosigners *Cosigners) bool {
+	validators, witnesses := 0, 0
+	for i := 0; i < cosigners.CountTotal(); i++ {
+		if cosigners.MaskBit(i) == Disabled {
+			continue
+		}
+		if cosigners.Witness(i) {
+			witnesses++
+		} else {
+			validators++
+		}
+	}
+	return validators >= p.validators && witnesses >= p.witnesses
+}
+
+// RolePolicy creates a Policy object
+// that deems a collective signature acceptable provided
+// that at least the given number of validators
+// and at least the given number of witnesses participated,
+// as recorded by SetWitnesses.
+// The roles are counted separately:
+// validators in excess of the requirement do not count as witnesses.
+func RolePolicy(validators, witnesses int) Policy {
+	return rolePolicy{validators, witnesses}
+}
@@ -0,0 +1,61 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cosi
+
+import (
+	"testing"
+)
+
+func TestRolePolicy(t *testing.T) {
+	n := 6
+	genKeys(n)
+	cos, err := NewCosignersErr(pubKeys[:n], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cos.SetWitnesses([]int{3, 4, 5}); err != nil {
+		t.Fatal(err)
+	}
+	if cos.Witness(0) || !cos.Witness(3) {
+		t.Errorf("wrong witness set")
+	}
+	cos.SetPolicy(RolePolicy(2, 2))
+
+	// Two validators and two witnesses suffice.
+	cos.SetMask([]byte{0x24})
+	sig := testCosign(t, rightMessage, priKeys[:n], cos)
+	if !cos.Verify(rightMessage, sig) {
+		t.Errorf("two validators and two witnesses rejected")
+	}
+	if !cos.Witness(3) {
+		t.Errorf("Verify changed the witness set")
+	}
+
+	// Three validators but one witness do not,
+	// although the same number of cosigners signed.
+	cos.SetMask([]byte{0x30})
+	sig = testCosign(t, rightMessage, priKeys[:n], cos)
+	if cos.Verify(rightMessage, sig) {
+		t.Errorf("validators counted as witnesses")
+	}
+	if !cos.VerifyWithPolicy(rightMessage, sig, ThresholdPolicy(4)) {
+		t.Errorf("roles affected a signature's validity")
+	}
+
+	// Once every cosigner is a validator, the witnesses are missing.
+	cos.SetWitnesses(nil)
+	if cos.Verify(rightMessage, sig) {
+		t.Errorf("signature accepted without witnesses")
+	}
+
+	if err := cos.SetWitnesses([]int{0, -1}); err != ErrSignerRange {
+		t.Errorf("out-of-range witness: got %v", err)
+	}
+	cos.SetWitnesses([]int{1})
+	cos.Reset()
+	if cos.Witness(1) {
+		t.Errorf("Reset kept the witness set")
+	}
+}
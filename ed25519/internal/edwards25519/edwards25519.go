@@ -4,6 +4,8 @@
 
 package edwards25519
 
+import "encoding/binary"
+
 // This code is a port of the public domain, “ref10” implementation of ed25519
 // from SUPERCOP.
 
@@ -667,6 +669,15 @@ func (p *ProjectiveGroupElement) Double(r *CompletedGroupElement) {
 	FeSub(&r.T, &r.T, &r.Z)
 }
 
+// ToExtended converts p to extended coordinates,
+// without any field inversion.
+func (p *ProjectiveGroupElement) ToExtended(r *ExtendedGroupElement) {
+	FeMul(&r.X, &p.X, &p.Z)
+	FeMul(&r.Y, &p.Y, &p.Z)
+	FeSquare(&r.Z, &p.Z)
+	FeMul(&r.T, &p.X, &p.Y)
+}
+
 func (p *ProjectiveGroupElement) ToBytes(s *[32]byte) {
 	var recip, x, y FieldElement
 
@@ -712,6 +723,17 @@ func (p *ExtendedGroupElement) Sub(a, b *ExtendedGroupElement) {
 	r.ToExtended(p)
 }
 
+// Replace p with q if b == 1; leave p unchanged if b == 0,
+// in constant time.
+//
+// Preconditions: b in {0,1}.
+func (p *ExtendedGroupElement) CMove(q *ExtendedGroupElement, b int32) {
+	FeCMove(&p.X, &q.X, b)
+	FeCMove(&p.Y, &q.Y, b)
+	FeCMove(&p.Z, &q.Z, b)
+	FeCMove(&p.T, &q.T, b)
+}
+
 func (p *ExtendedGroupElement) ToCached(r *CachedGroupElement) {
 	FeAdd(&r.yPlusX, &p.Y, &p.X)
 	FeSub(&r.yMinusX, &p.Y, &p.X)
@@ -781,6 +803,41 @@ func (p *ExtendedGroupElement) FromBytes(s *[32]byte) bool {
 	return true
 }
 
+// ToAffineBytes sets x and y to the encodings
+// of the affine coordinates of p.
+func (p *ExtendedGroupElement) ToAffineBytes(x, y *[32]byte) {
+	var recip, fx, fy FieldElement
+
+	FeInvert(&recip, &p.Z)
+	FeMul(&fx, &p.X, &recip)
+	FeMul(&fy, &p.Y, &recip)
+	FeToBytes(x, &fx)
+	FeToBytes(y, &fy)
+}
+
+// FromAffineBytes sets p to the point with affine coordinates x and y,
+// as encoded by ToAffineBytes.
+// It returns false if (x, y) does not satisfy the curve equation.
+// This is much cheaper than FromBytes, which must compute a square root.
+func (p *ExtendedGroupElement) FromAffineBytes(x, y *[32]byte) bool {
+	var x2, y2, lhs, rhs FieldElement
+
+	FeFromBytes(&p.X, x)
+	FeFromBytes(&p.Y, y)
+	FeOne(&p.Z)
+	FeMul(&p.T, &p.X, &p.Y)
+
+	// Check -x^2 + y^2 == 1 + d*x^2*y^2
+	FeSquare(&x2, &p.X)
+	FeSquare(&y2, &p.Y)
+	FeSub(&lhs, &y2, &x2)
+	FeMul(&rhs, &x2, &y2)
+	FeMul(&rhs, &rhs, &d)
+	FeAdd(&rhs, &rhs, &p.Z)
+	FeSub(&lhs, &lhs, &rhs)
+	return FeIsNonZero(&lhs) == 0
+}
+
 func (p *CompletedGroupElement) ToProjective(r *ProjectiveGroupElement) {
 	FeMul(&r.X, &p.X, &p.T)
 	FeMul(&r.Y, &p.Y, &p.Z)
@@ -947,6 +1004,74 @@ func GeDoubleScalarMultVartime(r *ProjectiveGroupElement, a *[32]byte, A *Extend
 	}
 }
 
+// GeMultiScalarMultVartime sets r = a[0]*A[0] + ... + a[n-1]*A[n-1] + b*B
+// where B is the Ed25519 base point (x,4/5) with x positive.
+// It shares a single chain of doublings among all the terms,
+// so it is considerably faster than n separate scalar multiplications.
+// The slices a and A must have the same length.
+func GeMultiScalarMultVartime(r *ProjectiveGroupElement, a [][32]byte, A []ExtendedGroupElement, b *[32]byte) {
+	var bSlide [256]int8
+	var t CompletedGroupElement
+	var u, A2 ExtendedGroupElement
+	var i int
+
+	aSlide := make([][256]int8, len(a))
+	Ai := make([][8]CachedGroupElement, len(A)) // A,3A,5A,7A,9A,11A,13A,15A
+	for j := range A {
+		slide(&aSlide[j], &a[j])
+
+		A[j].ToCached(&Ai[j][0])
+		A[j].Double(&t)
+		t.ToExtended(&A2)
+		for k := 0; k < 7; k++ {
+			geAdd(&t, &A2, &Ai[j][k])
+			t.ToExtended(&u)
+			u.ToCached(&Ai[j][k+1])
+		}
+	}
+	slide(&bSlide, b)
+
+	r.Zero()
+
+	for i = 255; i >= 0; i-- {
+		if bSlide[i] != 0 {
+			break
+		}
+	}
+	for j := range aSlide {
+		for k := 255; k > i; k-- {
+			if aSlide[j][k] != 0 {
+				i = k
+				break
+			}
+		}
+	}
+
+	for ; i >= 0; i-- {
+		r.Double(&t)
+
+		for j := range aSlide {
+			if aSlide[j][i] > 0 {
+				t.ToExtended(&u)
+				geAdd(&t, &u, &Ai[j][aSlide[j][i]/2])
+			} else if aSlide[j][i] < 0 {
+				t.ToExtended(&u)
+				geSub(&t, &u, &Ai[j][(-aSlide[j][i])/2])
+			}
+		}
+
+		if bSlide[i] > 0 {
+			t.ToExtended(&u)
+			geMixedAdd(&t, &u, &bi[bSlide[i]/2])
+		} else if bSlide[i] < 0 {
+			t.ToExtended(&u)
+			geMixedSub(&t, &u, &bi[(-bSlide[i])/2])
+		}
+
+		t.ToProjective(r)
+	}
+}
+
 // equal returns 1 if b == c and 0 otherwise, assuming that b and c are
 // non-negative.
 func equal(b, c int32) int32 {
@@ -1791,3 +1916,23 @@ func ScReduce(out *[32]byte, s *[64]byte) {
 	out[30] = byte(s11 >> 9)
 	out[31] = byte(s11 >> 17)
 }
+
+// order is the order of Curve25519 in little-endian form.
+var order = [4]uint64{0x5812631a5cf5d3ed, 0x14def9dea2f79cd6, 0, 0x1000000000000000}
+
+// ScMinimal returns true if the given scalar is less than the order of the
+// curve.
+func ScMinimal(scalar *[32]byte) bool {
+	for i := 3; ; i-- {
+		v := binary.LittleEndian.Uint64(scalar[i*8:])
+		if v > order[i] {
+			return false
+		} else if v < order[i] {
+			break
+		} else if i == 0 {
+			return false
+		}
+	}
+
+	return true
+}